@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deta/space/cmd/utils"
+	"github.com/deta/space/internal/build"
+	"github.com/deta/space/internal/runtime"
+	"github.com/deta/space/internal/spacefile"
+	"github.com/deta/space/pkg/components/emoji"
+	"github.com/deta/space/pkg/components/styles"
+	"github.com/deta/space/pkg/writer"
+	types "github.com/deta/space/shared"
+	"github.com/spf13/cobra"
+)
+
+func newCmdBuild() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "build [flags]",
+		Short: "Build your micros locally like the builder does",
+		Long: `Build your micros locally like the builder does, to catch wrong commands and includes before pushing.
+
+For every micro, the files of its src that would be pushed are copied to a temporary workspace, where its commands are run.
+The workspace of a micro always has the same path, so that restored virtualenvs keep working.
+The dependencies of Node.js micros are installed first. Every include path has to exist once the commands are done.
+
+The included paths, or the whole workspace if the micro has none, are collected in an artifact per micro in the output directory.
+
+Dependency and build cache directories like node_modules and .venv are cached in ~/.detaspace/cache, keyed by the workspace,
+the engine, the commands and the lockfiles of the micro, and restored before its commands. Use space cache to inspect and prune the cache.`,
+		Args:     cobra.NoArgs,
+		PreRunE:  utils.CheckAll(utils.CheckExists("dir"), checkSymlinkPolicy("symlinks")),
+		PostRunE: utils.CheckLatestVersion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir, _ := cmd.Flags().GetString("dir")
+			micro, _ := cmd.Flags().GetString("micro")
+			output, _ := cmd.Flags().GetString("output")
+			zipArtifacts, _ := cmd.Flags().GetBool("zip")
+			symlinks, _ := cmd.Flags().GetString("symlinks")
+			noCache, _ := cmd.Flags().GetBool("no-cache")
+
+			if !cmd.Flags().Changed("output") {
+				output = filepath.Join(projectDir, output)
+			}
+
+			var cache *build.Cache
+			if !noCache {
+				var err error
+				if cache, err = build.DefaultCache(); err != nil {
+					return fmt.Errorf("failed to locate the build cache, %w", err)
+				}
+			}
+
+			return buildMicros(projectDir, micro, output, zipArtifacts, runtime.SymlinkPolicy(symlinks), cache)
+		},
+	}
+
+	cmd.Flags().StringP("dir", "d", "./", "src of project to build")
+	cmd.MarkFlagDirname("dir")
+	cmd.Flags().StringP("micro", "m", "", "name of the micro to build, all micros are built by default")
+	cmd.Flags().StringP("output", "o", filepath.Join(".space", "build"), "directory of the artifacts")
+	cmd.MarkFlagDirname("output")
+	cmd.Flags().Bool("zip", false, "write the artifacts as zip files")
+	cmd.Flags().String("symlinks", string(runtime.SymlinkFollow), "how to copy symlinks, one of: follow, preserve, error")
+	cmd.Flags().Bool("no-cache", false, "do not restore or save cached dependencies")
+
+	return cmd
+}
+
+func buildMicros(projectDir string, microName string, output string, zipArtifacts bool, symlinks runtime.SymlinkPolicy, cache *build.Cache) error {
+	s, err := spacefile.LoadSpacefile(projectDir)
+	if err != nil {
+		return fmt.Errorf("failed to parse your Spacefile, %w", err)
+	}
+	printMigrations(s)
+
+	micros := s.Micros
+	if microName != "" {
+		micros = nil
+		for _, micro := range s.Micros {
+			if micro.Name == microName {
+				micros = []*types.Micro{micro}
+			}
+		}
+		if micros == nil {
+			return fmt.Errorf("micro %s not found in your Spacefile", microName)
+		}
+	}
+
+	var results []*build.Result
+	for _, micro := range micros {
+		utils.Logger.Printf("\n%s Building micro %s...\n", emoji.Package, styles.Code(micro.Name))
+
+		result := build.Micro(micro, build.Options{
+			ProjectDir:  projectDir,
+			OutputDir:   output,
+			Zip:         zipArtifacts,
+			ScanOptions: runtime.ScanOptions{RespectGitignore: s.RespectGitignore, Symlinks: symlinks},
+			Cache:       cache,
+			Stdout:      writer.NewPrefixer(micro.Name, os.Stdout),
+			Stderr:      writer.NewPrefixer(micro.Name, os.Stderr),
+		})
+		if len(result.Cached) > 0 {
+			utils.Logger.Printf("Restored %s from the cache.", strings.Join(result.Cached, ", "))
+		}
+		if result.Err != nil {
+			utils.Logger.Printf("%s Failed to build micro %s: %s", emoji.ErrorExclamation, micro.Name, result.Err)
+		}
+		results = append(results, result)
+	}
+
+	failed := printBuildSummary(results)
+	if failed > 0 {
+		return fmt.Errorf("failed to build %d of %d micros", failed, len(results))
+	}
+
+	utils.Logger.Println(styles.Greenf("\n%s Successfully built your micros!", emoji.Check))
+	return nil
+}
+
+// printBuildSummary prints a line per micro and returns the number of failed builds
+func printBuildSummary(results []*build.Result) int {
+	failed := 0
+
+	utils.Logger.Printf("\nSummary:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, result := range results {
+		duration := result.Duration.Round(100 * time.Millisecond)
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(w, "  %s\t%s\t%s\t\t%d steps\t%s\n", result.Micro, styles.Error("failed"), duration, len(result.Steps), result.Err)
+			continue
+		}
+		cache := "cache miss"
+		if len(result.Cached) > 0 {
+			cache = "cache hit"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%d files, %s\t%s\n", result.Micro, styles.Green("ok"), duration, cache, result.Files, runtime.FormatSize(result.Size), result.Artifact)
+	}
+	w.Flush()
+
+	return failed
+}
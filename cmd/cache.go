@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deta/space/cmd/utils"
+	"github.com/deta/space/internal/build"
+	"github.com/deta/space/internal/runtime"
+	"github.com/deta/space/pkg/components/emoji"
+	"github.com/deta/space/pkg/components/styles"
+	"github.com/spf13/cobra"
+)
+
+func newCmdCache() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect and prune the build cache",
+		Long: `Inspect and prune the build cache.
+
+space build caches the dependencies of your micros in ~/.detaspace/cache, keyed by their engine, commands and lockfiles.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Usage()
+		},
+	}
+
+	cmd.AddCommand(newCmdCacheStats())
+	cmd.AddCommand(newCmdCachePrune())
+
+	return cmd
+}
+
+func newCmdCacheStats() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:      "stats",
+		Short:    "List the entries of the build cache",
+		Args:     cobra.NoArgs,
+		PostRunE: utils.CheckLatestVersion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cache, err := build.DefaultCache()
+			if err != nil {
+				return fmt.Errorf("failed to locate the build cache, %w", err)
+			}
+
+			entries, err := cache.Entries()
+			if err != nil {
+				return fmt.Errorf("failed to read the build cache, %w", err)
+			}
+			if len(entries) == 0 {
+				utils.Logger.Printf("The build cache is empty.")
+				return nil
+			}
+
+			var total int64
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(w, "KEY\tMICRO\tENGINE\tDIRS\tSIZE\tLAST USED\n")
+			for _, entry := range entries {
+				total += entry.Size
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", shortKey(entry.Key), entry.Micro, entry.Engine, len(entry.Dirs), runtime.FormatSize(entry.Size), entry.UsedAt.Local().Format("2006-01-02 15:04"))
+			}
+			w.Flush()
+
+			utils.Logger.Printf("\n%d entries, %s in %s", len(entries), runtime.FormatSize(total), cache.Dir)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func shortKey(key string) string {
+	if len(key) > 12 {
+		return key[:12]
+	}
+	return key
+}
+
+func newCmdCachePrune() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove old entries from the build cache",
+		Long: `Remove the entries of the build cache that were not used recently, then the least recently used entries
+until the cache fits in --max-size. Pass --all to empty the cache.`,
+		Args:     cobra.NoArgs,
+		PostRunE: utils.CheckLatestVersion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			all, _ := cmd.Flags().GetBool("all")
+			olderThan, _ := cmd.Flags().GetDuration("older-than")
+			maxSize, _ := cmd.Flags().GetInt64("max-size")
+
+			if all {
+				olderThan, maxSize = 0, 0
+			} else if olderThan <= 0 && maxSize <= 0 {
+				return fmt.Errorf("nothing to prune, pass --older-than, --max-size or --all")
+			}
+
+			cache, err := build.DefaultCache()
+			if err != nil {
+				return fmt.Errorf("failed to locate the build cache, %w", err)
+			}
+
+			removed, err := cache.Prune(olderThan, maxSize<<20)
+			if err != nil {
+				return fmt.Errorf("failed to prune the build cache, %w", err)
+			}
+
+			var freed int64
+			for _, entry := range removed {
+				freed += entry.Size
+			}
+			utils.Logger.Println(styles.Greenf("%s Removed %d entries, freed %s", emoji.Check, len(removed), runtime.FormatSize(freed)))
+			return nil
+		},
+	}
+
+	cmd.Flags().Duration("older-than", 30*24*time.Hour, "remove the entries not used for this long")
+	cmd.Flags().Int64("max-size", 0, "maximum size of the cache in MB, 0 for no limit")
+	cmd.Flags().Bool("all", false, "remove every entry")
+
+	return cmd
+}
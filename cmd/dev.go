@@ -9,16 +9,21 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
-	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/alessio/shellescape"
 	"github.com/deta/space/cmd/utils"
+	"github.com/deta/space/internal/build"
+	"github.com/deta/space/internal/devstate"
+	"github.com/deta/space/internal/probe"
 	"github.com/deta/space/internal/proxy"
 	"github.com/deta/space/internal/runtime"
 	"github.com/deta/space/internal/spacefile"
+	"github.com/deta/space/internal/supervisor"
+	"github.com/deta/space/pkg/components/dashboard"
 	"github.com/deta/space/pkg/components/emoji"
 	"github.com/deta/space/pkg/components/styles"
 	"github.com/deta/space/pkg/writer"
@@ -42,7 +47,14 @@ var (
 		types.Nuxt:      "npm run dev -- --port $PORT",
 		types.SvelteKit: "npm run dev -- --port $PORT",
 	}
+	// EngineToRunCommand are the run commands of the engines that do not need one in the Spacefile
+	EngineToRunCommand = map[string]string{
+		types.Next:      "npm run start -- --port $PORT",
+		types.Nuxt:      "node .output/server/index.mjs",
+		types.SvelteKit: "node build/index.js",
+	}
 	errNoDevCommand = errors.New("no dev command found for micro")
+	errNoRunCommand = errors.New("no run command found for micro")
 )
 
 func NewCmdDev() *cobra.Command {
@@ -51,9 +63,29 @@ func NewCmdDev() *cobra.Command {
 		Short: "Spin up a local development environment for your Space project",
 		Long: `Spin up a local development environment for your Space project.
 
-The cli will start one process for each of your micros, then expose a single enpoint for your Space app.`,
+The cli will start one process for each of your micros, then expose a single enpoint for your Space app.
+
+Pass --prod to run your micros like they run in Space instead: each micro is started from its artifact built by space build,
+with its run command, and static micros are served from their serve directory. Micros that were not built run from their included files.
+
+Each micro is added to the proxy once it is ready: its port accepts connections and, if it provides actions or sets a ready_path
+in the Spacefile, the path answers without a server error. Use --ready-timeout to wait longer for slow micros.
+
+Each micro runs in its own process group, which is stopped with it. A micro that fails is restarted with an increasing delay,
+use --restart to choose when micros are restarted and --max-restarts to limit the consecutive restarts.
 
-		PreRunE:  utils.CheckAll(utils.CheckProjectInitialized("dir"), utils.CheckNotEmpty("id")),
+Pass --watch to restart a micro when the files of its src change, the files ignored by your .spaceignore files are not watched.
+Micros with frontend or fullstack engines reload by themselves and are not restarted,
+unless they list the glob patterns of the files to watch in the watch field of the Spacefile.
+
+The running micros and proxies are recorded in .space/dev.json, use space dev ps to list them,
+and space dev stop or space dev restart to control them from another terminal.
+
+In a terminal, space dev shows a dashboard with the status, port, URL, restarts and logs of each micro.
+Use tab to switch between micros, r to restart a micro, / to filter its logs, o to open your app and a to trigger an action.
+Pass --plain, or redirect the output, to print the logs of all the micros instead.`,
+
+		PreRunE:  utils.CheckAll(utils.CheckProjectInitialized("dir"), utils.CheckNotEmpty("id"), checkRestartPolicy("restart")),
 		PostRunE: utils.CheckLatestVersion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var err error
@@ -63,6 +95,15 @@ The cli will start one process for each of your micros, then expose a single enp
 			host, _ := cmd.Flags().GetString("host")
 			port, _ := cmd.Flags().GetInt("port")
 			open, _ := cmd.Flags().GetBool("open")
+			overlays, _ := cmd.Flags().GetStringArray("overlay")
+			prod, _ := cmd.Flags().GetBool("prod")
+			artifacts, _ := cmd.Flags().GetString("artifacts")
+			watchFiles, _ := cmd.Flags().GetBool("watch")
+			readyTimeout, _ := cmd.Flags().GetDuration("ready-timeout")
+			plain, _ := cmd.Flags().GetBool("plain")
+			if !cmd.Flags().Changed("artifacts") {
+				artifacts = filepath.Join(projectDir, artifacts)
+			}
 
 			if !cmd.Flags().Changed("id") {
 				projectID, err = runtime.GetProjectID(projectDir)
@@ -78,7 +119,12 @@ The cli will start one process for each of your micros, then expose a single enp
 				}
 			}
 
-			if err := dev(projectDir, projectID, host, port, open); err != nil {
+			var artifactsDir string
+			if prod {
+				artifactsDir = artifacts
+			}
+
+			if err := dev(projectDir, projectID, host, port, open, overlays, artifactsDir, watchFiles, readyTimeout, restartOptions(cmd), useDashboard(plain)); err != nil {
 				return err
 			}
 
@@ -89,6 +135,9 @@ The cli will start one process for each of your micros, then expose a single enp
 	cmd.AddCommand(newCmdDevUp())
 	cmd.AddCommand(newCmdDevProxy())
 	cmd.AddCommand(newCmdDevTrigger())
+	cmd.AddCommand(newCmdDevPs())
+	cmd.AddCommand(newCmdDevStop())
+	cmd.AddCommand(newCmdDevRestart())
 	cmd.AddCommand(newCmdServe())
 
 	cmd.Flags().StringP("dir", "d", ".", "directory of the project")
@@ -96,6 +145,15 @@ The cli will start one process for each of your micros, then expose a single enp
 	cmd.Flags().IntP("port", "p", 0, "port to run the proxy on")
 	cmd.Flags().StringP("host", "H", "localhost", "host to run the proxy on")
 	cmd.Flags().Bool("open", false, "open the app in the browser")
+	cmd.Flags().Bool("prod", false, "run the micros from their build artifacts with their run commands")
+	cmd.Flags().String("artifacts", filepath.Join(".space", "build"), "directory of the build artifacts used by --prod")
+	cmd.MarkFlagDirname("artifacts")
+	cmd.Flags().Bool("watch", false, "restart micros when their files change")
+	cmd.MarkFlagsMutuallyExclusive("watch", "prod")
+	cmd.Flags().Duration("ready-timeout", probe.DefaultTimeout, "how long to wait for each micro to be ready")
+	addRestartFlags(cmd)
+	cmd.Flags().Bool("plain", false, "print the logs of the micros instead of showing the dashboard")
+	cmd.PersistentFlags().StringArray("overlay", []string{}, "overlay to merge over the Spacefile, after Spacefile.dev and Spacefile.local")
 
 	return cmd
 }
@@ -116,14 +174,35 @@ func GetFreePort(start int) (int, error) {
 	return 0, errors.New("no free port found")
 }
 
-func dev(projectDir string, projectID string, host string, port int, open bool) error {
+// loadDevSpacefile loads the Spacefile with the dev overlays merged over it
+// and its variables expanded from the env and the .env file of the project
+func loadDevSpacefile(projectDir string, overlays []string) (*spacefile.Spacefile, error) {
+	s, err := spacefile.LoadSpacefileWithOverlays(projectDir, spacefile.Overlays(projectDir, overlays))
+	if err != nil {
+		return nil, err
+	}
+	printMigrations(s)
+
+	lookup, err := spacefile.EnvLookup(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Interpolate(lookup); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// dev runs the micros of the project behind the proxy, from their build artifacts in artifactsDir if it is set.
+// If watchFiles is set, micros are restarted when their files change, and if showDashboard is set they are shown in the dashboard.
+func dev(projectDir string, projectID string, host string, port int, open bool, overlays []string, artifactsDir string, watchFiles bool, readyTimeout time.Duration, restartOpts supervisor.Options, showDashboard bool) error {
 	meta, err := runtime.GetProjectMeta(projectDir)
 	if err != nil {
 		return err
 	}
 
-	routeDir := filepath.Join(projectDir, ".space", "micros")
-	spacefile, err := spacefile.LoadSpacefile(projectDir)
+	spacefile, err := loadDevSpacefile(projectDir, overlays)
 	if err != nil {
 		return fmt.Errorf("failed to parse Spacefile: %w", err)
 	}
@@ -134,46 +213,143 @@ func dev(projectDir string, projectID string, host string, port int, open bool)
 	}
 	addr := fmt.Sprintf("%s:%d", host, port)
 
+	wg := sync.WaitGroup{}
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	reverseProxy := proxy.NewReverseProxy(projectKey, meta.ID, meta.Name, meta.Alias)
+
+	// the micros and the proxy are recorded in the dev state, and controlled by space dev stop and space dev restart
+	registry := devstate.Open(projectDir)
+	defer registry.RemoveOwner(os.Getpid())
+	controller := newMicroController(cancelFunc, false)
+	controlAddr, controlToken, err := devstate.Serve(ctx, controller)
+	if err != nil {
+		return err
+	}
+	restartOpts = recordMicroEvents(registry, restartOpts)
+
 	utils.Logger.Printf("\n%s Checking for running micros...", emoji.Eyes)
+	running, err := runningMicros(registry)
+	if err != nil {
+		return err
+	}
+
+	// microPorts are the ports of the micros, found or started, to trigger their actions from the dashboard
+	microPorts := map[string]int{}
+	var dash *dashboard.Dashboard
+	if showDashboard {
+		dash = dashboard.New(&dashboard.Input{
+			Title:    meta.Name,
+			ProxyURL: fmt.Sprintf("http://%s", addr),
+			Actions:  devActions(spacefile.Micros),
+			Restart: func(name string) error {
+				if process, err := controller.process(name); err == nil {
+					process.Restart("requested from the dashboard")
+					return nil
+				}
+				if entry, ok := running[name]; ok {
+					return devstate.Restart(entry, name)
+				}
+				return fmt.Errorf("micro %s is not running", name)
+			},
+			Trigger: func(action dashboard.Action) (string, error) {
+				port, ok := microPorts[action.Micro]
+				if !ok {
+					return "", fmt.Errorf("micro %s is not running", action.Micro)
+				}
+				return triggerMicroAction(port, action.ID)
+			},
+			OpenURL: browser.OpenURL,
+		})
+		defer dash.Close()
+		browser.Stdout, browser.Stderr = dash.Log(""), dash.Log("")
+		restartOpts = showMicroEvents(dash, restartOpts)
+	}
+
 	var stoppedMicros []*types.Micro
 	for _, micro := range spacefile.Micros {
-		_, err := getMicroPort(micro, routeDir)
-		if err != nil {
+		entry, ok := running[micro.Name]
+		if !ok {
 			stoppedMicros = append(stoppedMicros, micro)
 			continue
 		}
 
+		spaceUrl := fmt.Sprintf("http://%s%s", addr, micro.Path)
 		utils.Logger.Printf("\nMicro %s found", styles.Green(micro.Name))
-		utils.Logger.Printf("L url: %s", styles.Blue(fmt.Sprintf("http://%s%s", addr, micro.Path)))
+		utils.Logger.Printf("L url: %s", styles.Blue(spaceUrl))
+		go registerMicro(ctx, reverseProxy, micro, entry.Port, readyTimeout)
+
+		microPorts[micro.Name] = entry.Port
+		if dash != nil {
+			dash.AddMicro(dashboard.Micro{Name: micro.Name, Port: entry.Port, URL: spaceUrl})
+			dash.SetStatus(dashboard.Status{Name: micro.Name, Status: entry.Status, PID: entry.PID, Restarts: entry.Restarts})
+			fmt.Fprintf(dash.Log(micro.Name), "Micro %s is run by the space process %d, its logs are printed there\n", micro.Name, entry.Owner)
+		}
 	}
 
 	startPort := port + 1
 
-	wg := sync.WaitGroup{}
-	ctx, cancelFunc := context.WithCancel(context.Background())
-	defer cancelFunc()
+	var artifacts map[string]string
+	if artifactsDir != "" {
+		var cleanup func()
+		artifacts, cleanup, err = prodArtifacts(projectDir, spacefile, stoppedMicros, artifactsDir)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+	}
 
 	utils.Logger.Printf("\n%s Starting %d micro servers...\n\n", emoji.Laptop, len(stoppedMicros))
 	for _, micro := range stoppedMicros {
+		micro := micro
 		freePort, err := GetFreePort(startPort)
 		if err != nil {
 			return err
 		}
 
-		command, err := MicroCommand(micro, projectDir, projectKey, freePort, ctx)
+		// the command is created again every time the micro is started
+		newCommand := func() (*exec.Cmd, error) {
+			var command *exec.Cmd
+			var err error
+			if artifacts != nil {
+				command, err = ProdMicroCommand(micro, artifacts[micro.Name], projectKey, freePort)
+			} else {
+				command, err = MicroCommand(micro, projectDir, projectKey, freePort, ctx)
+			}
+			if err == nil && dash != nil {
+				command.Stdout = dash.Log(micro.Name)
+				command.Stderr = command.Stdout
+			}
+			return command, err
+		}
+		command, err := newCommand()
 		if err != nil {
 			if errors.Is(err, errNoDevCommand) {
 				utils.Logger.Printf("%s micro %s has no dev command\n", emoji.X, micro.Name)
 				utils.Logger.Printf("See %s to get started\n", styles.Blue(spaceDevDocsURL))
 				continue
 			}
+			if errors.Is(err, errNoRunCommand) {
+				utils.Logger.Printf("%s micro %s has no run command\n", emoji.X, micro.Name)
+				continue
+			}
+			return err
 		}
 
-		portFile := filepath.Join(routeDir, fmt.Sprintf("%s.port", micro.Name))
-		if err := writePortFile(portFile, freePort); err != nil {
+		if err := registry.Register(devstate.Entry{
+			Kind:      devstate.KindMicro,
+			Name:      micro.Name,
+			Status:    string(supervisor.StatusStarting),
+			Port:      freePort,
+			Command:   strings.Join(command.Args, " "),
+			StartedAt: time.Now(),
+			Owner:     os.Getpid(),
+			Control:   controlAddr,
+			Token:     controlToken,
+		}); err != nil {
 			return err
 		}
-		defer os.Remove(portFile)
 
 		startPort = freePort + 1
 
@@ -185,30 +361,54 @@ func dev(projectDir string, projectID string, host string, port int, open bool)
 		spaceUrl := fmt.Sprintf("http://%s%s", addr, micro.Path)
 		utils.Logger.Printf("L url: %s\n\n", styles.Blue(spaceUrl))
 
+		microPorts[micro.Name] = freePort
+		if dash != nil {
+			dash.AddMicro(dashboard.Micro{Name: micro.Name, Port: freePort, URL: spaceUrl})
+		}
+
+		// the micro is added to the proxy once it is ready
+		go registerMicro(ctx, reverseProxy, micro, freePort, readyTimeout)
+
+		process := supervisor.New(micro.Name, newCommand, restartOpts)
+		controller.add(micro.Name, process)
+		if watchFiles && watchMicro(micro) {
+			go watchMicroFiles(ctx, projectDir, micro, runtime.ScanOptions{RespectGitignore: spacefile.RespectGitignore}, process)
+		}
+
 		wg.Add(1)
-		go func(command *exec.Cmd) {
+		go func() {
 			defer wg.Done()
-			err := command.Run()
-			if err != nil {
-				if errors.Is(err, exec.ErrNotFound) {
-					utils.Logger.Printf("%s Command not found: %s", emoji.ErrorExclamation, command.Args[0])
-					return
-				}
-				utils.Logger.Printf("Command `%s` exited.", command.String())
-				cancelFunc()
+			if err := process.Run(ctx); err != nil {
+				utils.Logger.Printf("%s Failed to run micro %s: %s", emoji.ErrorExclamation, styles.Green(micro.Name), err)
 			}
-		}(command)
+		}()
 	}
 
-	time.Sleep(3 * time.Second)
-	proxy := proxy.NewReverseProxy(projectKey, meta.ID, meta.Name, meta.Alias)
-	if err := loadMicrosFromDir(proxy, spacefile.Micros, routeDir); err != nil {
+	server := http.Server{
+		Addr:    addr,
+		Handler: reverseProxy,
+	}
+	if err := registerProxy(registry, host, port, controlAddr, controlToken); err != nil {
 		return err
 	}
 
-	server := http.Server{
-		Addr:    addr,
-		Handler: proxy,
+	if dash != nil {
+		utils.Logger.SetOutput(dash.Log(""))
+		utils.StdErrLogger.SetOutput(dash.Log(""))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := dash.Run()
+			utils.Logger.SetOutput(os.Stdout)
+			utils.StdErrLogger.SetOutput(os.Stderr)
+			if err != nil {
+				// the micros keep running, their logs are printed instead
+				utils.StdErrLogger.Printf("%s Failed to show the dashboard: %s", emoji.ErrorExclamation, err)
+				return
+			}
+			cancelFunc()
+		}()
 	}
 
 	wg.Add(1)
@@ -230,6 +430,9 @@ func dev(projectDir string, projectID string, host string, port int, open bool)
 		case <-ctx.Done():
 		}
 
+		if dash != nil {
+			dash.Quit()
+		}
 		utils.Logger.Printf("\n\nShutting down...\n\n")
 		server.Shutdown(context.Background())
 	}()
@@ -248,69 +451,131 @@ func dev(projectDir string, projectID string, host string, port int, open bool)
 	return nil
 }
 
-func writePortFile(portfile string, port int) error {
-	portDir := filepath.Dir(portfile)
-	if _, err := os.Stat(portDir); os.IsNotExist(err) {
-		if err := os.MkdirAll(portDir, 0755); err != nil {
-			return err
-		}
-	}
+// prodArtifacts finds the build artifact of every micro, micros that were not built are staged from their included files.
+// The staged artifacts are removed by the returned cleanup function.
+func prodArtifacts(projectDir string, s *spacefile.Spacefile, micros []*types.Micro, artifactsDir string) (map[string]string, func(), error) {
+	artifacts := map[string]string{}
 
-	return os.WriteFile(portfile, []byte(fmt.Sprintf("%d", port)), 0644)
-}
+	stagingDir, err := os.MkdirTemp("", "space-dev-prod-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		os.RemoveAll(stagingDir)
+	}
 
-func loadMicrosFromDir(proxy *proxy.ReverseProxy, micros []*types.Micro, routeDir string) error {
 	for _, micro := range micros {
-		portFile := filepath.Join(routeDir, fmt.Sprintf("%s.port", micro.Name))
-		if _, err := os.Stat(portFile); err != nil {
+		artifact := build.ArtifactDir(artifactsDir, micro)
+		if info, err := os.Stat(artifact); err == nil && info.IsDir() {
+			// commands run in the artifact, so paths relative to the project would not resolve
+			if artifacts[micro.Name], err = filepath.Abs(artifact); err != nil {
+				cleanup()
+				return nil, nil, err
+			}
 			continue
 		}
 
-		microPort, err := parsePort(portFile)
-		if err != nil {
-			continue
+		utils.Logger.Printf("%s Micro %s was not built, running it from its included files. Run %s to build it.", emoji.LightBulb, styles.Green(micro.Name), styles.Code("space build"))
+		result := build.Micro(micro, build.Options{
+			ProjectDir:   projectDir,
+			OutputDir:    stagingDir,
+			SkipCommands: true,
+			ScanOptions:  runtime.ScanOptions{RespectGitignore: s.RespectGitignore},
+		})
+		if result.Err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to stage micro %s, %w", micro.Name, result.Err)
 		}
+		artifacts[micro.Name] = result.Artifact
+	}
 
-		n, err := proxy.AddMicro(micro, microPort)
-		if err != nil {
-			return err
+	return artifacts, cleanup, nil
+}
+
+// runningMicros returns the micros recorded in the dev state by their name
+func runningMicros(registry *devstate.Registry) (map[string]devstate.Entry, error) {
+	entries, err := registry.List()
+	if err != nil {
+		return nil, err
+	}
+
+	micros := map[string]devstate.Entry{}
+	for _, e := range entries {
+		if e.Kind == devstate.KindMicro {
+			micros[e.Name] = e
 		}
+	}
+	return micros, nil
+}
 
-		if n != 0 {
-			utils.Logger.Printf("\nExtracted %d actions from %s.", n, micro.Name)
-			utils.Logger.Printf("L Preview URL: %s\n\n", "https://deta.space?devServer=http://localhost:4200")
+// registerRunningMicros adds the micros recorded in the dev state to the proxy, each once it is ready
+func registerRunningMicros(ctx context.Context, reverseProxy *proxy.ReverseProxy, micros []*types.Micro, running map[string]devstate.Entry, readyTimeout time.Duration) {
+	for _, micro := range micros {
+		entry, ok := running[micro.Name]
+		if !ok {
+			continue
 		}
+
+		go registerMicro(ctx, reverseProxy, micro, entry.Port, readyTimeout)
 	}
+}
 
-	return nil
+// registerProxy records a proxy of this space process in the dev state
+func registerProxy(registry *devstate.Registry, host string, port int, controlAddr, controlToken string) error {
+	return registry.Register(devstate.Entry{
+		Kind:      devstate.KindProxy,
+		Name:      fmt.Sprintf("%s:%d", host, port),
+		Status:    string(supervisor.StatusRunning),
+		PID:       os.Getpid(),
+		Host:      host,
+		Port:      port,
+		StartedAt: time.Now(),
+		Owner:     os.Getpid(),
+		Control:   controlAddr,
+		Token:     controlToken,
+	})
 }
 
-func getMicroPort(micro *types.Micro, routeDir string) (int, error) {
-	portFile := filepath.Join(routeDir, fmt.Sprintf("%s.port", micro.Name))
-	if _, err := os.Stat(portFile); err != nil {
-		return 0, err
+// registerMicro waits until a micro is ready, then adds it to the proxy and extracts its actions.
+// A micro that does not become ready in time is still routed to, without its actions.
+func registerMicro(ctx context.Context, reverseProxy *proxy.ReverseProxy, micro *types.Micro, port int, readyTimeout time.Duration) {
+	start := time.Now()
+	err := probe.Wait(ctx, port, probe.Options{Path: microProbePath(micro), Timeout: readyTimeout})
+	if errors.Is(err, context.Canceled) {
+		return
 	}
-
-	port, err := parsePort(portFile)
 	if err != nil {
-		return 0, err
+		reverseProxy.AddRoute(micro, port)
+		utils.Logger.Printf("\n%s Micro %s is not ready on port %d: %s", emoji.X, styles.Green(micro.Name), port, err)
+		if micro.ProvideActions {
+			utils.Logger.Printf("L its actions were not extracted, restart space dev once it starts correctly")
+		}
+		utils.Logger.Printf("L check its logs above, requests to it are forwarded anyway\n\n")
+		return
 	}
 
-	if !utils.IsPortActive(port) {
-		return 0, fmt.Errorf("port %d is not active", port)
+	n, err := reverseProxy.AddMicro(micro, port)
+	if err != nil {
+		utils.Logger.Printf("\n%s Failed to extract the actions of micro %s: %s\n\n", emoji.ErrorExclamation, styles.Green(micro.Name), err)
+		return
 	}
+	utils.Logger.Printf("%s Micro %s is ready (%s)", emoji.Check, styles.Green(micro.Name), time.Since(start).Round(time.Millisecond))
 
-	return port, nil
+	if n != 0 {
+		utils.Logger.Printf("\nExtracted %d actions from %s.", n, micro.Name)
+		utils.Logger.Printf("L Preview URL: %s\n\n", "https://deta.space?devServer=http://localhost:4200")
+	}
 }
 
-func parsePort(portFile string) (int, error) {
-	// check if the port is already in use
-	portStr, err := os.ReadFile(portFile)
-	if err != nil {
-		return 0, err
+// microProbePath is the path requested to check that a micro is ready, micros that provide actions have to list them
+func microProbePath(micro *types.Micro) string {
+	if micro.ReadyPath != "" {
+		return micro.ReadyPath
 	}
-
-	return strconv.Atoi(string(portStr))
+	if micro.ProvideActions {
+		return proxy.ActionEndpoint
+	}
+	return ""
 }
 
 func MicroCommand(micro *types.Micro, directory, projectKey string, port int, ctx context.Context) (*exec.Cmd, error) {
@@ -323,15 +588,44 @@ func MicroCommand(micro *types.Micro, directory, projectKey string, port int, ct
 		if root == "" {
 			root = micro.Src
 		}
-		devCommand = fmt.Sprintf("%s dev serve %s --port %d", shellescape.Quote(os.Args[0]), shellescape.Quote(root), port)
+		devCommand = serveCommand(root, port)
 	} else if EngineToDevCommand[micro.Engine] != "" {
 		devCommand = EngineToDevCommand[micro.Engine]
 	} else {
 		return nil, errNoDevCommand
 	}
 
-	commandDir := filepath.Join(directory, micro.Src)
+	return microCommand(micro, devCommand, filepath.Join(directory, micro.Src), projectKey, port)
+}
+
+// ProdMicroCommand runs a micro from its build artifact with its run command, or serves it if it is static
+func ProdMicroCommand(micro *types.Micro, artifactDir, projectKey string, port int) (*exec.Cmd, error) {
+	var runCommand string
+
+	if micro.Run != "" {
+		runCommand = micro.Run
+	} else if micro.Engine == "static" || (types.IsFrontendEngine(micro.Engine) && micro.Serve != "") {
+		// the artifact of a static micro holds its serve directory, relative to its src
+		root := micro.Serve
+		if root == "" {
+			root = "."
+		}
+		runCommand = serveCommand(filepath.Join(artifactDir, root), port)
+	} else if EngineToRunCommand[micro.Engine] != "" {
+		runCommand = EngineToRunCommand[micro.Engine]
+	} else {
+		return nil, errNoRunCommand
+	}
 
+	return microCommand(micro, runCommand, artifactDir, projectKey, port)
+}
+
+func serveCommand(root string, port int) string {
+	return fmt.Sprintf("%s dev serve %s --port %d", shellescape.Quote(os.Args[0]), shellescape.Quote(root), port)
+}
+
+// microEnviron is the environment of the process of a micro
+func microEnviron(micro *types.Micro, projectKey string, port int) map[string]string {
 	environ := map[string]string{
 		"PORT":                      fmt.Sprintf("%d", port),
 		"DETA_PROJECT_KEY":          projectKey,
@@ -359,7 +653,13 @@ func MicroCommand(micro *types.Micro, directory, projectKey string, port int, ct
 		}
 	}
 
-	fields, err := shell.Fields(devCommand, func(s string) string {
+	return environ
+}
+
+func microCommand(micro *types.Micro, command string, commandDir string, projectKey string, port int) (*exec.Cmd, error) {
+	environ := microEnviron(micro, projectKey, port)
+
+	fields, err := shell.Fields(command, func(s string) string {
 		if env, ok := environ[s]; ok {
 			return env
 		}
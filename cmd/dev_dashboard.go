@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/deta/space/cmd/utils"
+	"github.com/deta/space/internal/supervisor"
+	"github.com/deta/space/pkg/components/dashboard"
+	types "github.com/deta/space/shared"
+	"github.com/mattn/go-isatty"
+)
+
+// useDashboard reports whether space dev shows the dashboard, which needs a terminal to draw on and to read keys from
+func useDashboard(plain bool) bool {
+	return !plain && utils.IsOutputInteractive() && isatty.IsTerminal(os.Stdin.Fd())
+}
+
+// devActions lists the actions of the micros that can be triggered from the dashboard
+func devActions(micros []*types.Micro) []dashboard.Action {
+	var actions []dashboard.Action
+	for _, micro := range micros {
+		for _, action := range micro.Actions {
+			actions = append(actions, dashboard.Action{ID: action.ID, Name: action.Name, Micro: micro.Name})
+		}
+	}
+	return actions
+}
+
+// showMicroEvents shows the status transitions of the micros in the dashboard, after handling them like opts does
+func showMicroEvents(dash *dashboard.Dashboard, opts supervisor.Options) supervisor.Options {
+	onEvent := opts.OnEvent
+	opts.OnEvent = func(e supervisor.Event) {
+		if onEvent != nil {
+			onEvent(e)
+		}
+
+		dash.SetStatus(dashboard.Status{
+			Name:     e.Name,
+			Status:   string(e.Status),
+			PID:      e.PID,
+			Restarts: e.Restarts,
+			Err:      e.Err,
+		})
+	}
+	return opts
+}
+
+// triggerMicroAction triggers an action of a micro running on port and returns its response
+func triggerMicroAction(port int, actionID string) (string, error) {
+	res, err := postScheduledAction(port, actionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to trigger action, %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the response of the action, %w", err)
+	}
+
+	output := fmt.Sprintf("%s\n%s", res.Status, body)
+	if res.StatusCode >= 400 {
+		return output, fmt.Errorf("the action failed with %s", res.Status)
+	}
+	return output, nil
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/deta/space/cmd/utils"
+	"github.com/deta/space/internal/devstate"
+	"github.com/deta/space/internal/supervisor"
+	"github.com/deta/space/pkg/components/emoji"
+	"github.com/deta/space/pkg/components/styles"
+	"github.com/spf13/cobra"
+)
+
+// addRestartFlags adds the flags configuring how micros are restarted
+func addRestartFlags(cmd *cobra.Command) {
+	cmd.Flags().String("restart", string(supervisor.RestartOnFailure), "when to restart a micro that exits, one of: never, on-failure, always")
+	cmd.Flags().Int("max-restarts", supervisor.DefaultMaxRestarts, "maximum number of consecutive restarts of a micro, 0 to never restart it, -1 for no limit")
+}
+
+func checkRestartPolicy(flagName string) utils.PreRunFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		policy, _ := cmd.Flags().GetString(flagName)
+		for _, p := range supervisor.RestartPolicies {
+			if supervisor.RestartPolicy(policy) == p {
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid restart policy %s, must be one of: never, on-failure, always", policy)
+	}
+}
+
+// restartOptions reads the restart flags, the events of the micros are logged
+func restartOptions(cmd *cobra.Command) supervisor.Options {
+	policy, _ := cmd.Flags().GetString("restart")
+	maxRestarts, _ := cmd.Flags().GetInt("max-restarts")
+
+	return supervisor.Options{
+		Policy:      supervisor.RestartPolicy(policy),
+		MaxRestarts: maxRestarts,
+		OnEvent:     logMicroEvent,
+	}
+}
+
+// logMicroEvent prints the status transitions of a micro that matter in the plain output
+func logMicroEvent(e supervisor.Event) {
+	name := styles.Green(e.Name)
+
+	switch e.Status {
+	case supervisor.StatusRunning:
+		if e.Restarts > 0 {
+			utils.Logger.Printf("%s Micro %s restarted (restart %d)", emoji.Check, name, e.Restarts)
+		}
+	case supervisor.StatusRestarting:
+		if e.Reason != "" {
+			utils.Logger.Printf("%s Restarting micro %s, %s", emoji.Tools, name, e.Reason)
+		} else if e.Err != nil {
+			utils.Logger.Printf("%s Micro %s exited: %s, restarting it in %s", emoji.ErrorExclamation, name, e.Err, e.Delay)
+		} else if e.Delay > 0 {
+			utils.Logger.Printf("%s Micro %s exited, restarting it in %s", emoji.Tools, name, e.Delay)
+		}
+	case supervisor.StatusFailed:
+		utils.Logger.Printf("%s Micro %s failed: %s", emoji.X, name, e.Err)
+		if e.Restarts > 0 {
+			utils.Logger.Printf("L it was restarted %d times, check its logs above", e.Restarts)
+		}
+	case supervisor.StatusExited:
+		utils.Logger.Printf("Micro %s exited.", name)
+	}
+}
+
+// recordMicroEvents records the status transitions of the micros in the dev state, after handling them like opts does
+func recordMicroEvents(registry *devstate.Registry, opts supervisor.Options) supervisor.Options {
+	onEvent := opts.OnEvent
+	opts.OnEvent = func(e supervisor.Event) {
+		if onEvent != nil {
+			onEvent(e)
+		}
+
+		err := registry.Update(devstate.KindMicro, e.Name, os.Getpid(), func(entry *devstate.Entry) {
+			entry.Status = string(e.Status)
+			entry.PID = e.PID
+			entry.Restarts = e.Restarts
+			if e.Status == supervisor.StatusRunning {
+				entry.StartedAt = e.Time
+			}
+		})
+		if err != nil {
+			utils.StdErrLogger.Printf("%s Failed to record the status of micro %s: %s", emoji.ErrorExclamation, styles.Green(e.Name), err)
+		}
+	}
+	return opts
+}
+
+// microController handles space dev stop and space dev restart for the micros supervised by this space process
+type microController struct {
+	// shutdown stops the space process
+	shutdown func()
+	// stopExits stops the space process when one of its micros is stopped, for space dev up
+	stopExits bool
+
+	mu        sync.Mutex
+	processes map[string]*supervisor.Process
+}
+
+func newMicroController(shutdown func(), stopExits bool) *microController {
+	return &microController{
+		shutdown:  shutdown,
+		stopExits: stopExits,
+		processes: map[string]*supervisor.Process{},
+	}
+}
+
+func (c *microController) add(name string, process *supervisor.Process) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.processes[name] = process
+}
+
+func (c *microController) process(name string) (*supervisor.Process, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	process, ok := c.processes[name]
+	if !ok {
+		return nil, fmt.Errorf("micro %s is not run by space process %d", name, os.Getpid())
+	}
+	return process, nil
+}
+
+func (c *microController) Stop(name string) error {
+	if name == "" {
+		c.shutdown()
+		return nil
+	}
+
+	process, err := c.process(name)
+	if err != nil {
+		return err
+	}
+	if c.stopExits {
+		c.shutdown()
+		return nil
+	}
+	process.Stop()
+	return nil
+}
+
+func (c *microController) Restart(name string) error {
+	process, err := c.process(name)
+	if err != nil {
+		return err
+	}
+	process.Restart("requested by space dev restart")
+	return nil
+}
+
+// proxyController handles space dev stop for space dev proxy, which runs no micros
+type proxyController struct {
+	shutdown func()
+}
+
+func (c proxyController) Stop(name string) error {
+	if name != "" {
+		return fmt.Errorf("micro %s is not run by space process %d", name, os.Getpid())
+	}
+	c.shutdown()
+	return nil
+}
+
+func (c proxyController) Restart(name string) error {
+	return fmt.Errorf("micro %s is not run by space process %d", name, os.Getpid())
+}
+
+// describeChanges lists the first changed files for the logs
+func describeChanges(paths []string) string {
+	const shown = 3
+	if len(paths) <= shown {
+		return strings.Join(paths, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(paths[:shown], ", "), len(paths)-shown)
+}
@@ -6,14 +6,15 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/deta/space/cmd/utils"
+	"github.com/deta/space/internal/devstate"
+	"github.com/deta/space/internal/probe"
 	"github.com/deta/space/internal/proxy"
 	"github.com/deta/space/internal/runtime"
-	"github.com/deta/space/internal/spacefile"
 	"github.com/deta/space/pkg/components/emoji"
 	"github.com/deta/space/pkg/components/styles"
 	"github.com/pkg/browser"
@@ -26,7 +27,7 @@ func newCmdDevProxy() *cobra.Command {
 		Short: "Start a reverse proxy for your micros",
 		Long: `Start a reverse proxy for your micros
 
-The micros will be automatically discovered and proxied to.`,
+The micros will be automatically discovered and proxied to, each once it is ready.`,
 		PreRunE:  utils.CheckProjectInitialized("dir"),
 		PostRunE: utils.CheckLatestVersion,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -36,6 +37,8 @@ The micros will be automatically discovered and proxied to.`,
 			host, _ := cmd.Flags().GetString("host")
 			port, _ := cmd.Flags().GetInt("port")
 			open, _ := cmd.Flags().GetBool("open")
+			overlays, _ := cmd.Flags().GetStringArray("overlay")
+			readyTimeout, _ := cmd.Flags().GetDuration("ready-timeout")
 
 			if !cmd.Flags().Changed("port") {
 				port, err = GetFreePort(utils.DevPort)
@@ -44,7 +47,7 @@ The micros will be automatically discovered and proxied to.`,
 				}
 			}
 
-			if err := devProxy(directory, host, port, open); err != nil {
+			if err := devProxy(directory, host, port, open, overlays, readyTimeout); err != nil {
 				return err
 			}
 
@@ -56,11 +59,12 @@ The micros will be automatically discovered and proxied to.`,
 	cmd.Flags().IntP("port", "p", 0, "port to run the proxy on")
 	cmd.Flags().StringP("host", "H", "localhost", "host to run the proxy on")
 	cmd.Flags().Bool("open", false, "open the app in the browser")
+	cmd.Flags().Duration("ready-timeout", probe.DefaultTimeout, "how long to wait for each micro to be ready")
 
 	return cmd
 }
 
-func devProxy(projectDir string, host string, port int, open bool) error {
+func devProxy(projectDir string, host string, port int, open bool, overlays []string, readyTimeout time.Duration) error {
 	meta, err := runtime.GetProjectMeta(projectDir)
 	if err != nil {
 		return err
@@ -68,13 +72,20 @@ func devProxy(projectDir string, host string, port int, open bool) error {
 
 	addr := fmt.Sprintf("%s:%d", host, port)
 
-	microDir := filepath.Join(projectDir, ".space", "micros")
-	spacefile, _ := spacefile.LoadSpacefile(projectDir)
+	spacefile, err := loadDevSpacefile(projectDir, overlays)
+	if err != nil {
+		return fmt.Errorf("failed to parse Spacefile: %w", err)
+	}
 
-	if entries, err := os.ReadDir(microDir); err != nil || len(entries) == 0 {
+	registry := devstate.Open(projectDir)
+	running, err := runningMicros(registry)
+	if err != nil {
+		return err
+	}
+	if len(running) == 0 {
 		utils.Logger.Printf("%s No running micros detected.", emoji.X)
 		utils.Logger.Printf("L Use %s to manually start a micro", styles.Blue("space dev up <micro>"))
-		return err
+		return nil
 	}
 
 	projectKey, err := utils.GenerateDataKeyIfNotExists(meta.ID)
@@ -82,13 +93,21 @@ func devProxy(projectDir string, host string, port int, open bool) error {
 		return fmt.Errorf("failed to generate project key: %w", err)
 	}
 
-	reverseProxy := proxy.NewReverseProxy(projectKey, meta.ID, meta.Name, meta.Alias)
-	if err := loadMicrosFromDir(reverseProxy, spacefile.Micros, microDir); err != nil {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	controlAddr, controlToken, err := devstate.Serve(ctx, proxyController{shutdown: cancel})
+	if err != nil {
 		return err
 	}
-	if err != nil {
+	if err := registerProxy(registry, host, port, controlAddr, controlToken); err != nil {
 		return err
 	}
+	defer registry.RemoveOwner(os.Getpid())
+
+	reverseProxy := proxy.NewReverseProxy(projectKey, meta.ID, meta.Name, meta.Alias)
+	registerRunningMicros(ctx, reverseProxy, spacefile.Micros, running, readyTimeout)
+
 	server := &http.Server{
 		Addr:    addr,
 		Handler: reverseProxy,
@@ -105,7 +124,11 @@ func devProxy(projectDir string, host string, port int, open bool) error {
 	go func() {
 		sigs := make(chan os.Signal, 1)
 		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-		<-sigs
+		select {
+		case <-sigs:
+			cancel()
+		case <-ctx.Done():
+		}
 		utils.Logger.Printf("\n\nShutting down...\n\n")
 		server.Shutdown(context.Background())
 	}()
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deta/space/cmd/utils"
+	"github.com/deta/space/internal/devstate"
+	"github.com/deta/space/pkg/components/styles"
+	"github.com/spf13/cobra"
+)
+
+func newCmdDevPs() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ps",
+		Short: "List the running micros and proxies of your project",
+		Long: `List the micros and proxies run by space dev, space dev up and space dev proxy in your project,
+with their status, process id, port, uptime, restarts and command.
+
+Micros marked as orphaned kept running after the space process that ran them exited, stop them with space dev stop.`,
+		Args:     cobra.NoArgs,
+		PreRunE:  utils.CheckProjectInitialized("dir"),
+		PostRunE: utils.CheckLatestVersion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir, _ := cmd.Flags().GetString("dir")
+
+			entries, err := devstate.Open(projectDir).List()
+			if err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				utils.Logger.Printf("No micros are running.")
+				utils.Logger.Printf("L Use %s or %s to start them", styles.Blue("space dev"), styles.Blue("space dev up <micro>"))
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(w, "NAME\tTYPE\tSTATUS\tPID\tPORT\tUPTIME\tRESTARTS\tCOMMAND\n")
+			for _, e := range entries {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%d\t%s\n", e.Name, e.Kind, e.Status, formatPID(e.PID), e.Port, formatUptime(e), e.Restarts, e.Command)
+			}
+			w.Flush()
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("dir", "d", ".", "directory of the project")
+
+	return cmd
+}
+
+func formatPID(pid int) string {
+	if pid == 0 {
+		return "-"
+	}
+	return fmt.Sprint(pid)
+}
+
+// formatUptime is how long an entry has been running since it was last started
+func formatUptime(e devstate.Entry) string {
+	if !e.Running() || e.StartedAt.IsZero() {
+		return "-"
+	}
+	return time.Since(e.StartedAt).Round(time.Second).String()
+}
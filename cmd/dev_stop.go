@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/deta/space/cmd/utils"
+	"github.com/deta/space/internal/devstate"
+	"github.com/deta/space/internal/supervisor"
+	"github.com/deta/space/pkg/components/emoji"
+	"github.com/deta/space/pkg/components/styles"
+	"github.com/spf13/cobra"
+)
+
+func newCmdDevStop() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stop [micro]",
+		Short: "Stop the running micros of your project",
+		Long: `Stop a running micro, or everything run by space dev, space dev up and space dev proxy in your project.
+
+A micro run by space dev stays stopped until it is restarted with space dev restart, a micro run by space dev up exits.
+Micros left running by a space process that exited are stopped too.`,
+		Args:     cobra.MaximumNArgs(1),
+		PreRunE:  utils.CheckProjectInitialized("dir"),
+		PostRunE: utils.CheckLatestVersion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir, _ := cmd.Flags().GetString("dir")
+
+			var microName string
+			if len(args) > 0 {
+				microName = args[0]
+			}
+
+			if err := devStop(projectDir, microName); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("dir", "d", ".", "directory of the project")
+
+	return cmd
+}
+
+func newCmdDevRestart() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restart <micro>",
+		Short: "Restart a running micro of your project",
+		Long: `Restart a micro run by space dev or space dev up, even if it was stopped or failed.
+The logs of the micro are printed by the space process running it.`,
+		Args:     cobra.ExactArgs(1),
+		PreRunE:  utils.CheckProjectInitialized("dir"),
+		PostRunE: utils.CheckLatestVersion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir, _ := cmd.Flags().GetString("dir")
+
+			if err := devRestart(projectDir, args[0]); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("dir", "d", ".", "directory of the project")
+
+	return cmd
+}
+
+// devStop stops a micro, or everything recorded in the dev state if microName is empty
+func devStop(projectDir string, microName string) error {
+	registry := devstate.Open(projectDir)
+	entries, err := registry.List()
+	if err != nil {
+		return err
+	}
+
+	var targets []devstate.Entry
+	for _, e := range entries {
+		if microName == "" || (e.Kind == devstate.KindMicro && e.Name == microName) {
+			targets = append(targets, e)
+		}
+	}
+	if len(targets) == 0 {
+		if microName != "" {
+			utils.Logger.Printf("%s Micro %s is not running", emoji.X, styles.Green(microName))
+			return fmt.Errorf("micro %s is not running", microName)
+		}
+		utils.Logger.Printf("No micros are running.")
+		return nil
+	}
+
+	// a space process stops everything it runs at once, micros left running by a space process that exited are terminated
+	stoppedOwners := map[int]bool{}
+	for _, e := range targets {
+		if e.Orphaned() {
+			if err := supervisor.Terminate(e.PID, supervisor.DefaultGracePeriod); err != nil {
+				return fmt.Errorf("failed to stop micro %s, %w", e.Name, err)
+			}
+			if err := registry.Remove(devstate.KindMicro, e.Name, e.Owner); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if microName == "" && stoppedOwners[e.Owner] {
+			continue
+		}
+		if err := devstate.Stop(e, microName); err != nil {
+			return fmt.Errorf("failed to stop %s %s, %w", e.Kind, e.Name, err)
+		}
+		stoppedOwners[e.Owner] = true
+	}
+
+	if err := waitStopped(registry, targets); err != nil {
+		return err
+	}
+	for _, e := range targets {
+		utils.Logger.Printf("%s Stopped %s %s", emoji.Check, e.Kind, styles.Green(e.Name))
+	}
+	return nil
+}
+
+// waitStopped waits until the entries are stopped or removed from the dev state
+func waitStopped(registry *devstate.Registry, targets []devstate.Entry) error {
+	deadline := time.Now().Add(supervisor.DefaultGracePeriod + 5*time.Second)
+	for {
+		entries, err := registry.List()
+		if err != nil {
+			return err
+		}
+
+		var pending *devstate.Entry
+		for i, e := range entries {
+			for _, target := range targets {
+				if e.Kind == target.Kind && e.Name == target.Name && e.Owner == target.Owner && e.Status != string(supervisor.StatusStopped) {
+					pending = &entries[i]
+				}
+			}
+		}
+		if pending == nil {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for %s %s to stop, it is %s", pending.Kind, pending.Name, pending.Status)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func devRestart(projectDir string, microName string) error {
+	entry, ok, err := devstate.Open(projectDir).Micro(microName)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		utils.Logger.Printf("%s Micro %s is not running, to start it run:", emoji.X, styles.Green(microName))
+		utils.Logger.Printf("L %s", styles.Blue(fmt.Sprintf("space dev up %s", microName)))
+		return fmt.Errorf("micro %s is not running", microName)
+	}
+	if entry.Orphaned() {
+		utils.Logger.Printf("%s Micro %s was left running by a space process that exited, to restart it run:", emoji.X, styles.Green(microName))
+		utils.Logger.Printf("L %s", styles.Blue(fmt.Sprintf("space dev stop %s && space dev up %s", microName, microName)))
+		return fmt.Errorf("micro %s is not controlled by a running space process", microName)
+	}
+
+	if err := devstate.Restart(entry, microName); err != nil {
+		return fmt.Errorf("failed to restart micro %s, %w", microName, err)
+	}
+	utils.Logger.Printf("%s Restarting micro %s, its logs are printed by the space process %d", emoji.Check, styles.Green(microName), entry.Owner)
+	return nil
+}
@@ -8,11 +8,10 @@ import (
 	"io"
 	"net/http"
 	"os"
-	"path/filepath"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/deta/space/cmd/utils"
-	"github.com/deta/space/internal/spacefile"
+	"github.com/deta/space/internal/devstate"
 	"github.com/deta/space/pkg/components/emoji"
 	"github.com/deta/space/pkg/components/styles"
 	"github.com/deta/space/shared"
@@ -32,12 +31,13 @@ Make sure that the corresponding micro is running before triggering the action.`
 			experimental, _ := cmd.Flags().GetBool("experimental")
 			if !experimental {
 				projectDir, _ := cmd.Flags().GetString("dir")
+				overlays, _ := cmd.Flags().GetStringArray("overlay")
 
 				if len(args) == 0 {
 					return errors.New("action name is required")
 				}
 
-				if err := triggerScheduledAction(projectDir, args[0]); err != nil {
+				if err := triggerScheduledAction(projectDir, args[0], overlays); err != nil {
 					return err
 				}
 
@@ -140,12 +140,12 @@ Make sure that the corresponding micro is running before triggering the action.`
 	return cmd
 }
 
-func triggerScheduledAction(projectDir string, actionID string) (err error) {
-	spacefile, err := spacefile.LoadSpacefile(projectDir)
+func triggerScheduledAction(projectDir string, actionID string, overlays []string) (err error) {
+	spacefile, err := loadDevSpacefile(projectDir, overlays)
 	if err != nil {
 		return fmt.Errorf("failed to parse Spacefile: %w", err)
 	}
-	routeDir := filepath.Join(projectDir, ".space", "micros")
+	registry := devstate.Open(projectDir)
 
 	for _, micro := range spacefile.Micros {
 		for _, action := range micro.Actions {
@@ -154,31 +154,24 @@ func triggerScheduledAction(projectDir string, actionID string) (err error) {
 			}
 
 			utils.Logger.Printf("\n%s Checking if micro %s is running...\n", emoji.Eyes, styles.Green(micro.Name))
-			port, err := getMicroPort(micro, routeDir)
+			entry, ok, err := registry.Micro(micro.Name)
 			if err != nil {
+				return err
+			}
+			if !ok || !entry.Running() {
 				upCommand := fmt.Sprintf("space dev up %s", micro.Name)
 				utils.Logger.Printf("%s Micro %s is not running, to start it run:", emoji.X, styles.Green(micro.Name))
 				utils.Logger.Printf("L %s", styles.Blue(upCommand))
-				return err
+				return fmt.Errorf("micro %s is not running", micro.Name)
 			}
+			port := entry.Port
 
 			utils.Logger.Printf("%s Micro %s is running", styles.Green("✔️"), styles.Green(micro.Name))
 
-			body, err := json.Marshal(shared.ActionRequest{
-				Event: shared.ActionEvent{
-					ID:      actionID,
-					Trigger: "schedule",
-				},
-			})
-			if err != nil {
-				return err
-			}
-
-			actionEndpoint := fmt.Sprintf("http://localhost:%d/%s", port, actionEndpoint)
 			utils.Logger.Printf("\nTriggering action %s", styles.Green(actionID))
-			utils.Logger.Printf("L POST %s", styles.Blue(actionEndpoint))
+			utils.Logger.Printf("L POST %s", styles.Blue(scheduledActionURL(port)))
 
-			res, err := http.Post(actionEndpoint, "application/json", bytes.NewReader(body))
+			res, err := postScheduledAction(port, actionID)
 			if err != nil {
 				return fmt.Errorf("failed to trigger action: %w", err)
 			}
@@ -200,3 +193,22 @@ func triggerScheduledAction(projectDir string, actionID string) (err error) {
 	}
 	return fmt.Errorf("\n%s action `%s` not found", emoji.X, actionID)
 }
+
+func scheduledActionURL(port int) string {
+	return fmt.Sprintf("http://localhost:%d/%s", port, actionEndpoint)
+}
+
+// postScheduledAction triggers an action of a micro running on port, like Space does on the schedule of the action
+func postScheduledAction(port int, actionID string) (*http.Response, error) {
+	body, err := json.Marshal(shared.ActionRequest{
+		Event: shared.ActionEvent{
+			ID:      actionID,
+			Trigger: "schedule",
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return http.Post(scheduledActionURL(port), "application/json", bytes.NewReader(body))
+}
@@ -5,13 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"os/signal"
-	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/deta/space/cmd/utils"
+	"github.com/deta/space/internal/devstate"
 	"github.com/deta/space/internal/runtime"
-	"github.com/deta/space/internal/spacefile"
+	"github.com/deta/space/internal/supervisor"
 	"github.com/deta/space/pkg/components/emoji"
 	"github.com/deta/space/pkg/components/styles"
 	"github.com/pkg/browser"
@@ -22,7 +25,7 @@ func newCmdDevUp() *cobra.Command {
 	devUpCmd := &cobra.Command{
 		Short:    "Start a single micro for local development",
 		Use:      "up <micro>",
-		PreRunE:  utils.CheckAll(utils.CheckProjectInitialized("dir"), utils.CheckNotEmpty("id")),
+		PreRunE:  utils.CheckAll(utils.CheckProjectInitialized("dir"), utils.CheckNotEmpty("id"), checkRestartPolicy("restart")),
 		PostRunE: utils.CheckLatestVersion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var err error
@@ -31,6 +34,7 @@ func newCmdDevUp() *cobra.Command {
 			projectID, _ := cmd.Flags().GetString("id")
 			port, _ := cmd.Flags().GetInt("port")
 			open, _ := cmd.Flags().GetBool("open")
+			overlays, _ := cmd.Flags().GetStringArray("overlay")
 
 			if !cmd.Flags().Changed("id") {
 				projectID, err = runtime.GetProjectID(projectDir)
@@ -46,7 +50,7 @@ func newCmdDevUp() *cobra.Command {
 				}
 			}
 
-			if err := devUp(projectDir, projectID, port, args[0], open); err != nil {
+			if err := devUp(projectDir, projectID, port, args[0], open, overlays, restartOptions(cmd)); err != nil {
 				return err
 			}
 
@@ -58,13 +62,14 @@ func newCmdDevUp() *cobra.Command {
 	devUpCmd.Flags().StringP("id", "i", "", "project id")
 	devUpCmd.Flags().IntP("port", "p", 0, "port to run the micro on")
 	devUpCmd.Flags().Bool("open", false, "open the app in the browser")
+	addRestartFlags(devUpCmd)
 
 	return devUpCmd
 }
 
-func devUp(projectDir string, projectId string, port int, microName string, open bool) (err error) {
+func devUp(projectDir string, projectId string, port int, microName string, open bool, overlays []string, restartOpts supervisor.Options) (err error) {
 
-	spacefile, err := spacefile.LoadSpacefile(projectDir)
+	spacefile, err := loadDevSpacefile(projectDir, overlays)
 	if err != nil {
 		return fmt.Errorf("failed to parse Spacefile: %w", err)
 	}
@@ -79,17 +84,22 @@ func devUp(projectDir string, projectId string, port int, microName string, open
 			continue
 		}
 
-		portFile := filepath.Join(projectDir, ".space", "micros", fmt.Sprintf("%s.port", microName))
-		if _, err := os.Stat(portFile); err == nil {
-			microPort, _ := parsePort(portFile)
-			if utils.IsPortActive(microPort) {
-				utils.Logger.Printf("%s %s is already running on port %d", emoji.X, styles.Green(microName), microPort)
-			}
+		registry := devstate.Open(projectDir)
+		if entry, ok, err := registry.Micro(microName); err != nil {
+			return err
+		} else if ok {
+			utils.Logger.Printf("%s %s is already running on port %d", emoji.X, styles.Green(microName), entry.Port)
+			utils.Logger.Printf("L Use %s to stop it", styles.Blue(fmt.Sprintf("space dev stop %s", microName)))
+			return fmt.Errorf("micro %s is already running", microName)
 		}
 
-		writePortFile(portFile, port)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
 
-		command, err := MicroCommand(micro, projectDir, projectKey, port, context.Background())
+		newCommand := func() (*exec.Cmd, error) {
+			return MicroCommand(micro, projectDir, projectKey, port, ctx)
+		}
+		command, err := newCommand()
 		if err != nil {
 			if errors.Is(err, errNoDevCommand) {
 				utils.Logger.Printf("%s micro %s has no dev command\n", emoji.X, micro.Name)
@@ -98,20 +108,36 @@ func devUp(projectDir string, projectId string, port int, microName string, open
 			}
 			return err
 		}
-		defer os.Remove(portFile)
 
-		if err := command.Start(); err != nil {
-			return fmt.Errorf("failed to start %s: %s", styles.Green(microName), err.Error())
+		// the micro is recorded in the dev state, and controlled by space dev stop and space dev restart
+		controller := newMicroController(cancel, true)
+		controlAddr, controlToken, err := devstate.Serve(ctx, controller)
+		if err != nil {
+			return err
+		}
+		if err := registry.Register(devstate.Entry{
+			Kind:      devstate.KindMicro,
+			Name:      micro.Name,
+			Status:    string(supervisor.StatusStarting),
+			Port:      port,
+			Command:   strings.Join(command.Args, " "),
+			StartedAt: time.Now(),
+			Owner:     os.Getpid(),
+			Control:   controlAddr,
+			Token:     controlToken,
+		}); err != nil {
+			return err
 		}
+		defer registry.RemoveOwner(os.Getpid())
 
-		// If we receive a SIGINT or SIGTERM, we want to send a SIGTERM to the child process
+		// If we receive a SIGINT or SIGTERM, the micro is stopped along with its child processes
 		go func() {
 			sigs := make(chan os.Signal, 1)
 			signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 			<-sigs
 			utils.Logger.Printf("\n\nShutting down...\n\n")
 
-			command.Process.Signal(syscall.SIGTERM)
+			cancel()
 		}()
 
 		if open {
@@ -122,7 +148,13 @@ func devUp(projectDir string, projectId string, port int, microName string, open
 		utils.Logger.Printf("\n%s Micro %s running on %s", styles.Green("✔️"), styles.Green(microName), styles.Blue(microUrl))
 		utils.Logger.Printf("\n%s Use %s to emulate the routing of your Space app\n\n", emoji.LightBulb, styles.Blue("space dev proxy"))
 
-		command.Wait()
+		// dev up exits with the error of the micro once it is not restarted anymore
+		restartOpts.ReturnOnExit = true
+		process := supervisor.New(micro.Name, newCommand, recordMicroEvents(registry, restartOpts))
+		controller.add(micro.Name, process)
+		if err := process.Run(ctx); err != nil {
+			return fmt.Errorf("micro %s exited, %w", micro.Name, err)
+		}
 		return nil
 	}
 	return fmt.Errorf("micro %s not found", microName)
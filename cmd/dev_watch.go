@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/deta/space/cmd/utils"
+	"github.com/deta/space/internal/runtime"
+	"github.com/deta/space/internal/supervisor"
+	"github.com/deta/space/internal/watch"
+	"github.com/deta/space/pkg/components/emoji"
+	"github.com/deta/space/pkg/components/styles"
+	types "github.com/deta/space/shared"
+)
+
+// watchMicro reports whether a micro is restarted when its files change in watch mode.
+// Frontend and fullstack engines reload by themselves, unless the micro sets its own watch globs.
+func watchMicro(micro *types.Micro) bool {
+	if len(micro.Watch) > 0 {
+		return true
+	}
+	return !types.IsFrontendEngine(micro.Engine) && !types.IsFullstackEngine(micro.Engine)
+}
+
+// watchMicroFiles watches the src of a micro until ctx is done, and restarts its process when files change
+func watchMicroFiles(ctx context.Context, projectDir string, micro *types.Micro, scanOpts runtime.ScanOptions, process *supervisor.Process) {
+	err := watch.Watch(ctx, projectDir, micro.Src, watch.Options{ScanOptions: scanOpts, Globs: micro.Watch}, func(paths []string) {
+		process.Restart(fmt.Sprintf("%s changed", describeChanges(paths)))
+	})
+	if err != nil {
+		utils.Logger.Printf("%s Failed to watch micro %s, it will not be restarted on changes: %s", emoji.ErrorExclamation, styles.Green(micro.Name), err)
+	}
+}
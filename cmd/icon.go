@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/deta/space/cmd/utils"
+	"github.com/deta/space/internal/discovery"
+	"github.com/deta/space/internal/runtime"
+	"github.com/deta/space/internal/spacefile"
+	"github.com/deta/space/pkg/components/emoji"
+	"github.com/deta/space/pkg/components/styles"
+	"github.com/spf13/cobra"
+)
+
+const defaultIconName = "icon.png"
+
+func newCmdIcon() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "icon",
+		Short: "Generate and convert your app icon",
+		Long: `Generate and convert your app icon.
+
+App icons are 512x512 PNG files, space push converts the icon of your Spacefile automatically.
+If none is set, space push generates one from the app name unless --no-generate-icon is used.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Usage()
+		},
+	}
+
+	cmd.AddCommand(newCmdIconGenerate())
+	cmd.AddCommand(newCmdIconConvert())
+
+	return cmd
+}
+
+func newCmdIconGenerate() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate a placeholder icon from the name of your app",
+		Long: `Generate a placeholder icon with the initials of your app on a colored background.
+
+The name is read from the Discovery file or the project by default, the same name always gives the same icon.`,
+		Args:     cobra.NoArgs,
+		PreRunE:  utils.CheckExists("dir"),
+		PostRunE: utils.CheckLatestVersion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir, _ := cmd.Flags().GetString("dir")
+			output, _ := cmd.Flags().GetString("output")
+			setIcon, _ := cmd.Flags().GetBool("set")
+
+			name, _ := cmd.Flags().GetString("name")
+			if !cmd.Flags().Changed("name") {
+				name = resolveAppName(projectDir, nil)
+			}
+
+			icon, err := spacefile.GenerateIcon(name)
+			if err != nil {
+				return fmt.Errorf("failed to generate icon, %w", err)
+			}
+
+			return writeIcon(projectDir, output, icon, setIcon)
+		},
+	}
+
+	cmd.Flags().StringP("dir", "d", "./", "src of project")
+	cmd.MarkFlagDirname("dir")
+	cmd.Flags().StringP("name", "n", "", "name of the app to generate the icon from")
+	cmd.Flags().StringP("output", "o", defaultIconName, "path of the generated icon, relative to the project")
+	cmd.Flags().Bool("set", false, "set the generated icon in the Spacefile")
+
+	return cmd
+}
+
+func newCmdIconConvert() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "convert <src>",
+		Short: "Convert an image to an app icon",
+		Long: `Convert a PNG, JPEG, GIF, WebP or SVG image to a 512x512 PNG app icon.
+
+The image is cropped to a square around its center before being resized.`,
+		Args:     cobra.ExactArgs(1),
+		PreRunE:  utils.CheckExists("dir"),
+		PostRunE: utils.CheckLatestVersion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir, _ := cmd.Flags().GetString("dir")
+			output, _ := cmd.Flags().GetString("output")
+			setIcon, _ := cmd.Flags().GetBool("set")
+
+			icon, err := spacefile.ConvertIcon(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to convert %s, %w", args[0], err)
+			}
+
+			return writeIcon(projectDir, output, icon, setIcon)
+		},
+	}
+
+	cmd.Flags().StringP("dir", "d", "./", "src of project")
+	cmd.MarkFlagDirname("dir")
+	cmd.Flags().StringP("output", "o", defaultIconName, "path of the converted icon, relative to the project")
+	cmd.Flags().Bool("set", false, "set the converted icon in the Spacefile")
+
+	return cmd
+}
+
+func writeIcon(projectDir string, output string, icon *spacefile.Icon, setIcon bool) error {
+	outputPath := output
+	if !filepath.IsAbs(outputPath) {
+		outputPath = filepath.Join(projectDir, output)
+	}
+
+	if err := os.WriteFile(outputPath, icon.Raw, 0644); err != nil {
+		return fmt.Errorf("failed to write icon, %w", err)
+	}
+	utils.Logger.Println(styles.Greenf("\n%s Saved your icon to %s", emoji.Check, output))
+
+	if !setIcon {
+		return nil
+	}
+
+	if err := editSpacefile(projectDir, func(doc *spacefile.Document) error {
+		return doc.Set("icon", filepath.ToSlash(output))
+	}); err != nil {
+		return err
+	}
+	utils.Logger.Println(styles.Greenf("%s Set the icon in the Spacefile", emoji.Check))
+
+	return nil
+}
+
+// resolveAppName finds the name of the app from the Discovery file, the Spacefile, the project or its directory
+func resolveAppName(projectDir string, s *spacefile.Spacefile) string {
+	if discoveryData, err := discovery.ReadDiscoveryFile(filepath.Join(projectDir, discovery.DiscoveryFilename)); err == nil && discoveryData.AppName != "" {
+		return discoveryData.AppName
+	}
+
+	if s != nil && s.AppName != "" {
+		return s.AppName
+	}
+
+	if meta, err := runtime.GetProjectMeta(projectDir); err == nil && meta.Name != "" {
+		return meta.Name
+	}
+
+	abs, err := filepath.Abs(projectDir)
+	if err != nil {
+		return projectDir
+	}
+	return filepath.Base(abs)
+}
+
+// appIcon converts the icon of the Spacefile. If none is set, it generates one if generate is set and returns nil otherwise.
+func appIcon(projectDir string, s *spacefile.Spacefile, generate bool) (*spacefile.Icon, error) {
+	if s.Icon == "" {
+		if !generate {
+			return nil, nil
+		}
+		return spacefile.GenerateIcon(resolveAppName(projectDir, s))
+	}
+
+	iconPath := s.Icon
+	if !filepath.IsAbs(iconPath) {
+		iconPath = filepath.Join(projectDir, iconPath)
+	}
+	return spacefile.ConvertIcon(iconPath)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/deta/space/cmd/utils"
+	"github.com/deta/space/internal/spacefile"
+	"github.com/deta/space/pkg/components/emoji"
+	"github.com/deta/space/pkg/components/styles"
+	"github.com/spf13/cobra"
+)
+
+func newCmdLint() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint [flags]",
+		Short: "Check your Spacefile for problems beyond its schema",
+		Long: `Check your Spacefile for problems beyond its schema, such as colliding micro paths,
+missing include entries, public routes that never match or unused env presets.
+
+A rule can be disabled with a comment in the Spacefile:
+
+  # space-lint-disable <rule>             for the whole file
+  # space-lint-disable-next-line <rule>   for the next line
+  # space-lint-disable-line <rule>        at the end of a line, for that line
+
+Only the Spacefile is linted, the Discovery.md file is not checked.
+
+Use --output json or --output sarif to get every diagnostic of the Spacefile in a machine-readable format.
+In these modes the exit code reflects the highest severity found: 2 for errors, 1 for warnings and 0 otherwise.`,
+		PostRunE: checkLatestVersionForTextOutput("output"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir, _ := cmd.Flags().GetString("dir")
+			output, _ := cmd.Flags().GetString("output")
+			listRules, _ := cmd.Flags().GetBool("rules")
+
+			if listRules {
+				printLintRules()
+				return nil
+			}
+
+			if err := lint(projectDir, output); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		PreRunE: utils.CheckAll(utils.CheckExists("dir"), checkOutputFormat("output")),
+	}
+	cmd.Flags().StringP("dir", "d", "./", "src of project to lint")
+	cmd.Flags().StringP("output", "o", outputText, "output format, one of: text, json, sarif")
+	cmd.Flags().Bool("rules", false, "list the lint rules")
+
+	return cmd
+}
+
+func printLintRules() {
+	for _, rule := range spacefile.LintRules() {
+		utils.Logger.Printf("%s (%s)\n  %s\n", styles.Code(rule.ID), rule.Severity, rule.Description)
+	}
+}
+
+func lint(projectDir string, output string) error {
+	if output == outputText {
+		utils.Logger.Printf("\n%s Linting your Spacefile...\n", emoji.Package)
+	}
+
+	diagnostics, err := spacefile.Lint(projectDir)
+	if err != nil {
+		return fmt.Errorf("failed to parse Spacefile, %w", err)
+	}
+
+	switch output {
+	case outputJSON:
+		if err := spacefile.WriteJSON(os.Stdout, diagnostics); err != nil {
+			return err
+		}
+		return diagnosticsExitError(diagnostics)
+	case outputSARIF:
+		if err := spacefile.WriteSARIF(os.Stdout, diagnostics, utils.SpaceVersion); err != nil {
+			return err
+		}
+		return diagnosticsExitError(diagnostics)
+	}
+
+	return printDiagnostics(projectDir, diagnostics, "No problems found in your Spacefile!")
+}
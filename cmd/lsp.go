@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/deta/space/cmd/utils"
+	"github.com/deta/space/internal/lsp"
+	"github.com/spf13/cobra"
+)
+
+func newCmdLSP() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lsp",
+		Short: "Start a language server for Spacefiles",
+		Long: `Start a language server for Spacefiles, speaking the Language Server Protocol over stdio.
+
+Point your editor to "space lsp" to get live diagnostics, engine completion, field documentation on hover
+and go to definition for src, icon and include paths.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// stdout is reserved for the protocol, nothing else can be printed on it
+			return lsp.NewServer(os.Stdin, os.Stdout, utils.SpaceVersion).Run()
+		},
+	}
+
+	// most editors pass --stdio to language servers, it is accepted and ignored as stdio is the only transport
+	cmd.Flags().Bool("stdio", true, "communicate over stdio")
+	cmd.Flags().MarkHidden("stdio")
+
+	return cmd
+}
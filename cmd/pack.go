@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/deta/space/cmd/utils"
+	"github.com/deta/space/internal/bundle"
+	"github.com/deta/space/internal/discovery"
+	"github.com/deta/space/internal/runtime"
+	"github.com/deta/space/internal/spacefile"
+	"github.com/deta/space/pkg/components/emoji"
+	"github.com/deta/space/pkg/components/styles"
+	"github.com/spf13/cobra"
+)
+
+// bundleExt is the extension of bundles written by space pack
+const bundleExt = ".spacebundle"
+
+func newCmdPack() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pack [flags]",
+		Short: "Pack your project into a bundle that can be pushed later",
+		Long: `Pack your project into a single self-contained bundle that can be pushed later with space push --from-bundle.
+
+The bundle holds the zipped code, the Spacefile, the processed icon or one generated from the app name unless --no-generate-icon is passed, the Discovery.md file with its screenshots
+and a manifest with the digest of each of them, which is verified before the bundle is pushed.
+
+The files are scanned for secrets before being packed, like with space push.`,
+		Args:     cobra.NoArgs,
+		PreRunE:  utils.CheckAll(utils.CheckExists("dir"), checkSymlinkPolicy("symlinks")),
+		PostRunE: utils.CheckLatestVersion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir, _ := cmd.Flags().GetString("dir")
+			output, _ := cmd.Flags().GetString("output")
+			interpolate, _ := cmd.Flags().GetBool("interpolate")
+			maxSize, _ := cmd.Flags().GetInt64("max-size")
+			symlinks, _ := cmd.Flags().GetString("symlinks")
+			allowSecrets, _ := cmd.Flags().GetBool("allow-secrets")
+			noGenerateIcon, _ := cmd.Flags().GetBool("no-generate-icon")
+
+			return pack(projectDir, output, interpolate, allowSecrets, !noGenerateIcon, runtime.SymlinkPolicy(symlinks), maxSize<<20)
+		},
+	}
+
+	cmd.Flags().StringP("dir", "d", "./", "src of project to pack")
+	cmd.MarkFlagDirname("dir")
+	cmd.Flags().StringP("output", "o", "", "path of the bundle, defaults to <app name>.spacebundle")
+	cmd.Flags().Bool("interpolate", false, "expand ${VAR} variables from the env and .env in the packed Spacefile")
+	cmd.Flags().Int64("max-size", runtime.DefaultMaxArchiveSize>>20, "maximum size of the archive of your project in MB, 0 for no limit")
+	cmd.Flags().String("symlinks", string(runtime.SymlinkFollow), "how to pack symlinks, one of: follow, preserve, error")
+	cmd.Flags().Bool("allow-secrets", false, "pack even if potential secrets are found in your project")
+	cmd.Flags().Bool("no-generate-icon", false, "do not generate an icon from the app name if the Spacefile has none")
+
+	return cmd
+}
+
+func pack(projectDir string, output string, interpolate, allowSecrets, generateIcon bool, symlinks runtime.SymlinkPolicy, maxSize int64) error {
+	utils.Logger.Printf("Validating your Spacefile...")
+
+	s, err := spacefile.LoadSpacefile(projectDir)
+	if err != nil {
+		return fmt.Errorf("failed to parse your Spacefile, %w", err)
+	}
+	printMigrations(s)
+
+	raw, err := pushedSpacefile(projectDir, interpolate)
+	if err != nil {
+		return err
+	}
+
+	scanOpts := runtime.ScanOptions{RespectGitignore: s.RespectGitignore, Symlinks: symlinks}
+	if err := checkSecrets(projectDir, scanOpts, raw, interpolate, allowSecrets); err != nil {
+		return err
+	}
+
+	zippedCode, stats, err := archiveProject(projectDir, scanOpts, maxSize)
+	if err != nil {
+		return fmt.Errorf("failed to zip your project, %w", err)
+	}
+	defer func() {
+		zippedCode.Close()
+		os.Remove(zippedCode.Name())
+	}()
+
+	printSkippedFiles(stats.Skipped)
+
+	contents := bundle.Contents{
+		Code:       zippedCode,
+		CodeDigest: stats.Digest,
+		CodeFiles:  stats.Files,
+		Spacefile:  raw,
+		AutoPWA:    *s.AutoPWA,
+		CLIVersion: utils.SpaceVersion,
+	}
+
+	if icon, err := appIcon(projectDir, s, generateIcon); err != nil {
+		utils.Logger.Printf("%s Failed to process your icon, packing without it: %s", emoji.ErrorExclamation, err)
+	} else if icon != nil {
+		contents.Icon, contents.IconContentType = icon.Raw, icon.IconMeta.ContentType
+	}
+
+	if contents.Discovery, contents.Media, err = packDiscovery(projectDir); err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = bundleName(resolveAppName(projectDir, s))
+	}
+	manifest, err := writeBundle(output, contents)
+	if err != nil {
+		return fmt.Errorf("failed to write the bundle, %w", err)
+	}
+
+	utils.Logger.Printf("\n%s Packed your project (%d files, %d screenshots) into %s", emoji.Package, manifest.CodeFiles, len(manifest.Media), styles.Code(output))
+	utils.Logger.Printf("Push it with %s", styles.Codef("space push --from-bundle %s", output))
+	return nil
+}
+
+// packDiscovery reads the Discovery file and its media, if the project has one
+func packDiscovery(projectDir string) ([]byte, []bundle.Media, error) {
+	discoveryPath := filepath.Join(projectDir, discovery.DiscoveryFilename)
+	raw, err := os.ReadFile(discoveryPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the Discovery file, %w", err)
+	}
+
+	discoveryData, err := discovery.ReadDiscoveryFile(discoveryPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	screenshots, err := discovery.ParseScreenshot(discoveryData.Media)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to process Discovery data media, %w", err)
+	}
+
+	media := make([]bundle.Media, 0, len(screenshots))
+	for _, screenshot := range screenshots {
+		media = append(media, bundle.Media{Raw: screenshot.Raw, ContentType: screenshot.ContentType})
+	}
+	return raw, media, nil
+}
+
+// writeBundle writes the bundle to a temporary file first, so that a failed pack does not leave a partial bundle
+func writeBundle(path string, contents bundle.Contents) (*bundle.Manifest, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), ".space-pack-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	manifest, err := bundle.Write(f, contents)
+	if err == nil {
+		err = f.Chmod(0644)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+var bundleNameReg = regexp.MustCompile(`[^a-z0-9]+`)
+
+// bundleName is the default file name of the bundle of an app
+func bundleName(appName string) string {
+	name := strings.Trim(bundleNameReg.ReplaceAllString(strings.ToLower(appName), "-"), "-")
+	if name == "" {
+		name = "app"
+	}
+	return name + bundleExt
+}
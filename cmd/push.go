@@ -2,17 +2,24 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/deta/space/cmd/utils"
 	"github.com/deta/space/internal/api"
 	"github.com/deta/space/internal/auth"
+	"github.com/deta/space/internal/bundle"
 	"github.com/deta/space/internal/runtime"
+	"github.com/deta/space/internal/secrets"
 	"github.com/deta/space/internal/spacefile"
 	"github.com/deta/space/pkg/components/emoji"
 	"github.com/deta/space/pkg/components/styles"
@@ -32,13 +39,51 @@ Space will automatically update your Builder instance with the new revision.
 
 If you don't want to follow the logs of the build and update, pass the --skip-logs argument which will exit the process as soon as the build is started instead of waiting for it to finish.
 
-Tip: Use the .spaceignore file to exclude certain files and directories from being uploaded during push.
+If nothing changed since the last successful push, the push is skipped. Pass --force to push anyway.
+
+Pass --dry-run to list the files that would be uploaded and the files excluded by the ignore rules, without pushing.
+Use --output json with --dry-run to get the list in a machine-readable format.
+
+Pass --from-bundle to push a bundle written by space pack, the files of the project are not read.
+The project id is read from the project in --dir unless --id is passed.
+
+The icon of your Spacefile is converted to a 512x512 PNG. If none is set, an icon is generated from the app name, pass --no-generate-icon to push without one.
+
+Tip: Use .spaceignore files to exclude certain files and directories from being uploaded during push.
+A .spaceignore file applies to its directory, so each micro can have its own. Set respect_gitignore in your Spacefile to also exclude the files ignored by git.
+
+Symlinks are followed by default, except for directories outside of the project which are skipped. Use --symlinks preserve to push them as symlinks or --symlinks error to refuse them.
+Sockets and named pipes are skipped, and executable files keep their executable bit.
+
+The files are scanned for secrets like private keys, env files and cloud credentials before being uploaded, and the push is stopped if any is found.
+List the files or rules (rule:<id>) to skip in a .spaceallowlist file, add a space:allow-secret comment to a line, or pass --allow-secrets to push anyway.
 `,
-		Args:     cobra.NoArgs,
-		PreRunE:  utils.CheckAll(utils.CheckProjectInitialized("dir"), utils.CheckNotEmpty("id", "tag")),
-		PostRunE: utils.CheckLatestVersion,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkSymlinkPolicy("symlinks")(cmd, args); err != nil {
+				return err
+			}
+			if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+				return utils.CheckAll(utils.CheckExists("dir"), checkDryRunOutput("output"))(cmd, args)
+			}
+			// a bundle is pushed without reading the project, only its id is needed
+			if cmd.Flags().Changed("from-bundle") {
+				return utils.CheckAll(utils.CheckExists("from-bundle"), utils.CheckNotEmpty("id", "tag"))(cmd, args)
+			}
+			return utils.CheckAll(utils.CheckProjectInitialized("dir"), utils.CheckNotEmpty("id", "tag"))(cmd, args)
+		},
+		PostRunE: checkLatestVersionForTextOutput("output"),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			projectDir, _ := cmd.Flags().GetString("dir")
+			maxSize, _ := cmd.Flags().GetInt64("max-size")
+			symlinks, _ := cmd.Flags().GetString("symlinks")
+			allowSecrets, _ := cmd.Flags().GetBool("allow-secrets")
+
+			if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+				output, _ := cmd.Flags().GetString("output")
+				return pushDryRun(projectDir, output, runtime.SymlinkPolicy(symlinks), maxSize<<20)
+			}
+
 			projectID, _ := cmd.Flags().GetString("id")
 			if !cmd.Flags().Changed("id") {
 				var err error
@@ -53,7 +98,15 @@ Tip: Use the .spaceignore file to exclude certain files and directories from bei
 			skipLogs, _ := cmd.Flags().GetBool("skip-logs")
 			experimental, _ := cmd.Flags().GetBool("experimental")
 
-			return push(projectID, projectDir, pushTag, openInBrowser, skipLogs, experimental)
+			if bundlePath, _ := cmd.Flags().GetString("from-bundle"); bundlePath != "" {
+				return pushBundle(projectID, bundlePath, pushTag, openInBrowser, skipLogs, experimental)
+			}
+
+			interpolate, _ := cmd.Flags().GetBool("interpolate")
+			force, _ := cmd.Flags().GetBool("force")
+			noGenerateIcon, _ := cmd.Flags().GetBool("no-generate-icon")
+
+			return push(projectID, projectDir, pushTag, openInBrowser, skipLogs, experimental, interpolate, force, allowSecrets, !noGenerateIcon, runtime.SymlinkPolicy(symlinks), maxSize<<20)
 		},
 	}
 
@@ -65,40 +118,123 @@ Tip: Use the .spaceignore file to exclude certain files and directories from bei
 	cmd.Flags().BoolP("skip-logs", "", false, "skip following logs after push")
 	cmd.Flags().BoolP("experimental", "", false, "use experimental builds")
 	cmd.Flags().MarkHidden("experimental")
+	cmd.Flags().Bool("interpolate", false, "expand ${VAR} variables from the env and .env in the pushed Spacefile")
+	cmd.Flags().BoolP("force", "f", false, "push even if nothing changed since the last push")
+	cmd.Flags().Int64("max-size", runtime.DefaultMaxArchiveSize>>20, "maximum size of the archive of your project in MB, 0 for no limit")
+	cmd.Flags().String("symlinks", string(runtime.SymlinkFollow), "how to push symlinks, one of: follow, preserve, error")
+	cmd.Flags().Bool("allow-secrets", false, "push even if potential secrets are found in your project")
+	cmd.Flags().Bool("no-generate-icon", false, "do not generate an icon from the app name if the Spacefile has none")
+	cmd.Flags().Bool("dry-run", false, "list the files that would be uploaded without pushing")
+	cmd.Flags().String("output", outputText, "output format of --dry-run, one of: text, json")
+	cmd.Flags().String("from-bundle", "", "push a bundle written by space pack instead of the project")
+	cmd.MarkFlagFilename("from-bundle", "spacebundle")
+	cmd.MarkFlagsMutuallyExclusive("from-bundle", "dry-run")
+	cmd.MarkFlagsMutuallyExclusive("from-bundle", "interpolate")
+	cmd.MarkFlagsMutuallyExclusive("from-bundle", "no-generate-icon")
 
 	return cmd
 }
 
-func push(projectID, projectDir, pushTag string, openInBrowser, skipLogs, experimental bool) error {
+func push(projectID, projectDir, pushTag string, openInBrowser, skipLogs, experimental, interpolate, force, allowSecrets, generateIcon bool, symlinks runtime.SymlinkPolicy, maxSize int64) error {
 	utils.Logger.Printf("Validating your Spacefile...")
 
 	s, err := spacefile.LoadSpacefile(projectDir)
 	if err != nil {
 		return fmt.Errorf("failed to parse your Spacefile, %w", err)
 	}
+	printMigrations(s)
 
 	utils.Logger.Printf(styles.Green("\nYour Spacefile looks good, proceeding with your push!"))
 
+	raw, err := pushedSpacefile(projectDir, interpolate)
+	if err != nil {
+		return err
+	}
+
+	scanOpts := runtime.ScanOptions{RespectGitignore: s.RespectGitignore, Symlinks: symlinks}
+	if err := checkSecrets(projectDir, scanOpts, raw, interpolate, allowSecrets); err != nil {
+		return err
+	}
+
 	// push code & run build steps
-	zippedCode, nbFiles, err := runtime.ZipDir(projectDir)
+	zippedCode, stats, err := archiveProject(projectDir, scanOpts, maxSize)
 	if err != nil {
 		return fmt.Errorf("failed to zip your project, %w", err)
 	}
+	defer func() {
+		zippedCode.Close()
+		os.Remove(zippedCode.Name())
+	}()
+
+	printSkippedFiles(stats.Skipped)
+
+	// the icon is converted to a 512x512 png, or generated if missing unless opted out
+	var icon *spacefile.Icon
+	if icon, err = appIcon(projectDir, s, generateIcon); err != nil {
+		utils.Logger.Printf("%s Failed to process your icon, pushing without it: %s", emoji.ErrorExclamation, err)
+	}
+
+	contents := &runtime.PushContents{
+		ArchiveDigest: stats.Digest,
+		Spacefile:     raw,
+		GenerateIcon:  generateIcon,
+		Interpolate:   interpolate,
+		Symlinks:      symlinks,
+		MaxSize:       maxSize,
+	}
+	if icon != nil {
+		contents.Icon = icon.Raw
+	}
+	digest := runtime.PushDigest(contents)
+	if last, err := runtime.GetPushMeta(projectDir); err == nil && last.ProjectID == projectID && last.Digest == digest && !force {
+		utils.Logger.Printf("\n%s No changes since revision %s, pushed on %s.", emoji.LightBulb, styles.Code(pushedRevision(last)), last.PushedAt.Local().Format("2006-01-02 15:04"))
+		utils.Logger.Printf("Use %s to push anyway.", styles.Code("space push --force"))
+		return nil
+	}
+
+	return pushRevision(projectID, pushTag, openInBrowser, skipLogs, experimental, &revisionContents{
+		spacefile: raw,
+		icon:      icon,
+		code:      zippedCode,
+		files:     stats.Files,
+		autoPWA:   *s.AutoPWA,
+		onBuilt: func(buildID, tag string) {
+			if err := runtime.StorePushMeta(projectDir, &runtime.PushMeta{
+				ProjectID: projectID,
+				Digest:    digest,
+				Revision:  buildID,
+				Tag:       tag,
+				PushedAt:  time.Now().UTC(),
+			}); err != nil {
+				utils.Logger.Printf("%s Failed to record this push, the next push will not detect unchanged code: %s", emoji.ErrorExclamation, err)
+			}
+		},
+	})
+}
+
+// revisionContents are the files uploaded to create a new revision
+type revisionContents struct {
+	spacefile []byte
+	// icon is not pushed if it is nil
+	icon    *spacefile.Icon
+	code    io.ReadSeeker
+	files   int
+	autoPWA bool
+	// onBuilt, if set, is called once the build is complete
+	onBuilt func(buildID, tag string)
+}
 
-	build, err := utils.Client.CreateBuild(&api.CreateBuildRequest{AppID: projectID, Tag: pushTag, Experimental: experimental, AutoPWA: *s.AutoPWA})
+// pushRevision uploads the contents of a revision, then follows the build and the update of the Builder instance
+func pushRevision(projectID, pushTag string, openInBrowser, skipLogs, experimental bool, contents *revisionContents) error {
+	build, err := utils.Client.CreateBuild(&api.CreateBuildRequest{AppID: projectID, Tag: pushTag, Experimental: experimental, AutoPWA: contents.autoPWA})
 	if err != nil {
 		return fmt.Errorf("failed to start a build, %w", err)
 	}
 	utils.Logger.Printf("\n%s Successfully started your build!", emoji.Check)
 
 	// push spacefile
-	raw, err := os.ReadFile(filepath.Join(projectDir, "Spacefile"))
-	if err != nil {
-		return fmt.Errorf("failed to read Spacefile, %w", err)
-	}
-
 	_, err = utils.Client.PushSpacefile(&api.PushSpacefileRequest{
-		Manifest: raw,
+		Manifest: contents.spacefile,
 		BuildID:  build.ID,
 	})
 	if err != nil {
@@ -106,11 +242,11 @@ func push(projectID, projectDir, pushTag string, openInBrowser, skipLogs, experi
 	}
 	utils.Logger.Printf("%s Successfully pushed your Spacefile!", emoji.Check)
 
-	// // push spacefile icon
-	if icon, err := s.GetIcon(); err == nil {
+	// push spacefile icon
+	if contents.icon != nil {
 		if _, err := utils.Client.PushIcon(&api.PushIconRequest{
-			Icon:        icon.Raw,
-			ContentType: icon.IconMeta.ContentType,
+			Icon:        contents.icon.Raw,
+			ContentType: contents.icon.IconMeta.ContentType,
 			BuildID:     build.ID,
 		}); err != nil {
 			return fmt.Errorf("failed to push the icon, %w", err)
@@ -118,7 +254,7 @@ func push(projectID, projectDir, pushTag string, openInBrowser, skipLogs, experi
 	}
 
 	if _, err = utils.Client.PushCode(&api.PushCodeRequest{
-		BuildID: build.ID, ZippedCode: zippedCode,
+		BuildID: build.ID, ZippedCode: contents.code,
 	}); err != nil {
 		if errors.Is(auth.ErrNoAccessTokenFound, err) {
 			utils.Logger.Println(utils.LoginInfo())
@@ -127,7 +263,7 @@ func push(projectID, projectDir, pushTag string, openInBrowser, skipLogs, experi
 		return fmt.Errorf("failed to push your code, %w", err)
 	}
 
-	utils.Logger.Printf("\n%s Pushing your code (%d files) & running build process...\n\n", emoji.Package, nbFiles)
+	utils.Logger.Printf("\n%s Pushing your code (%d files) & running build process...\n\n", emoji.Package, contents.files)
 
 	if skipLogs {
 		b, err := utils.Client.GetBuild(&api.GetBuildRequest{BuildID: build.ID})
@@ -180,6 +316,10 @@ func push(projectID, projectDir, pushTag string, openInBrowser, skipLogs, experi
 		return fmt.Errorf("failed to push code and create a revision, please try again")
 	}
 
+	if contents.onBuilt != nil {
+		contents.onBuilt(build.ID, b.Tag)
+	}
+
 	// get promotion via build id (build id == revision id)
 	// loop until either p is not nil, err is not nil, or i is equal to `fetchPromotionRetryCount`
 	var p *api.GetReleasePromotionResponse
@@ -280,3 +420,223 @@ func push(projectID, projectDir, pushTag string, openInBrowser, skipLogs, experi
 	return nil
 
 }
+
+// pushBundle pushes the contents of a bundle written by space pack, after verifying their digests
+func pushBundle(projectID, bundlePath, pushTag string, openInBrowser, skipLogs, experimental bool) error {
+	b, err := bundle.Open(bundlePath)
+	if err != nil {
+		return fmt.Errorf("failed to open the bundle, %w", err)
+	}
+	defer b.Close()
+
+	utils.Logger.Printf("%s Verified the bundle %s, packed on %s.", emoji.Check, styles.Code(bundlePath), b.Manifest.CreatedAt.Local().Format("2006-01-02 15:04"))
+
+	raw, err := b.ReadFile(bundle.SpacefileName)
+	if err != nil {
+		return err
+	}
+	code, err := b.Code()
+	if err != nil {
+		return err
+	}
+
+	contents := &revisionContents{
+		spacefile: raw,
+		code:      code,
+		files:     b.Manifest.CodeFiles,
+		autoPWA:   b.Manifest.AutoPWA,
+	}
+	if b.Has(bundle.IconName) {
+		icon, err := b.ReadFile(bundle.IconName)
+		if err != nil {
+			return err
+		}
+		contents.icon = &spacefile.Icon{Raw: icon, IconMeta: &spacefile.IconMeta{ContentType: b.Manifest.IconContentType}}
+	}
+
+	return pushRevision(projectID, pushTag, openInBrowser, skipLogs, experimental, contents)
+}
+
+func checkSymlinkPolicy(flagName string) utils.PreRunFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		policy, _ := cmd.Flags().GetString(flagName)
+		for _, p := range runtime.SymlinkPolicies {
+			if runtime.SymlinkPolicy(policy) == p {
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid symlink policy %s, must be one of: follow, preserve, error", policy)
+	}
+}
+
+func checkDryRunOutput(flagName string) utils.PreRunFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString(flagName)
+		if output != outputText && output != outputJSON {
+			return fmt.Errorf("invalid output format %s, must be one of: text, json", output)
+		}
+		return nil
+	}
+}
+
+// pushDryRun reports the files that a push would upload, it does not contact the api
+func pushDryRun(projectDir string, output string, symlinks runtime.SymlinkPolicy, maxSize int64) error {
+	s, err := spacefile.LoadSpacefile(projectDir)
+	if err != nil {
+		return fmt.Errorf("failed to parse your Spacefile, %w", err)
+	}
+
+	report, err := runtime.Report(projectDir, runtime.ScanOptions{RespectGitignore: s.RespectGitignore, Symlinks: symlinks})
+	if err != nil {
+		return fmt.Errorf("failed to scan your project, %w", err)
+	}
+
+	if output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s Files that would be uploaded (%d):\n", emoji.Package, len(report.Files))
+	for _, file := range report.Files {
+		fmt.Fprintf(w, "  %s\t%s\n", file.Path, runtime.FormatSize(file.Size))
+	}
+	w.Flush()
+
+	fmt.Fprintf(w, "\nFiles excluded by ignore rules (%d):\n", len(report.Ignored))
+	for _, file := range report.Ignored {
+		path, source := file.Path, "default ignore list"
+		if file.Dir {
+			path += "/"
+		}
+		if file.Rule.Source != runtime.IgnoreSourceDefault {
+			source = file.Rule.Source
+		}
+		fmt.Fprintf(w, "  %s\t%s\t(%s, line %d)\n", path, file.Rule.Pattern, source, file.Rule.Line)
+	}
+	w.Flush()
+
+	printSkippedFiles(report.Skipped)
+
+	utils.Logger.Printf("\nTotal: %d files, %s (%s compressed)", len(report.Files), runtime.FormatSize(report.Size), runtime.FormatSize(report.CompressedSize))
+	if maxSize > 0 && report.CompressedSize > maxSize {
+		utils.Logger.Printf("%s The archive is larger than the maximum size of %s, the push would fail.", emoji.ErrorExclamation, runtime.FormatSize(maxSize))
+	}
+	utils.Logger.Printf("\n%s Dry run, nothing was pushed. Use the .spaceignore file to exclude more files.", emoji.LightBulb)
+
+	return nil
+}
+
+// checkSecrets scans the files to push, and the pushed Spacefile if it was interpolated, for secrets
+func checkSecrets(projectDir string, scanOpts runtime.ScanOptions, spacefileContent []byte, interpolated bool, allowSecrets bool) error {
+	files, err := runtime.ListFiles(projectDir, scanOpts)
+	if err != nil {
+		return fmt.Errorf("failed to scan your project, %w", err)
+	}
+
+	allowlist, err := secrets.LoadAllowlist(projectDir)
+	if err != nil {
+		return err
+	}
+
+	findings, err := secrets.Scan(files, allowlist)
+	if err != nil {
+		return fmt.Errorf("failed to scan your project for secrets, %w", err)
+	}
+	if interpolated {
+		spacefileFindings, err := secrets.ScanContent("Spacefile (interpolated)", bytes.NewReader(spacefileContent), allowlist)
+		if err != nil {
+			return fmt.Errorf("failed to scan your Spacefile for secrets, %w", err)
+		}
+		findings = append(findings, spacefileFindings...)
+	}
+
+	if len(findings) == 0 {
+		return nil
+	}
+
+	utils.Logger.Printf("\n%s Found %d potential secrets in the files to push:", emoji.ErrorExclamation, len(findings))
+	for _, finding := range findings {
+		utils.Logger.Printf("  %s", finding)
+	}
+
+	if allowSecrets {
+		utils.Logger.Printf("Pushing anyway as --allow-secrets was passed.")
+		return nil
+	}
+
+	utils.Logger.Printf("\n%s Exclude the files with .spaceignore, allow them in %s, or pass %s to push anyway.", emoji.LightBulb, secrets.AllowlistFile, styles.Code("--allow-secrets"))
+	return fmt.Errorf("push stopped, found %d potential secrets", len(findings))
+}
+
+func printSkippedFiles(skipped []runtime.SkippedFile) {
+	for _, file := range skipped {
+		utils.Logger.Printf("%s Skipped %s: %s", emoji.ErrorExclamation, file.Path, file.Reason)
+	}
+}
+
+// pushedSpacefile reads the Spacefile as it is pushed, with its variables expanded if interpolate is set
+func pushedSpacefile(projectDir string, interpolate bool) ([]byte, error) {
+	raw, err := os.ReadFile(filepath.Join(projectDir, "Spacefile"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Spacefile, %w", err)
+	}
+
+	// variables are only expanded on demand, their values end up in the pushed Spacefile
+	if interpolate {
+		lookup, err := spacefile.EnvLookup(projectDir)
+		if err != nil {
+			return nil, err
+		}
+		if raw, err = spacefile.InterpolateContent(raw, lookup); err != nil {
+			return nil, fmt.Errorf("failed to interpolate your Spacefile, %w", err)
+		}
+		utils.Logger.Printf("%s Variables were expanded in the pushed Spacefile, make sure it does not contain secrets", emoji.ErrorExclamation)
+	}
+
+	// migrated Spacefiles are pushed with the only version known by Space
+	if raw, err = spacefile.PushedContent(raw); err != nil {
+		return nil, fmt.Errorf("failed to set the version of your Spacefile, %w", err)
+	}
+
+	return raw, nil
+}
+
+func pushedRevision(p *runtime.PushMeta) string {
+	if p.Tag != "" {
+		return fmt.Sprintf("%s (%s)", p.Tag, p.Revision)
+	}
+	return p.Revision
+}
+
+// archiveProject zips the project to a temporary file, which is streamed when pushed.
+// The caller has to close and remove the file.
+func archiveProject(projectDir string, scanOpts runtime.ScanOptions, maxSize int64) (*os.File, *runtime.ArchiveStats, error) {
+	f, err := os.CreateTemp("", "space-push-*.zip")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts := runtime.ArchiveOptions{ScanOptions: scanOpts, MaxSize: maxSize}
+	if utils.IsOutputInteractive() {
+		opts.Progress = func(p runtime.ArchiveProgress) {
+			fmt.Fprintf(os.Stdout, "\r%s Zipping your project... %d/%d files (%s)", emoji.Package, p.Files, p.Total, runtime.FormatSize(p.Written))
+			if p.Files == p.Total {
+				fmt.Fprintln(os.Stdout)
+			}
+		}
+	}
+
+	stats, err := runtime.Archive(f, projectDir, opts)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, nil, err
+	}
+
+	return f, stats, nil
+}
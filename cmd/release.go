@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"bufio"
-	"bytes"
 	"errors"
 	"fmt"
 	"os"
@@ -12,7 +11,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/adrg/frontmatter"
 	"github.com/spf13/cobra"
 
 	"github.com/deta/space/cmd/utils"
@@ -326,18 +324,11 @@ func getDiscoveryData(projectDir string, spacefile *spacefile.Spacefile) (*share
 		return nil, err
 	}
 
-	df, err := os.ReadFile(discoveryPath)
+	discoveryData, err := discovery.ReadDiscoveryFile(discoveryPath)
 	if err != nil {
 		return nil, err
 	}
 
-	discoveryData := &shared.DiscoveryData{}
-	rest, err := frontmatter.Parse(bytes.NewReader(df), &discoveryData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse the Discovery file, %w", err)
-	}
-
-	discoveryData.ContentRaw = string(rest)
 	if discoveryData.AppName == "" {
 		utils.Logger.Printf("\nNo app name found in Discovery file. Using the app name from your Spacefile: %s", styles.Code(spacefile.AppName))
 		utils.Logger.Printf("Using the app name from your Spacefile is deprecated and will be removed in a future version.\n\n")
@@ -31,12 +31,19 @@ Complete documentation available at %s`, utils.DocsUrl),
 	cmd.AddCommand(newCmdLogin())
 	cmd.AddCommand(newCmdLink())
 	cmd.AddCommand(newCmdPush())
+	cmd.AddCommand(newCmdBuild())
+	cmd.AddCommand(newCmdPack())
+	cmd.AddCommand(newCmdCache())
 	cmd.AddCommand(newCmdExec())
 	cmd.AddCommand(NewCmdDev())
 	cmd.AddCommand(newCmdNew())
 	cmd.AddCommand(NewCmdVersion(utils.SpaceVersion, utils.Platform))
 	cmd.AddCommand(newCmdOpen())
 	cmd.AddCommand(newCmdValidate())
+	cmd.AddCommand(newCmdLint())
+	cmd.AddCommand(newCmdLSP())
+	cmd.AddCommand(newCmdSpacefile())
+	cmd.AddCommand(newCmdIcon())
 	cmd.AddCommand(newCmdRelease())
 	cmd.AddCommand(newCmdAPI())
 	cmd.AddCommand(newCmdPrintAccessToken())
@@ -0,0 +1,421 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/deta/space/cmd/utils"
+	"github.com/deta/space/internal/discovery"
+	"github.com/deta/space/internal/spacefile"
+	"github.com/deta/space/pkg/components/choose"
+	"github.com/deta/space/pkg/components/emoji"
+	"github.com/deta/space/pkg/components/styles"
+	"github.com/deta/space/pkg/components/text"
+	"github.com/deta/space/pkg/scanner"
+	"github.com/deta/space/shared"
+	"github.com/spf13/cobra"
+)
+
+func newCmdSpacefile() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "spacefile",
+		Short: "Edit and inspect your Spacefile",
+		Long: `Edit your Spacefile from the command line.
+
+Comments and formatting of the Spacefile are kept, and every change is validated before it is written.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Usage()
+		},
+	}
+
+	cmd.AddCommand(newCmdSpacefileAdd())
+	cmd.AddCommand(newCmdSpacefileRemove())
+	cmd.AddCommand(newCmdSpacefileSet())
+	cmd.AddCommand(newCmdSpacefileRender())
+	cmd.AddCommand(newCmdSpacefileMigrate())
+
+	return cmd
+}
+
+func newCmdSpacefileAdd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add [src]",
+		Short: "Add a micro to your Spacefile",
+		Long: `Add a micro to your Spacefile.
+
+The src directory of the micro is scanned to detect its name and engine, use the flags to override them.`,
+		Args:     cobra.MaximumNArgs(1),
+		PreRunE:  utils.CheckExists("dir"),
+		PostRunE: utils.CheckLatestVersion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir, _ := cmd.Flags().GetString("dir")
+
+			src := "."
+			if len(args) > 0 {
+				src = args[0]
+			}
+
+			validateName, err := microNameValidator(projectDir)
+			if err != nil {
+				return err
+			}
+
+			micro, err := detectMicro(projectDir, src)
+			if err != nil {
+				return err
+			}
+
+			if cmd.Flags().Changed("name") {
+				micro.Name, _ = cmd.Flags().GetString("name")
+			} else if utils.IsOutputInteractive() {
+				if micro.Name, err = selectMicroName(micro.Name, validateName); err != nil {
+					return err
+				}
+			}
+
+			if err := validateName(micro.Name); err != nil {
+				return err
+			}
+
+			if cmd.Flags().Changed("engine") {
+				micro.Engine, _ = cmd.Flags().GetString("engine")
+			} else if micro.Engine == "" {
+				if !utils.IsOutputInteractive() {
+					return fmt.Errorf("could not detect the engine of %s, please provide one with --engine", src)
+				}
+				if micro.Engine, err = choose.Run("Which engine does the micro use?", spacefile.Engines()...); err != nil {
+					return err
+				}
+			}
+
+			if cmd.Flags().Changed("serve") {
+				micro.Serve, _ = cmd.Flags().GetString("serve")
+			} else if micro.Engine == shared.Static && micro.Serve == "" {
+				micro.Serve = "./"
+			}
+
+			if cmd.Flags().Changed("path") {
+				micro.Path, _ = cmd.Flags().GetString("path")
+			}
+
+			if err := addMicro(projectDir, micro); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("dir", "d", "./", "src of project to edit")
+	cmd.MarkFlagDirname("dir")
+	cmd.Flags().StringP("name", "n", "", "name of the micro")
+	cmd.Flags().StringP("engine", "e", "", "engine of the micro")
+	cmd.Flags().String("serve", "", "directory to serve, for static micros")
+	cmd.Flags().String("path", "", "path the micro receives requests on")
+
+	return cmd
+}
+
+func newCmdSpacefileRemove() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:      "remove <micro>",
+		Short:    "Remove a micro from your Spacefile",
+		Args:     cobra.ExactArgs(1),
+		PreRunE:  utils.CheckExists("dir"),
+		PostRunE: utils.CheckLatestVersion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir, _ := cmd.Flags().GetString("dir")
+
+			if err := editSpacefile(projectDir, func(doc *spacefile.Document) error {
+				return doc.RemoveMicro(args[0])
+			}); err != nil {
+				return err
+			}
+
+			utils.Logger.Println(styles.Greenf("\n%s Removed micro %s from the Spacefile", emoji.Check, args[0]))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("dir", "d", "./", "src of project to edit")
+	cmd.MarkFlagDirname("dir")
+
+	return cmd
+}
+
+func newCmdSpacefileSet() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set <path> <value>",
+		Short: "Set a field of your Spacefile",
+		Long: `Set a field of your Spacefile using a dotted path.
+
+Micros and env presets are selected by name and actions by id. Missing env presets, actions
+and fields are created, the micro must already exist, use "space spacefile add" to add one. For example:
+
+  space spacefile set micros.api.presets.env.API_URL.default https://example.com
+  space spacefile set micros.api.public true`,
+		Args:     cobra.ExactArgs(2),
+		PreRunE:  utils.CheckExists("dir"),
+		PostRunE: utils.CheckLatestVersion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir, _ := cmd.Flags().GetString("dir")
+
+			if err := editSpacefile(projectDir, func(doc *spacefile.Document) error {
+				return doc.Set(args[0], args[1])
+			}); err != nil {
+				return err
+			}
+
+			utils.Logger.Println(styles.Greenf("\n%s Set %s in the Spacefile", emoji.Check, args[0]))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("dir", "d", "./", "src of project to edit")
+	cmd.MarkFlagDirname("dir")
+
+	return cmd
+}
+
+func newCmdSpacefileRender() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "render",
+		Short: "Print your Spacefile merged with its overlays",
+		Long: `Print your Spacefile merged with its overlays, as used by space dev.
+
+Spacefile.dev and Spacefile.local are merged over the Spacefile when they exist, followed by the --overlay files.
+Micros and env presets are matched by name, actions by id, and a null value removes a field.
+Use --interpolate to expand ${VAR} and ${VAR:-default} from the env and the .env file, as space dev does.`,
+		Args:    cobra.NoArgs,
+		PreRunE: utils.CheckExists("dir"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir, _ := cmd.Flags().GetString("dir")
+			overlays, _ := cmd.Flags().GetStringArray("overlay")
+			interpolate, _ := cmd.Flags().GetBool("interpolate")
+
+			overlays = spacefile.Overlays(projectDir, overlays)
+			content, err := spacefile.Render(projectDir, overlays)
+			if err != nil {
+				return fmt.Errorf("failed to render Spacefile, %w", err)
+			}
+
+			if interpolate {
+				lookup, err := spacefile.EnvLookup(projectDir)
+				if err != nil {
+					return err
+				}
+				if content, err = spacefile.InterpolateContent(content, lookup); err != nil {
+					return fmt.Errorf("failed to interpolate Spacefile, %w", err)
+				}
+			}
+
+			if _, err := os.Stdout.Write(content); err != nil {
+				return err
+			}
+
+			// the merged result is printed even if invalid, to help finding the faulty overlay
+			if _, err := spacefile.LoadSpacefileWithOverlays(projectDir, overlays); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("dir", "d", "./", "src of project to render")
+	cmd.MarkFlagDirname("dir")
+	cmd.Flags().StringArray("overlay", []string{}, "overlay to merge over the Spacefile, after Spacefile.dev and Spacefile.local")
+	cmd.Flags().Bool("interpolate", false, "expand variables from the env and .env")
+
+	return cmd
+}
+
+func newCmdSpacefileMigrate() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate your Spacefile to the latest version",
+		Long: fmt.Sprintf(`Migrate your Spacefile to the latest version, %d.
+
+The migrations still needed by the Spacefile are applied in order, starting from its version. Outdated Spacefiles
+are already migrated in memory whenever they are loaded, this command writes the result to the file.
+From version 0 to 1, deprecated engines are replaced and app_name is moved to Discovery.md.
+Use --dry-run to print the changes without writing them.
+
+Space only knows version 0, the version of the Spacefile is set back to 0 when it is pushed.`, spacefile.CurrentVersion),
+		Args:     cobra.NoArgs,
+		PreRunE:  utils.CheckExists("dir"),
+		PostRunE: utils.CheckLatestVersion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir, _ := cmd.Flags().GetString("dir")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+			return migrateSpacefile(projectDir, dryRun)
+		},
+	}
+
+	cmd.Flags().StringP("dir", "d", "./", "src of project to migrate")
+	cmd.MarkFlagDirname("dir")
+	cmd.Flags().Bool("dry-run", false, "print the changes without writing them")
+
+	return cmd
+}
+
+func migrateSpacefile(projectDir string, dryRun bool) error {
+	result, err := spacefile.Migrate(projectDir)
+	if err != nil {
+		return fmt.Errorf("failed to migrate Spacefile, %w", err)
+	}
+
+	if result.From == result.To {
+		utils.Logger.Println(styles.Greenf("\n%s Your Spacefile is already at version %d", emoji.Check, result.To))
+		return nil
+	}
+
+	utils.Logger.Printf("\nMigrations from version %d to %d:", result.From, result.To)
+	for _, description := range result.Applied {
+		utils.Logger.Printf("L %s", description)
+	}
+
+	if dryRun {
+		utils.Logger.Print(result.Diff())
+		if result.AppName != "" {
+			utils.Logger.Printf("\napp_name %s would be moved to %s", styles.Code(result.AppName), discovery.DiscoveryFilename)
+		}
+		return nil
+	}
+
+	// the app name is moved first, so that it is not lost if writing the Discovery file fails
+	if result.AppName != "" {
+		changed, err := discovery.SetAppName(projectDir, result.AppName)
+		if err != nil {
+			return fmt.Errorf("failed to move app_name to %s, %w", discovery.DiscoveryFilename, err)
+		}
+		if changed {
+			utils.Logger.Printf("\n%s Moved app_name %s to %s", emoji.Check, styles.Code(result.AppName), discovery.DiscoveryFilename)
+		} else {
+			utils.Logger.Printf("\n%s already has an app_name, app_name %s was removed from the Spacefile", discovery.DiscoveryFilename, styles.Code(result.AppName))
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(projectDir, spacefile.SpacefileName), result.Migrated, 0644); err != nil {
+		return fmt.Errorf("failed to write Spacefile, %w", err)
+	}
+
+	utils.Logger.Println(styles.Greenf("\n%s Migrated your Spacefile from version %d to %d", emoji.Check, result.From, result.To))
+	return nil
+}
+
+// printMigrations warns about the changes made in memory to an outdated Spacefile
+func printMigrations(s *spacefile.Spacefile) {
+	migrations := s.Migrations()
+	if len(migrations) == 0 {
+		return
+	}
+
+	utils.Logger.Printf("\n%s Your Spacefile is outdated and was migrated to version %d in memory, run %s to update it:", emoji.LightBulb, spacefile.CurrentVersion, styles.Code("space spacefile migrate"))
+	for _, d := range migrations {
+		utils.Logger.Printf("L %s", d.Message)
+	}
+}
+
+// detectMicro scans the src directory of a micro to seed its name and engine
+func detectMicro(projectDir string, src string) (*shared.Micro, error) {
+	microDir := filepath.Join(projectDir, src)
+	micros, err := scanner.Scan(microDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s, %w", src, err)
+	}
+
+	abs, err := filepath.Abs(microDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path of %s, %w", src, err)
+	}
+
+	micro := &shared.Micro{Name: filepath.Base(abs)}
+	// the scan only describes the micro if it was found at the root of src
+	if len(micros) == 1 && micros[0].Src == microDir {
+		micro = micros[0]
+		utils.Logger.Printf("\nMicro found in \"%s\"", styles.Code(src))
+		utils.Logger.Printf("L engine: %s\n", styles.Blue(micro.Engine))
+	}
+	micro.Src = filepath.ToSlash(filepath.Clean(src))
+
+	return micro, nil
+}
+
+// microNameValidator checks the name of a new micro against the schema and the micros already in the Spacefile
+func microNameValidator(projectDir string) (func(string) error, error) {
+	existing := make(map[string]struct{})
+	doc, err := spacefile.LoadDocument(projectDir)
+	if err != nil && !errors.Is(err, spacefile.ErrSpacefileNotFound) {
+		return nil, fmt.Errorf("failed to load Spacefile, %w", err)
+	}
+	if doc != nil {
+		for _, name := range doc.MicroNames() {
+			existing[name] = struct{}{}
+		}
+	}
+
+	return func(name string) error {
+		if err := spacefile.ValidateMicroName(name); err != nil {
+			return err
+		}
+		if _, ok := existing[name]; ok {
+			return fmt.Errorf("%w: %s", spacefile.ErrMicroExists, name)
+		}
+		return nil
+	}, nil
+}
+
+func selectMicroName(placeholder string, validate func(string) error) (string, error) {
+	promptInput := text.Input{
+		Prompt:      "What is the name of the micro?",
+		Placeholder: placeholder,
+		Validator:   validate,
+	}
+
+	return text.Run(&promptInput)
+}
+
+func addMicro(projectDir string, micro *shared.Micro) error {
+	if err := editSpacefile(projectDir, func(doc *spacefile.Document) error {
+		// mark new micro as primary if it is the only one
+		if len(doc.MicroNames()) == 0 {
+			micro.Primary = true
+		}
+		return doc.AddMicro(micro)
+	}); err != nil {
+		return err
+	}
+
+	utils.Logger.Println(styles.Greenf("\n%s Added micro %s to the Spacefile", emoji.Check, micro.Name))
+	return nil
+}
+
+// editSpacefile applies an edit to the Spacefile of a project, the file is only written if the result is valid
+func editSpacefile(projectDir string, edit func(doc *spacefile.Document) error) error {
+	doc, err := spacefile.LoadDocument(projectDir)
+	if errors.Is(err, spacefile.ErrSpacefileNotFound) {
+		doc = spacefile.NewDocument()
+		err = doc.Set("v", "0")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load Spacefile, %w", err)
+	}
+
+	if err := edit(doc); err != nil {
+		return err
+	}
+
+	if err := doc.Validate(projectDir); err != nil {
+		return fmt.Errorf("the Spacefile was not changed, %w", err)
+	}
+
+	if err := doc.Save(projectDir); err != nil {
+		return fmt.Errorf("failed to save Spacefile, %w", err)
+	}
+
+	return nil
+}
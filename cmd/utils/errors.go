@@ -0,0 +1,13 @@
+package utils
+
+import "fmt"
+
+// ExitError makes the cli exit with the given code without printing an error message,
+// it is used by commands that already reported their outcome on stdout
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
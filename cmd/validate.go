@@ -1,8 +1,10 @@
 package cmd
 
 import (
-	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/deta/space/cmd/utils"
 	"github.com/deta/space/internal/spacefile"
@@ -11,50 +13,132 @@ import (
 	"github.com/spf13/cobra"
 )
 
+const (
+	outputText  = "text"
+	outputJSON  = "json"
+	outputSARIF = "sarif"
+)
+
 func newCmdValidate() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:      "validate [flags]",
-		Short:    "Validate your Spacefile and check for errors",
-		PostRunE: utils.CheckLatestVersion,
+		Use:   "validate [flags]",
+		Short: "Validate your Spacefile and check for errors",
+		Long: `Validate your Spacefile and check for errors.
+
+Only the Spacefile and the files it points to, like the icon, are checked, the Discovery.md file is not.
+
+Use --output json or --output sarif to get every diagnostic of the Spacefile in a machine-readable format.
+In these modes the exit code reflects the highest severity found: 2 for errors, 1 for warnings and 0 otherwise.`,
+		PostRunE: checkLatestVersionForTextOutput("output"),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			projectDir, _ := cmd.Flags().GetString("dir")
-			if err := validate(projectDir); err != nil {
+			output, _ := cmd.Flags().GetString("output")
+			if err := validate(projectDir, output); err != nil {
 				return err
 			}
 
 			return nil
 		},
-		PreRunE: utils.CheckExists("dir"),
+		PreRunE: utils.CheckAll(utils.CheckExists("dir"), checkOutputFormat("output")),
 	}
 	cmd.Flags().StringP("dir", "d", "./", "src of project to validate")
+	cmd.Flags().StringP("output", "o", outputText, "output format, one of: text, json, sarif")
 
 	return cmd
 }
 
-func validate(projectDir string) error {
-	utils.Logger.Printf("\n%s Validating your Spacefile...\n", emoji.Package)
+func checkOutputFormat(flagName string) utils.PreRunFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString(flagName)
+		switch output {
+		case outputText, outputJSON, outputSARIF:
+			return nil
+		}
+		return fmt.Errorf("invalid output format %s, must be one of: text, json, sarif", output)
+	}
+}
 
-	s, err := spacefile.LoadSpacefile(projectDir)
+// checkLatestVersionForTextOutput skips the version check when the output is machine-readable
+func checkLatestVersionForTextOutput(flagName string) utils.PreRunFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		if output, _ := cmd.Flags().GetString(flagName); output != outputText {
+			return nil
+		}
+		return utils.CheckLatestVersion(cmd, args)
+	}
+}
+
+func validate(projectDir string, output string) error {
+	if output == outputText {
+		utils.Logger.Printf("\n%s Validating your Spacefile...\n", emoji.Package)
+	}
+
+	diagnostics, err := spacefile.Validate(projectDir)
 	if err != nil {
 		return fmt.Errorf("failed to parse Spacefile, %w", err)
 	}
 
-	if s.Icon == "" {
-		utils.Logger.Printf("\n%s No app icon specified.", styles.Blue("i"))
-	} else {
-		if err := spacefile.ValidateIcon(s.Icon); err != nil {
-			switch {
-			case errors.Is(spacefile.ErrInvalidIconType, err):
-				return fmt.Errorf("invalid icon type, please use a 512x512 sized PNG or WebP icon")
-			case errors.Is(spacefile.ErrInvalidIconSize, err):
-				return fmt.Errorf("icon size is not valid, please use a 512x512 sized PNG or WebP icon")
-			case errors.Is(spacefile.ErrInvalidIconPath, err):
-				return fmt.Errorf("cannot find the icon in provided path, please provide a valid icon path or leave it empty to auto-generate one")
-			default:
-				return err
-			}
+	switch output {
+	case outputJSON:
+		if err := spacefile.WriteJSON(os.Stdout, diagnostics); err != nil {
+			return err
+		}
+		return diagnosticsExitError(diagnostics)
+	case outputSARIF:
+		if err := spacefile.WriteSARIF(os.Stdout, diagnostics, utils.SpaceVersion); err != nil {
+			return err
+		}
+		return diagnosticsExitError(diagnostics)
+	}
+
+	return printDiagnostics(projectDir, diagnostics, "Spacefile looks good!")
+}
+
+// printDiagnostics prints diagnostics as text with snippets of the Spacefile, errors are returned so that the command fails
+func printDiagnostics(projectDir string, diagnostics []spacefile.Diagnostic, successMessage string) error {
+	spacefilePath := filepath.Join(projectDir, spacefile.SpacefileName)
+	source, err := os.ReadFile(spacefilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read Spacefile, %w", err)
+	}
+
+	var problems []spacefile.Diagnostic
+	for _, d := range diagnostics {
+		switch d.Severity {
+		case spacefile.SeverityInfo:
+			utils.Logger.Printf("\n%s %s.", styles.Blue("i"), capitalize(d.Message))
+		default:
+			problems = append(problems, d)
 		}
 	}
-	utils.Logger.Println(styles.Greenf("\n%s Spacefile looks good!", emoji.Sparkles))
+
+	problemsErr := spacefile.NewValidationError(problems, spacefilePath, source)
+	if spacefile.HighestSeverity(problems) == spacefile.SeverityError {
+		return problemsErr
+	}
+
+	if len(problems) > 0 {
+		utils.Logger.Printf("\n%s", problemsErr.Error())
+	}
+
+	utils.Logger.Println(styles.Greenf("\n%s %s", emoji.Sparkles, successMessage))
 	return nil
 }
+
+// diagnosticsExitError maps the highest severity of the diagnostics to the exit code of the cli
+func diagnosticsExitError(diagnostics []spacefile.Diagnostic) error {
+	switch spacefile.HighestSeverity(diagnostics) {
+	case spacefile.SeverityError:
+		return &utils.ExitError{Code: 2}
+	case spacefile.SeverityWarning:
+		return &utils.ExitError{Code: 1}
+	}
+	return nil
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -456,8 +456,9 @@ func (c *DetaClient) PushDiscoveryFile(r *PushDiscoveryFileRequest) (*PushDiscov
 
 // PushCodeRequest push code request
 type PushCodeRequest struct {
-	BuildID    string `json:"build_id"`
-	ZippedCode []byte `json:"zipped_code"`
+	BuildID string `json:"build_id"`
+	// ZippedCode is streamed, it is read twice to sign the request
+	ZippedCode io.ReadSeeker `json:"-"`
 }
 
 // PushCodeResponse push code response
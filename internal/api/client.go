@@ -168,7 +168,9 @@ func (c *DetaClient) Patch(path string, body []byte) ([]byte, error) {
 // Request send an http request to the deta api
 func (c *DetaClient) request(i *requestInput) (*requestOutput, error) {
 	marshalled, _ := i.Body.([]byte)
-	if i.Body != nil && i.ContentType == "" {
+	// streamed bodies are read twice, once to sign them and once to send them
+	stream, isStream := i.Body.(io.ReadSeeker)
+	if i.Body != nil && i.ContentType == "" && !isStream {
 		// default set content-type to application/json
 		i.ContentType = "application/json"
 		var err error
@@ -178,11 +180,28 @@ func (c *DetaClient) request(i *requestInput) (*requestOutput, error) {
 		}
 	}
 
-	req, err := http.NewRequest(i.Method, fmt.Sprintf("%s%s", i.Root, i.Path), bytes.NewBuffer(marshalled))
+	var body io.Reader = bytes.NewBuffer(marshalled)
+	if isStream {
+		// the caller owns the stream, it must not be closed once sent
+		body = io.NopCloser(stream)
+	}
+
+	req, err := http.NewRequest(i.Method, fmt.Sprintf("%s%s", i.Root, i.Path), body)
 	if err != nil {
 		return nil, err
 	}
 
+	if isStream {
+		size, err := stream.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := stream.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		req.ContentLength = size
+	}
+
 	// headers
 	if i.ContentType != "" {
 		req.Header.Set("Content-type", i.ContentType)
@@ -224,17 +243,28 @@ func (c *DetaClient) request(i *requestInput) (*requestOutput, error) {
 		timestamp := strconv.FormatInt(now+c.TimestampShift, 10)
 
 		// compute signature
-		signature, err := auth.CalcSignature(&auth.CalcSignatureInput{
+		signatureInput := &auth.CalcSignatureInput{
 			AccessToken: i.AccessToken,
 			HTTPMethod:  i.Method,
 			URI:         req.URL.RequestURI(),
 			Timestamp:   timestamp,
 			ContentType: i.ContentType,
 			RawBody:     marshalled,
-		})
+		}
+		if isStream {
+			signatureInput.Body = stream
+		}
+
+		signature, err := auth.CalcSignature(signatureInput)
 		if err != nil {
 			return nil, fmt.Errorf("failed to calculate auth signature: %w", err)
 		}
+
+		if isStream {
+			if _, err := stream.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
 		// set needed access key auth headers
 		req.Header.Set("X-Deta-Timestamp", timestamp)
 		req.Header.Set("X-Deta-Signature", signature)
@@ -3,6 +3,7 @@ package auth
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -132,6 +133,8 @@ type CalcSignatureInput struct {
 	Timestamp   string
 	ContentType string
 	RawBody     []byte
+	// Body is signed instead of RawBody if set, so that large bodies do not have to be held in memory
+	Body io.Reader
 }
 
 // CalcSignature calculates the signature for signing the requests
@@ -144,18 +147,25 @@ func CalcSignature(i *CalcSignatureInput) (string, error) {
 	accessKeyID := tokenParts[0]
 	accessKeySecret := tokenParts[1]
 
-	stringToSign := fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n",
-		i.HTTPMethod,
-		i.URI,
-		i.Timestamp,
-		i.ContentType,
-		i.RawBody,
-	)
-
 	mac := hmac.New(sha256.New, []byte(accessKeySecret))
-	_, err := mac.Write([]byte(stringToSign))
-	if err != nil {
-		return "", fmt.Errorf("failed to calculate hmac: %w", err)
+	if i.Body == nil {
+		stringToSign := fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n",
+			i.HTTPMethod,
+			i.URI,
+			i.Timestamp,
+			i.ContentType,
+			i.RawBody,
+		)
+		if _, err := mac.Write([]byte(stringToSign)); err != nil {
+			return "", fmt.Errorf("failed to calculate hmac: %w", err)
+		}
+	} else {
+		// same string to sign as above, with the body streamed in
+		fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n", i.HTTPMethod, i.URI, i.Timestamp, i.ContentType)
+		if _, err := io.Copy(mac, i.Body); err != nil {
+			return "", fmt.Errorf("failed to calculate hmac: %w", err)
+		}
+		mac.Write([]byte("\n"))
 	}
 	signature := mac.Sum(nil)
 	hexSign := hex.EncodeToString(signature)
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 )
@@ -36,3 +37,31 @@ func TestSignature(t *testing.T) {
 		})
 	}
 }
+
+func TestStreamedSignature(t *testing.T) {
+	body := []byte("PK\x03\x04 zipped code")
+	input := CalcSignatureInput{
+		AccessToken: "xkcfKpsU_zwDNmNSqG9TGEiR8sSm8HVrSWuJ31b4d",
+		URI:         "/api/v0/builds/1/code",
+		Timestamp:   "1681294911",
+		HTTPMethod:  "POST",
+		ContentType: "application/zip",
+		RawBody:     body,
+	}
+
+	expected, err := CalcSignature(&input)
+	if err != nil {
+		t.Fatalf("expected no error, actual: %s", err)
+	}
+
+	input.RawBody = nil
+	input.Body = bytes.NewReader(body)
+	actual, err := CalcSignature(&input)
+	if err != nil {
+		t.Fatalf("expected no error, actual: %s", err)
+	}
+
+	if actual != expected {
+		t.Errorf("expected: %s, actual: %s", expected, actual)
+	}
+}
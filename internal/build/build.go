@@ -0,0 +1,424 @@
+// Package build emulates the builder locally, to check the commands and includes of micros before pushing
+package build
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+	"time"
+
+	"github.com/deta/space/internal/runtime"
+	"github.com/deta/space/shared"
+)
+
+var (
+	ErrCommandFailed  = errors.New("build command failed")
+	ErrMissingInclude = errors.New("included path not found")
+)
+
+// Options configures a build
+type Options struct {
+	ProjectDir string
+	// OutputDir holds an artifact per micro, named after the micro
+	OutputDir string
+	// Zip writes the artifacts as zip files instead of directories
+	Zip bool
+	// SkipCommands only collects the included files of the micro, without running any command
+	SkipCommands bool
+	// Cache, if set, restores the dependencies of the micro before its commands and saves them after
+	Cache       *Cache
+	ScanOptions runtime.ScanOptions
+	// Stdout and Stderr receive the output of the build commands
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Result describes the build of a micro
+type Result struct {
+	Micro string
+	// Steps are the commands that were run, in order
+	Steps []string
+	// Artifact is the path of the artifact directory or zip, if the build succeeded
+	Artifact string
+	Files    int
+	Size     int64
+	// Missing lists the included paths that do not exist after the build
+	Missing []string
+	// Cached lists the directories restored from the cache, the cache missed if it is empty
+	Cached   []string
+	Duration time.Duration
+	Err      error
+}
+
+// Micro builds a micro in a workspace holding the files of its src that would be pushed,
+// then collects its included paths into an artifact
+func Micro(micro *shared.Micro, opts Options) *Result {
+	start := time.Now()
+	result := &Result{Micro: micro.Name}
+	result.Err = buildMicro(micro, opts, result)
+	result.Duration = time.Since(start)
+	return result
+}
+
+func buildMicro(micro *shared.Micro, opts Options, result *Result) error {
+	workspace, err := workspaceDir(opts.ProjectDir, micro)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace, %w", err)
+	}
+	defer os.RemoveAll(workspace)
+
+	if err := copySource(opts.ProjectDir, micro.Src, workspace, opts.ScanOptions); err != nil {
+		return fmt.Errorf("failed to copy the source of micro %s, %w", micro.Name, err)
+	}
+
+	if !opts.SkipCommands {
+		if err := runSteps(micro, workspace, opts, result); err != nil {
+			return err
+		}
+	}
+
+	includes := artifactPaths(micro)
+	for _, include := range includes {
+		if _, err := os.Stat(filepath.Join(workspace, filepath.FromSlash(include))); err != nil {
+			result.Missing = append(result.Missing, include)
+		}
+	}
+	if len(result.Missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingInclude, strings.Join(result.Missing, ", "))
+	}
+
+	artifact := ArtifactDir(opts.OutputDir, micro)
+	if err := os.RemoveAll(artifact); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(artifact + ".zip"); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(artifact, 0755); err != nil {
+		return err
+	}
+
+	if len(includes) == 0 {
+		includes = []string{"."}
+	}
+	for _, include := range includes {
+		files, size, err := copyTree(filepath.Join(workspace, filepath.FromSlash(include)), filepath.Join(artifact, filepath.FromSlash(include)))
+		if err != nil {
+			return fmt.Errorf("failed to collect %s, %w", include, err)
+		}
+		result.Files += files
+		result.Size += size
+	}
+
+	if opts.Zip {
+		if err := zipDir(artifact, artifact+".zip"); err != nil {
+			return fmt.Errorf("failed to zip the artifact, %w", err)
+		}
+		if err := os.RemoveAll(artifact); err != nil {
+			return err
+		}
+		artifact += ".zip"
+	}
+	result.Artifact = artifact
+
+	return nil
+}
+
+// workspaceDir creates the empty workspace of a micro in the temporary directory. Its path only depends on the
+// project and the micro, so that the virtualenvs restored from the cache point to the workspace they are restored to.
+func workspaceDir(projectDir string, micro *shared.Micro) (string, error) {
+	abs, err := filepath.Abs(projectDir)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(abs + "\x00" + micro.Name))
+	workspace := filepath.Join(os.TempDir(), fmt.Sprintf("space-build-%s-%x", micro.Name, sum[:6]))
+	// a workspace left by an interrupted build is started over
+	if err := os.RemoveAll(workspace); err != nil {
+		return "", err
+	}
+	if err := os.Mkdir(workspace, 0700); err != nil {
+		return "", err
+	}
+	return workspace, nil
+}
+
+// ArtifactDir is the directory of the artifact of a micro in an output directory, when not zipped
+func ArtifactDir(outputDir string, micro *shared.Micro) string {
+	return filepath.Join(outputDir, micro.Name)
+}
+
+// Env is the environment of the build commands of a micro
+func Env(micro *shared.Micro) []string {
+	return append(os.Environ(),
+		"CI=true",
+		"DETA_SPACE_APP_MICRO_NAME="+micro.Name,
+		"DETA_SPACE_APP_MICRO_TYPE="+micro.Type(),
+	)
+}
+
+// runSteps runs the commands of a micro in its workspace, between restoring and saving its cached directories
+func runSteps(micro *shared.Micro, workspace string, opts Options, result *Result) error {
+	var key string
+	cacheable := false
+	if opts.Cache != nil {
+		var err error
+		if key, cacheable, err = opts.Cache.Key(micro, workspace); err != nil {
+			return fmt.Errorf("failed to compute the cache key, %w", err)
+		}
+	}
+	if cacheable {
+		restored, err := opts.Cache.Restore(key, workspace)
+		if err != nil {
+			return fmt.Errorf("failed to restore the cache, %w", err)
+		}
+		result.Cached = restored
+	}
+
+	steps := micro.Commands
+	// dependencies restored from a cache keyed by the lockfile do not need to be installed again
+	if install := installStep(micro, workspace); install != "" && !contains(result.Cached, "node_modules") {
+		steps = append([]string{install}, steps...)
+	}
+
+	env := Env(micro)
+	for _, step := range steps {
+		result.Steps = append(result.Steps, step)
+		if err := runStep(step, workspace, env, opts); err != nil {
+			return fmt.Errorf("%w, %s: %v", ErrCommandFailed, step, err)
+		}
+	}
+
+	if cacheable {
+		if err := opts.Cache.Save(key, micro, workspace); err != nil {
+			return fmt.Errorf("failed to save the cache, %w", err)
+		}
+	}
+	return nil
+}
+
+// Steps are the commands run to build a micro, the dependencies of node micros are installed before its commands
+func Steps(micro *shared.Micro, workspace string) []string {
+	if install := installStep(micro, workspace); install != "" {
+		return append([]string{install}, micro.Commands...)
+	}
+	return micro.Commands
+}
+
+func installStep(micro *shared.Micro, workspace string) string {
+	if !isNodeEngine(micro.Engine) {
+		return ""
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "package.json")); err != nil {
+		return ""
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "package-lock.json")); err == nil {
+		return "npm ci"
+	}
+	return "npm install"
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func isNodeEngine(engine string) bool {
+	return strings.HasPrefix(engine, "nodejs") || shared.IsFrontendEngine(engine) || shared.IsFullstackEngine(engine)
+}
+
+// artifactPaths are the paths of the workspace kept after the build, the whole workspace if empty
+func artifactPaths(micro *shared.Micro) []string {
+	if len(micro.Include) > 0 {
+		return micro.Include
+	}
+	if micro.Serve != "" {
+		return []string{micro.Serve}
+	}
+	return nil
+}
+
+func runStep(step string, dir string, env []string, opts Options) error {
+	var cmd *exec.Cmd
+	if goruntime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", step)
+	} else {
+		cmd = exec.Command("sh", "-c", step)
+	}
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = opts.Stderr
+	return cmd.Run()
+}
+
+// copySource copies the files of src that would be pushed to the workspace
+func copySource(projectDir string, src string, workspace string, scanOpts runtime.ScanOptions) error {
+	files, err := runtime.ListFiles(projectDir, scanOpts)
+	if err != nil {
+		return err
+	}
+
+	prefix := path.Clean(filepath.ToSlash(src))
+	for _, file := range files {
+		relPath := file.Path
+		if prefix != "." {
+			if !strings.HasPrefix(relPath, prefix+"/") {
+				continue
+			}
+			relPath = strings.TrimPrefix(relPath, prefix+"/")
+		}
+
+		if err := copyArchiveFile(file, filepath.Join(workspace, filepath.FromSlash(relPath))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyArchiveFile(file runtime.ArchiveFile, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if file.Mode()&os.ModeSymlink != 0 {
+		target, err := io.ReadAll(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(filepath.FromSlash(string(target)), dst)
+	}
+
+	return writeFile(dst, src, file.Mode())
+}
+
+func writeFile(dst string, src io.Reader, mode os.FileMode) error {
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, src); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// copyTree copies a file or a directory, symlinks are copied as symlinks
+func copyTree(src string, dst string) (int, int64, error) {
+	var files int
+	var size int64
+	err := filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, 0755)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			files++
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			files++
+			size += info.Size()
+			return writeFile(target, f, info.Mode())
+		}
+		return nil
+	})
+	return files, size, err
+}
+
+// zipDir zips the content of a directory
+func zipDir(dir string, dst string) error {
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Method = zip.Deflate
+
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(w, filepath.ToSlash(link))
+			return err
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
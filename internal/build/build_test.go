@@ -0,0 +1,92 @@
+package build
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"testing"
+
+	"github.com/deta/space/shared"
+)
+
+func writeProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestBuildMicro(t *testing.T) {
+	if goruntime.GOOS == "windows" {
+		t.Skip("build commands use sh")
+	}
+
+	projectDir := writeProject(t, map[string]string{
+		"api/main.py":     "print('hello')",
+		"api/.env":        "SECRET=1",
+		"web/index.html":  "<html></html>",
+		"web/__pycache__": "",
+	})
+	output := t.TempDir()
+
+	micro := &shared.Micro{
+		Name:     "api",
+		Src:      "api",
+		Engine:   "python3.9",
+		Commands: []string{"test ! -e .env", `mkdir -p out && echo "$DETA_SPACE_APP_MICRO_NAME" > out/name`},
+		Include:  []string{"main.py", "out"},
+	}
+	result := Micro(micro, Options{ProjectDir: projectDir, OutputDir: output, Stdout: io.Discard, Stderr: io.Discard})
+	if result.Err != nil {
+		t.Fatalf("failed to build micro: %v", result.Err)
+	}
+
+	if result.Files != 2 || len(result.Steps) != 2 || result.Artifact != filepath.Join(output, "api") {
+		t.Errorf("unexpected result %+v", result)
+	}
+	name, err := os.ReadFile(filepath.Join(output, "api", "out", "name"))
+	if err != nil || string(name) != "api\n" {
+		t.Errorf("expected the build env in the artifact, got %q, %v", name, err)
+	}
+
+	micro.Include = append(micro.Include, "dist")
+	result = Micro(micro, Options{ProjectDir: projectDir, OutputDir: output, Zip: true, Stdout: io.Discard, Stderr: io.Discard})
+	if !errors.Is(result.Err, ErrMissingInclude) || len(result.Missing) != 1 || result.Missing[0] != "dist" {
+		t.Errorf("expected dist to be missing, got %+v", result)
+	}
+
+	micro.Include = nil
+	micro.Commands = []string{"exit 3"}
+	result = Micro(micro, Options{ProjectDir: projectDir, OutputDir: output, Stdout: io.Discard, Stderr: io.Discard})
+	if !errors.Is(result.Err, ErrCommandFailed) {
+		t.Errorf("expected the command to fail, got %v", result.Err)
+	}
+}
+
+func TestSteps(t *testing.T) {
+	workspace := writeProject(t, map[string]string{
+		"package.json":      "{}",
+		"package-lock.json": "{}",
+	})
+
+	steps := Steps(&shared.Micro{Engine: "svelte", Commands: []string{"npm run build"}}, workspace)
+	if len(steps) != 2 || steps[0] != "npm ci" || steps[1] != "npm run build" {
+		t.Errorf("expected dependencies to be installed first, got %v", steps)
+	}
+
+	steps = Steps(&shared.Micro{Engine: "python3.9", Commands: []string{"make"}}, workspace)
+	if len(steps) != 1 {
+		t.Errorf("expected only the commands of a python micro, got %v", steps)
+	}
+}
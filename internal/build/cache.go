@@ -0,0 +1,270 @@
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deta/space/shared"
+)
+
+const (
+	// cachePath is relative to the home directory
+	cachePath      = ".detaspace/cache"
+	cacheEntryFile = "entry.json"
+	cacheDataDir   = "data"
+)
+
+// lockfiles pin the dependencies of a micro, a micro without any is not cached
+var lockfiles = []string{"package-lock.json", "pnpm-lock.yaml", "yarn.lock", "requirements.txt", "go.sum"}
+
+// cachedDirs are the dependency and build cache directories restored before the commands of a micro
+var cachedDirs = []string{"node_modules", ".venv", ".next/cache", ".nuxt", ".svelte-kit", ".parcel-cache", "target"}
+
+// Cache stores the dependencies of micros, content-addressed by their workspace, engine, commands and lockfiles
+type Cache struct {
+	Dir string
+}
+
+// CacheEntry describes the cached directories of a build
+type CacheEntry struct {
+	Key       string    `json:"key"`
+	Micro     string    `json:"micro"`
+	Engine    string    `json:"engine"`
+	Dirs      []string  `json:"dirs"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	UsedAt    time.Time `json:"used_at"`
+}
+
+// CacheStats sums up the entries of a cache
+type CacheStats struct {
+	Entries int
+	Size    int64
+}
+
+// DefaultCache is the cache in the home directory of the user
+func DefaultCache() (*Cache, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Cache{Dir: filepath.Join(home, filepath.FromSlash(cachePath))}, nil
+}
+
+// Key computes the cache key of a micro from its workspace, its engine, its commands and the lockfiles of its workspace,
+// it returns false if the workspace has no lockfile
+func (c *Cache) Key(micro *shared.Micro, workspace string) (string, bool, error) {
+	h := sha256.New()
+	// virtualenvs keep absolute paths to the workspace they were created in, they only work when restored to the same path
+	fmt.Fprintf(h, "workspace %s\n", workspace)
+	fmt.Fprintf(h, "engine %s\n", micro.Engine)
+	for _, command := range micro.Commands {
+		fmt.Fprintf(h, "command %s\n", command)
+	}
+
+	found := false
+	for _, name := range lockfiles {
+		f, err := os.Open(filepath.Join(workspace, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", false, err
+		}
+
+		lh := sha256.New()
+		_, err = io.Copy(lh, f)
+		f.Close()
+		if err != nil {
+			return "", false, err
+		}
+		fmt.Fprintf(h, "lockfile %s %x\n", name, lh.Sum(nil))
+		found = true
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), found, nil
+}
+
+// Restore copies the cached directories of a key to the workspace, it returns the restored directories
+func (c *Cache) Restore(key string, workspace string) ([]string, error) {
+	entry, err := c.entry(key)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	for _, dir := range entry.Dirs {
+		dst := filepath.Join(workspace, filepath.FromSlash(dir))
+		if err := os.RemoveAll(dst); err != nil {
+			return nil, err
+		}
+		if _, _, err := copyTree(filepath.Join(c.Dir, key, cacheDataDir, filepath.FromSlash(dir)), dst); err != nil {
+			return nil, fmt.Errorf("failed to restore %s, %w", dir, err)
+		}
+	}
+
+	entry.UsedAt = time.Now().UTC()
+	if err := c.writeEntry(entry); err != nil {
+		return nil, err
+	}
+
+	return entry.Dirs, nil
+}
+
+// Save stores the cached directories of a workspace under a key, replacing the previous entry
+func (c *Cache) Save(key string, micro *shared.Micro, workspace string) error {
+	if err := os.MkdirAll(c.Dir, 0760); err != nil {
+		return err
+	}
+
+	// the entry is written next to its final location, then moved, so that a failed save does not leave a partial entry
+	tmp, err := os.MkdirTemp(c.Dir, "tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	now := time.Now().UTC()
+	entry := &CacheEntry{Key: key, Micro: micro.Name, Engine: micro.Engine, CreatedAt: now, UsedAt: now}
+	for _, dir := range cachedDirs {
+		src := filepath.Join(workspace, filepath.FromSlash(dir))
+		if info, err := os.Stat(src); err != nil || !info.IsDir() {
+			continue
+		}
+
+		_, size, err := copyTree(src, filepath.Join(tmp, cacheDataDir, filepath.FromSlash(dir)))
+		if err != nil {
+			return fmt.Errorf("failed to cache %s, %w", dir, err)
+		}
+		entry.Dirs = append(entry.Dirs, dir)
+		entry.Size += size
+	}
+	if len(entry.Dirs) == 0 {
+		return nil
+	}
+
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, cacheEntryFile), content, 0660); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(filepath.Join(c.Dir, key)); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(c.Dir, key))
+}
+
+// Entries lists the entries of the cache, most recently used first
+func (c *Cache) Entries() ([]CacheEntry, error) {
+	dirs, err := os.ReadDir(c.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []CacheEntry
+	for _, dir := range dirs {
+		if !dir.IsDir() || strings.HasPrefix(dir.Name(), "tmp-") {
+			continue
+		}
+		entry, err := c.entry(dir.Name())
+		if err != nil {
+			// entries that cannot be read are left for prune to remove
+			entries = append(entries, CacheEntry{Key: dir.Name()})
+			continue
+		}
+		entries = append(entries, *entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].UsedAt.After(entries[j].UsedAt)
+	})
+	return entries, nil
+}
+
+// Stats sums up the entries of the cache
+func (c *Cache) Stats() (*CacheStats, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &CacheStats{Entries: len(entries)}
+	for _, entry := range entries {
+		stats.Size += entry.Size
+	}
+	return stats, nil
+}
+
+// Prune removes the entries not used since olderThan, then the least recently used entries until the cache
+// is under maxSize. Zero values disable each of the rules, both are zero to remove every entry.
+func (c *Cache) Prune(olderThan time.Duration, maxSize int64) ([]CacheEntry, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+
+	var removed []CacheEntry
+	cutoff := time.Now().Add(-olderThan)
+	// entries are the most recently used first, so the least recently used are removed first
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+
+		remove := olderThan == 0 && maxSize == 0
+		remove = remove || entry.UsedAt.IsZero()
+		remove = remove || olderThan > 0 && entry.UsedAt.Before(cutoff)
+		remove = remove || maxSize > 0 && total > maxSize
+		if !remove {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(c.Dir, entry.Key)); err != nil {
+			return removed, err
+		}
+		total -= entry.Size
+		removed = append(removed, entry)
+	}
+
+	return removed, nil
+}
+
+func (c *Cache) entry(key string) (*CacheEntry, error) {
+	content, err := os.ReadFile(filepath.Join(c.Dir, key, cacheEntryFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (c *Cache) writeEntry(entry *CacheEntry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(c.Dir, entry.Key, cacheEntryFile), content, 0660)
+}
@@ -0,0 +1,85 @@
+package build
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"testing"
+	"time"
+
+	"github.com/deta/space/shared"
+)
+
+func TestCache(t *testing.T) {
+	if goruntime.GOOS == "windows" {
+		t.Skip("build commands use sh")
+	}
+
+	projectDir := writeProject(t, map[string]string{
+		"api/main.py":          "print('hello')",
+		"api/requirements.txt": "fastapi==0.95.0",
+	})
+	cache := &Cache{Dir: t.TempDir()}
+
+	// the virtualenv records the workspace it was created in, it is only restored to the same path
+	micro := &shared.Micro{
+		Name:   "api",
+		Src:    "api",
+		Engine: "python3.9",
+		Commands: []string{
+			`test ! -e .venv/installed || { test "$(cat .venv/installed)" = "$PWD" && echo restored > restored; }`,
+			"mkdir -p .venv && pwd > .venv/installed",
+		},
+	}
+	build := func() *Result {
+		result := Micro(micro, Options{ProjectDir: projectDir, OutputDir: t.TempDir(), Cache: cache, Stdout: io.Discard, Stderr: io.Discard})
+		if result.Err != nil {
+			t.Fatalf("failed to build micro: %v", result.Err)
+		}
+		return result
+	}
+
+	if result := build(); len(result.Cached) != 0 {
+		t.Fatalf("expected a cache miss, got %v", result.Cached)
+	}
+	result := build()
+	if len(result.Cached) != 1 || result.Cached[0] != ".venv" {
+		t.Fatalf("expected .venv to be restored, got %v", result.Cached)
+	}
+	if _, err := os.Stat(filepath.Join(result.Artifact, "restored")); err != nil {
+		t.Errorf("expected the commands to see the restored virtualenv at the path it was created in")
+	}
+
+	// changing the lockfile changes the key
+	if err := os.WriteFile(filepath.Join(projectDir, "api", "requirements.txt"), []byte("fastapi==0.96.0"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if result := build(); len(result.Cached) != 0 {
+		t.Errorf("expected a cache miss after changing the lockfile, got %v", result.Cached)
+	}
+
+	stats, err := cache.Stats()
+	if err != nil || stats.Entries != 2 {
+		t.Fatalf("expected 2 entries, got %+v, %v", stats, err)
+	}
+
+	removed, err := cache.Prune(time.Hour, 0)
+	if err != nil || len(removed) != 0 {
+		t.Errorf("expected recent entries to be kept, got %v, %v", removed, err)
+	}
+	removed, err = cache.Prune(0, 0)
+	if err != nil || len(removed) != 2 {
+		t.Errorf("expected every entry to be removed, got %v, %v", removed, err)
+	}
+}
+
+func TestCacheWithoutLockfile(t *testing.T) {
+	workspace := writeProject(t, map[string]string{"main.py": ""})
+	cache := &Cache{Dir: t.TempDir()}
+
+	_, ok, err := cache.Key(&shared.Micro{Engine: "python3.9"}, workspace)
+	if err != nil || ok {
+		t.Errorf("expected a workspace without lockfile to not be cached, got %t, %v", ok, err)
+	}
+}
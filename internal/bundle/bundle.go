@@ -0,0 +1,346 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// FormatVersion is the version of the bundle format written by Write
+const FormatVersion = 1
+
+// names of the members of a bundle
+const (
+	ManifestName  = "manifest.json"
+	CodeName      = "code.zip"
+	SpacefileName = "Spacefile"
+	IconName      = "icon.png"
+	DiscoveryName = "Discovery.md"
+	mediaDir      = "media"
+)
+
+var (
+	// ErrInvalidBundle is returned when a file is not a bundle or is missing members
+	ErrInvalidBundle = errors.New("invalid bundle")
+	// ErrDigestMismatch is returned when a member of a bundle does not match the digest of its manifest
+	ErrDigestMismatch = errors.New("digest mismatch")
+)
+
+// Media is a screenshot or a video url of the Discovery data
+type Media struct {
+	Raw         []byte
+	ContentType string
+}
+
+// Contents are the files written to a bundle
+type Contents struct {
+	// Code is the zipped code of the project, as written by runtime.Archive
+	Code io.Reader
+	// CodeDigest is the digest of the content of the zipped code
+	CodeDigest string
+	CodeFiles  int
+	// Spacefile is the raw Spacefile, as pushed
+	Spacefile []byte
+	AutoPWA   bool
+	// Icon is the processed icon, the bundle has no icon if it is empty
+	Icon            []byte
+	IconContentType string
+	// Discovery is the raw Discovery file, the bundle has no Discovery file if it is empty
+	Discovery []byte
+	Media     []Media
+	// CLIVersion is the version of the cli that wrote the bundle
+	CLIVersion string
+}
+
+// FileEntry is the digest of a member of a bundle
+type FileEntry struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	// SHA256 is the hex encoded digest of the member
+	SHA256 string `json:"sha256"`
+}
+
+// MediaEntry points to the media of the Discovery data in a bundle
+type MediaEntry struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+}
+
+// Manifest describes the members of a bundle, it is the last member of the bundle
+type Manifest struct {
+	Version    int       `json:"version"`
+	CreatedAt  time.Time `json:"created_at"`
+	CLIVersion string    `json:"cli_version,omitempty"`
+	AutoPWA    bool      `json:"auto_pwa"`
+	// CodeDigest is the digest of the content of the zipped code, the same as the digest of runtime.ArchiveStats
+	CodeDigest      string       `json:"code_digest"`
+	CodeFiles       int          `json:"code_files"`
+	IconContentType string       `json:"icon_content_type,omitempty"`
+	Media           []MediaEntry `json:"media,omitempty"`
+	Files           []FileEntry  `json:"files"`
+}
+
+// Write writes a bundle of the contents to w.
+// The zipped code is stored without compression, so that it can be read from the bundle without extracting it.
+func Write(w io.Writer, contents Contents) (*Manifest, error) {
+	zw := zip.NewWriter(w)
+	manifest := &Manifest{
+		Version:    FormatVersion,
+		CreatedAt:  time.Now().UTC(),
+		CLIVersion: contents.CLIVersion,
+		AutoPWA:    contents.AutoPWA,
+		CodeDigest: contents.CodeDigest,
+		CodeFiles:  contents.CodeFiles,
+	}
+
+	add := func(name string, method uint16, r io.Reader) error {
+		dst, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: manifest.CreatedAt})
+		if err != nil {
+			return err
+		}
+
+		h := sha256.New()
+		size, err := io.Copy(io.MultiWriter(dst, h), r)
+		if err != nil {
+			return fmt.Errorf("failed to write %s, %w", name, err)
+		}
+		manifest.Files = append(manifest.Files, FileEntry{Name: name, Size: size, SHA256: hex.EncodeToString(h.Sum(nil))})
+		return nil
+	}
+	addBytes := func(name string, content []byte) error {
+		return add(name, zip.Deflate, bytes.NewReader(content))
+	}
+
+	if err := add(CodeName, zip.Store, contents.Code); err != nil {
+		return nil, err
+	}
+	if err := addBytes(SpacefileName, contents.Spacefile); err != nil {
+		return nil, err
+	}
+	if len(contents.Icon) > 0 {
+		if err := addBytes(IconName, contents.Icon); err != nil {
+			return nil, err
+		}
+		manifest.IconContentType = contents.IconContentType
+	}
+	if len(contents.Discovery) > 0 {
+		if err := addBytes(DiscoveryName, contents.Discovery); err != nil {
+			return nil, err
+		}
+	}
+	for i, media := range contents.Media {
+		name := fmt.Sprintf("%s/%02d", mediaDir, i)
+		if err := addBytes(name, media.Raw); err != nil {
+			return nil, err
+		}
+		manifest.Media = append(manifest.Media, MediaEntry{Name: name, ContentType: media.ContentType})
+	}
+
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	dst, err := zw.CreateHeader(&zip.FileHeader{Name: ManifestName, Method: zip.Deflate, Modified: manifest.CreatedAt})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dst.Write(raw); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Bundle is an opened bundle, whose members match the digests of its manifest
+type Bundle struct {
+	Manifest Manifest
+
+	f *os.File
+	// members are the verified members of the bundle, the manifest is not one of them
+	members map[string]*zip.File
+}
+
+// Open opens the bundle at path and verifies the digest of every member
+func Open(path string) (*Bundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := open(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+func open(f *os.File) (*Bundle, error) {
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := zip.NewReader(f, stat.Size())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidBundle, err)
+	}
+
+	members := make(map[string]*zip.File, len(zr.File))
+	for _, member := range zr.File {
+		if _, ok := members[member.Name]; ok {
+			return nil, fmt.Errorf("%w: %s is present twice", ErrInvalidBundle, member.Name)
+		}
+		members[member.Name] = member
+	}
+
+	b := &Bundle{f: f}
+	manifest, ok := members[ManifestName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is missing", ErrInvalidBundle, ManifestName)
+	}
+	raw, err := readMember(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidBundle, err)
+	}
+	if err := json.Unmarshal(raw, &b.Manifest); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse %s, %s", ErrInvalidBundle, ManifestName, err)
+	}
+	if b.Manifest.Version != FormatVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d, update the cli to push this bundle", ErrInvalidBundle, b.Manifest.Version)
+	}
+
+	if b.members, err = verify(&b.Manifest, members); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// verify checks the members of a bundle against its manifest and returns the verified members.
+// Every member but the manifest has to be listed with its digest, including the icon and the media,
+// and every listed member has to match its digest.
+func verify(manifest *Manifest, members map[string]*zip.File) (map[string]*zip.File, error) {
+	verified := make(map[string]*zip.File, len(manifest.Files))
+	for _, entry := range manifest.Files {
+		if _, ok := verified[entry.Name]; ok {
+			return nil, fmt.Errorf("%w: %s is listed twice", ErrInvalidBundle, entry.Name)
+		}
+
+		member, ok := members[entry.Name]
+		if !ok || entry.Name == ManifestName {
+			return nil, fmt.Errorf("%w: %s is missing", ErrInvalidBundle, entry.Name)
+		}
+
+		r, err := member.Open()
+		if err != nil {
+			return nil, err
+		}
+		h := sha256.New()
+		size, err := io.Copy(h, r)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s, %w", entry.Name, err)
+		}
+
+		if size != entry.Size || hex.EncodeToString(h.Sum(nil)) != entry.SHA256 {
+			return nil, fmt.Errorf("%w: %s was modified", ErrDigestMismatch, entry.Name)
+		}
+		verified[entry.Name] = member
+	}
+
+	for name := range members {
+		if _, ok := verified[name]; !ok && name != ManifestName {
+			return nil, fmt.Errorf("%w: %s is not listed in the manifest", ErrInvalidBundle, name)
+		}
+	}
+
+	required := []string{CodeName, SpacefileName}
+	if manifest.IconContentType != "" {
+		required = append(required, IconName)
+	}
+	for _, media := range manifest.Media {
+		required = append(required, media.Name)
+	}
+	for _, name := range required {
+		if _, ok := verified[name]; !ok {
+			return nil, fmt.Errorf("%w: %s is missing from the files of the manifest", ErrInvalidBundle, name)
+		}
+	}
+
+	// the icon is pushed with the content type of the manifest
+	if _, ok := verified[IconName]; ok && manifest.IconContentType == "" {
+		return nil, fmt.Errorf("%w: %s has no content type", ErrInvalidBundle, IconName)
+	}
+	return verified, nil
+}
+
+// Close closes the file of the bundle
+func (b *Bundle) Close() error {
+	return b.f.Close()
+}
+
+// Has reports whether the bundle has a verified member
+func (b *Bundle) Has(name string) bool {
+	_, ok := b.members[name]
+	return ok
+}
+
+// ReadFile reads a verified member of the bundle
+func (b *Bundle) ReadFile(name string) ([]byte, error) {
+	member, ok := b.members[name]
+	if !ok {
+		return nil, fmt.Errorf("%s not found in bundle", name)
+	}
+	return readMember(member)
+}
+
+func readMember(member *zip.File) ([]byte, error) {
+	r, err := member.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// Code returns the zipped code of the bundle, read in place from the bundle file.
+// It stays valid until the bundle is closed.
+func (b *Bundle) Code() (io.ReadSeeker, error) {
+	member, ok := b.members[CodeName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is missing", ErrInvalidBundle, CodeName)
+	}
+	if member.Method != zip.Store {
+		return nil, fmt.Errorf("%w: %s is compressed", ErrInvalidBundle, CodeName)
+	}
+
+	offset, err := member.DataOffset()
+	if err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(b.f, offset, int64(member.UncompressedSize64)), nil
+}
+
+// Media reads the media of the Discovery data, in order
+func (b *Bundle) Media() ([]Media, error) {
+	media := make([]Media, 0, len(b.Manifest.Media))
+	for _, entry := range b.Manifest.Media {
+		raw, err := b.ReadFile(entry.Name)
+		if err != nil {
+			return nil, err
+		}
+		media = append(media, Media{Raw: raw, ContentType: entry.ContentType})
+	}
+	return media, nil
+}
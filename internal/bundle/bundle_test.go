@@ -0,0 +1,241 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func writeBundle(t *testing.T, contents Contents) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "app.spacebundle")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create bundle: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := Write(f, contents); err != nil {
+		t.Fatalf("failed to write bundle: %v", err)
+	}
+	return path
+}
+
+func TestWriteAndOpen(t *testing.T) {
+	code := []byte("PK zipped code")
+	path := writeBundle(t, Contents{
+		Code:            bytes.NewReader(code),
+		CodeDigest:      "abc",
+		CodeFiles:       3,
+		Spacefile:       []byte("v: 0\nmicros: []\n"),
+		AutoPWA:         true,
+		Icon:            []byte("png"),
+		IconContentType: "image/png",
+		Discovery:       []byte("---\ntitle: app\n---\n"),
+		Media:           []Media{{Raw: []byte("jpg"), ContentType: "image/jpeg"}, {Raw: []byte("https://youtu.be/x"), ContentType: "text/plain"}},
+	})
+
+	b, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to open bundle: %v", err)
+	}
+	defer b.Close()
+
+	if m := b.Manifest; m.Version != FormatVersion || !m.AutoPWA || m.CodeDigest != "abc" || m.CodeFiles != 3 || m.IconContentType != "image/png" || len(m.Files) != 6 {
+		t.Errorf("unexpected manifest %+v", m)
+	}
+
+	r, err := b.Code()
+	if err != nil {
+		t.Fatalf("failed to read code: %v", err)
+	}
+	// the code is read twice when the push is signed
+	for i := 0; i < 2; i++ {
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			t.Fatalf("failed to seek code: %v", err)
+		}
+		if actual, err := io.ReadAll(r); err != nil || !bytes.Equal(actual, code) {
+			t.Errorf("expected code %q, got %q, %v", code, actual, err)
+		}
+	}
+
+	media, err := b.Media()
+	if err != nil || len(media) != 2 || string(media[1].Raw) != "https://youtu.be/x" || media[0].ContentType != "image/jpeg" {
+		t.Errorf("unexpected media %+v, %v", media, err)
+	}
+	if !b.Has(DiscoveryName) || !b.Has(IconName) {
+		t.Errorf("expected the Discovery file and the icon in the bundle")
+	}
+}
+
+func TestOpenWithoutOptionalMembers(t *testing.T) {
+	path := writeBundle(t, Contents{Code: bytes.NewReader(nil), Spacefile: []byte("v: 0\n")})
+
+	b, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to open bundle: %v", err)
+	}
+	defer b.Close()
+
+	if b.Has(IconName) || b.Has(DiscoveryName) || len(b.Manifest.Media) != 0 {
+		t.Errorf("expected no optional members, got %+v", b.Manifest)
+	}
+}
+
+func TestOpenModifiedBundle(t *testing.T) {
+	path := writeBundle(t, Contents{Code: bytes.NewReader([]byte("original code")), Spacefile: []byte("v: 0\n")})
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read bundle: %v", err)
+	}
+	// the code is stored uncompressed, so it can be tampered with in place
+	raw = bytes.Replace(raw, []byte("original code"), []byte("modified code"), 1)
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		t.Fatalf("failed to write bundle: %v", err)
+	}
+
+	if _, err := Open(path); err == nil {
+		t.Fatalf("expected a modified bundle to be refused")
+	}
+}
+
+func TestOpenInvalidBundle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.spacebundle")
+	if err := os.WriteFile(path, []byte("not a bundle"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if _, err := Open(path); !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("expected ErrInvalidBundle, got %v", err)
+	}
+}
+
+// rewriteBundle rewrites the members of a bundle, the manifest is passed decoded and written last
+func rewriteBundle(t *testing.T, path string, rewrite func(manifest *Manifest, members map[string][]byte)) {
+	t.Helper()
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("failed to open bundle: %v", err)
+	}
+	members := make(map[string][]byte)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		members[f.Name], err = io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", f.Name, err)
+		}
+	}
+	zr.Close()
+
+	var manifest Manifest
+	if err := json.Unmarshal(members[ManifestName], &manifest); err != nil {
+		t.Fatalf("failed to parse manifest: %v", err)
+	}
+	delete(members, ManifestName)
+	rewrite(&manifest, members)
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatalf("failed to encode manifest: %v", err)
+	}
+
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	members[ManifestName] = raw
+	names = append(names, ManifestName)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		w.Write(members[name])
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to write bundle: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write bundle: %v", err)
+	}
+}
+
+func TestOpenBundleWithUnlistedMembers(t *testing.T) {
+	contents := func() Contents {
+		return Contents{
+			Code:            bytes.NewReader([]byte("code")),
+			Spacefile:       []byte("v: 0\n"),
+			Icon:            []byte("png"),
+			IconContentType: "image/png",
+			Media:           []Media{{Raw: []byte("jpg"), ContentType: "image/jpeg"}},
+		}
+	}
+
+	cases := map[string]func(manifest *Manifest, members map[string][]byte){
+		// an icon added to a bundle packed without one is not pushed
+		"unlisted icon": func(manifest *Manifest, members map[string][]byte) {
+			manifest.Files = removeEntry(manifest.Files, IconName)
+		},
+		"unlisted member": func(manifest *Manifest, members map[string][]byte) {
+			members["extra.js"] = []byte("alert(1)")
+		},
+		"unlisted media": func(manifest *Manifest, members map[string][]byte) {
+			manifest.Files = removeEntry(manifest.Files, manifest.Media[0].Name)
+		},
+		"missing media": func(manifest *Manifest, members map[string][]byte) {
+			manifest.Media = append(manifest.Media, MediaEntry{Name: "media/01", ContentType: "image/jpeg"})
+		},
+		"icon without content type": func(manifest *Manifest, members map[string][]byte) {
+			manifest.IconContentType = ""
+		},
+	}
+
+	for name, rewrite := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := writeBundle(t, contents())
+			rewriteBundle(t, path, rewrite)
+
+			if _, err := Open(path); !errors.Is(err, ErrInvalidBundle) {
+				t.Fatalf("expected ErrInvalidBundle, got %v", err)
+			}
+		})
+	}
+
+	// rewriting without changes keeps the bundle valid
+	path := writeBundle(t, contents())
+	rewriteBundle(t, path, func(manifest *Manifest, members map[string][]byte) {})
+	b, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to open bundle: %v", err)
+	}
+	defer b.Close()
+	if !b.Has(IconName) || b.Has(ManifestName) {
+		t.Errorf("expected only the listed members to be reported")
+	}
+}
+
+func removeEntry(entries []FileEntry, name string) []FileEntry {
+	var kept []FileEntry
+	for _, entry := range entries {
+		if entry.Name != name {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,109 @@
+package devstate
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const tokenHeader = "X-Space-Dev-Token"
+
+// Controller stops and restarts what a space process runs, on behalf of space dev stop and space dev restart
+type Controller interface {
+	// Stop stops a micro, or everything the space process runs if name is empty
+	Stop(name string) error
+	// Restart restarts a micro
+	Restart(name string) error
+}
+
+// Serve handles the control requests of other space processes on a local port until ctx is done.
+// It returns the address and the token of the control endpoint, which are recorded in the entries of the space process.
+func Serve(ctx context.Context, c Controller) (string, string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to listen for control requests, %w", err)
+	}
+
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		listener.Close()
+		return "", "", err
+	}
+	token := hex.EncodeToString(raw)
+
+	mux := http.NewServeMux()
+	handle := func(fn func(name string) error) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			if r.Header.Get(tokenHeader) != token {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			if err := fn(r.URL.Query().Get("name")); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+	mux.Handle("/stop", handle(c.Stop))
+	mux.Handle("/restart", handle(c.Restart))
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	return listener.Addr().String(), token, nil
+}
+
+// Stop asks the space process running an entry to stop a micro, or everything it runs if name is empty
+func Stop(e Entry, name string) error {
+	return control(e, "stop", name)
+}
+
+// Restart asks the space process running an entry to restart a micro
+func Restart(e Entry, name string) error {
+	return control(e, "restart", name)
+}
+
+func control(e Entry, action string, name string) error {
+	if e.Control == "" {
+		return fmt.Errorf("%s %s is not controlled by a running space process", e.Kind, e.Name)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s/%s?name=%s", e.Control, action, url.QueryEscape(name)), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(tokenHeader, e.Token)
+
+	client := http.Client{Timeout: 10 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach the space process %d, %w", e.Owner, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusNoContent {
+		msg, _ := io.ReadAll(res.Body)
+		if len(msg) == 0 {
+			return fmt.Errorf("the space process %d answered %s", e.Owner, res.Status)
+		}
+		return errors.New(strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package devstate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type controller struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (c *controller) Stop(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, "stop "+name)
+	return nil
+}
+
+func (c *controller) Restart(name string) error {
+	if name != "api" {
+		return fmt.Errorf("micro %s is not running", name)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, "restart "+name)
+	return nil
+}
+
+func TestControl(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := &controller{}
+	addr, token, err := Serve(ctx, c)
+	if err != nil {
+		t.Fatalf("failed to serve: %v", err)
+	}
+	e := Entry{Kind: KindMicro, Name: "api", Owner: os.Getpid(), Control: addr, Token: token}
+
+	if err := Restart(e, "api"); err != nil {
+		t.Errorf("failed to restart micro: %v", err)
+	}
+	if err := Stop(e, ""); err != nil {
+		t.Errorf("failed to stop: %v", err)
+	}
+	if err := Restart(e, "web"); err == nil || err.Error() != "micro web is not running" {
+		t.Errorf("expected the error of the controller, got %v", err)
+	}
+
+	e.Token = "wrong"
+	if err := Stop(e, "api"); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected a request with a wrong token to be rejected, got %v", err)
+	}
+
+	if got := strings.Join(c.calls, ","); got != "restart api,stop " {
+		t.Errorf("unexpected calls %q", got)
+	}
+
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	e.Token = token
+	if err := Stop(e, ""); err == nil {
+		t.Errorf("expected the control endpoint to be closed")
+	}
+}
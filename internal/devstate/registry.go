@@ -0,0 +1,226 @@
+package devstate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/deta/space/internal/supervisor"
+)
+
+const (
+	// registryPath and lockPath are relative to the project directory
+	registryPath = ".space/dev.json"
+	lockPath     = ".space/dev.lock"
+)
+
+// Kind of a registry entry
+type Kind string
+
+const (
+	KindMicro Kind = "micro"
+	KindProxy Kind = "proxy"
+)
+
+// StatusOrphaned is the status of a micro still running after the space process that ran it exited
+const StatusOrphaned = "orphaned"
+
+// Entry is a micro or a proxy run by a space process
+type Entry struct {
+	Kind Kind   `json:"kind"`
+	Name string `json:"name"`
+	// Status is the status of the supervised process of a micro, the proxy is running as long as it is registered
+	Status string `json:"status"`
+	// PID is the id of the running process of a micro, or of the space process running a proxy
+	PID       int       `json:"pid,omitempty"`
+	Host      string    `json:"host,omitempty"`
+	Port      int       `json:"port"`
+	Command   string    `json:"command,omitempty"`
+	Restarts  int       `json:"restarts,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	// Owner is the id of the space process running the entry, it is controlled on the Control address with Token
+	Owner   int    `json:"owner"`
+	Control string `json:"control,omitempty"`
+	Token   string `json:"token,omitempty"`
+}
+
+// Running reports whether the entry accepts requests
+func (e Entry) Running() bool {
+	return e.Status == string(supervisor.StatusRunning) || e.Status == StatusOrphaned
+}
+
+// Orphaned reports whether the space process running the entry exited, leaving its process running
+func (e Entry) Orphaned() bool {
+	return e.Status == StatusOrphaned
+}
+
+// Registry records what the space processes of a project run, in a state file shared by space dev, space dev up and space dev proxy.
+// The state file is locked while it is read or written, and the entries of space processes that exited are pruned when it is.
+type Registry struct {
+	path     string
+	lockPath string
+}
+
+// Open returns the registry of a project, the state file is created on the first write
+func Open(projectDir string) *Registry {
+	return &Registry{
+		path:     filepath.Join(projectDir, registryPath),
+		lockPath: filepath.Join(projectDir, lockPath),
+	}
+}
+
+// Register adds an entry, replacing the entry of the same kind and name
+func (r *Registry) Register(e Entry) error {
+	return r.update(func(entries []Entry) []Entry {
+		entries = remove(entries, func(old Entry) bool {
+			return old.Kind == e.Kind && old.Name == e.Name
+		})
+		return append(entries, e)
+	})
+}
+
+// Update changes the entry of a kind and name registered by owner, if any
+func (r *Registry) Update(kind Kind, name string, owner int, fn func(e *Entry)) error {
+	return r.update(func(entries []Entry) []Entry {
+		for i := range entries {
+			if entries[i].Kind == kind && entries[i].Name == name && entries[i].Owner == owner {
+				fn(&entries[i])
+			}
+		}
+		return entries
+	})
+}
+
+// Remove removes the entry of a kind and name, an owner only removes its own entries if it is not 0
+func (r *Registry) Remove(kind Kind, name string, owner int) error {
+	return r.update(func(entries []Entry) []Entry {
+		return remove(entries, func(e Entry) bool {
+			return e.Kind == kind && e.Name == name && (owner == 0 || e.Owner == owner)
+		})
+	})
+}
+
+// RemoveOwner removes all the entries of a space process, once it exits
+func (r *Registry) RemoveOwner(owner int) error {
+	return r.update(func(entries []Entry) []Entry {
+		return remove(entries, func(e Entry) bool {
+			return e.Owner == owner
+		})
+	})
+}
+
+// List returns the entries, the proxies first, then by name
+func (r *Registry) List() ([]Entry, error) {
+	var list []Entry
+	err := r.update(func(entries []Entry) []Entry {
+		list = append(list, entries...)
+		return entries
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Kind != list[j].Kind {
+			return list[i].Kind == KindProxy
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// Micro returns the entry of a micro
+func (r *Registry) Micro(name string) (Entry, bool, error) {
+	entries, err := r.List()
+	if err != nil {
+		return Entry{}, false, err
+	}
+	for _, e := range entries {
+		if e.Kind == KindMicro && e.Name == name {
+			return e, true, nil
+		}
+	}
+	return Entry{}, false, nil
+}
+
+type state struct {
+	Entries []Entry `json:"entries"`
+}
+
+// update locks the state file, prunes its entries and saves the entries returned by fn
+func (r *Registry) update(fn func(entries []Entry) []Entry) error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("failed to create the state directory, %w", err)
+	}
+
+	unlock, err := lockFile(r.lockPath)
+	if err != nil {
+		return fmt.Errorf("failed to lock the dev state, %w", err)
+	}
+	defer unlock()
+
+	var s state
+	raw, err := os.ReadFile(r.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read the dev state, %w", err)
+	}
+	// a corrupted state file is replaced, the processes it recorded are lost
+	if len(raw) > 0 {
+		json.Unmarshal(raw, &s)
+	}
+
+	s.Entries = fn(prune(s.Entries))
+	if len(s.Entries) == 0 {
+		if err := os.Remove(r.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove the dev state, %w", err)
+		}
+		return nil
+	}
+
+	raw, err = json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// the state is replaced at once, so that it is never read half written
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return fmt.Errorf("failed to write the dev state, %w", err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write the dev state, %w", err)
+	}
+	return nil
+}
+
+// prune drops the entries of space processes that exited, their micros that are still running are kept as orphaned
+func prune(entries []Entry) []Entry {
+	var pruned []Entry
+	for _, e := range entries {
+		if supervisor.Alive(e.Owner) {
+			pruned = append(pruned, e)
+			continue
+		}
+		if e.Kind == KindMicro && e.PID != 0 && supervisor.Alive(e.PID) {
+			e.Status = StatusOrphaned
+			e.Control, e.Token = "", ""
+			pruned = append(pruned, e)
+		}
+	}
+	return pruned
+}
+
+func remove(entries []Entry, match func(e Entry) bool) []Entry {
+	var kept []Entry
+	for _, e := range entries {
+		if !match(e) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
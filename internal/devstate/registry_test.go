@@ -0,0 +1,109 @@
+package devstate
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+
+	"github.com/deta/space/internal/supervisor"
+)
+
+// exitedPID returns the id of a process that exited
+func exitedPID(t *testing.T) int {
+	t.Helper()
+
+	cmd := exec.Command("go", "version")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("failed to run process: %v", err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestRegistry(t *testing.T) {
+	r := Open(t.TempDir())
+	owner := os.Getpid()
+
+	if err := r.Register(Entry{Kind: KindMicro, Name: "api", Port: 4201, Owner: owner, Status: "starting"}); err != nil {
+		t.Fatalf("failed to register micro: %v", err)
+	}
+	if err := r.Register(Entry{Kind: KindProxy, Name: "proxy", Port: 4200, PID: owner, Owner: owner, Status: "running"}); err != nil {
+		t.Fatalf("failed to register proxy: %v", err)
+	}
+	if err := r.Update(KindMicro, "api", owner, func(e *Entry) {
+		e.Status = string(supervisor.StatusRunning)
+		e.PID = owner
+	}); err != nil {
+		t.Fatalf("failed to update micro: %v", err)
+	}
+
+	entries, err := r.List()
+	if err != nil {
+		t.Fatalf("failed to list entries: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Kind != KindProxy || entries[1].Name != "api" {
+		t.Fatalf("expected the proxy then the micro, got %+v", entries)
+	}
+
+	micro, ok, err := r.Micro("api")
+	if err != nil || !ok {
+		t.Fatalf("expected micro api to be registered, got %v", err)
+	}
+	if !micro.Running() || micro.Port != 4201 {
+		t.Errorf("expected micro api to run on port 4201, got %+v", micro)
+	}
+
+	if err := r.RemoveOwner(owner); err != nil {
+		t.Fatalf("failed to remove entries: %v", err)
+	}
+	if entries, _ := r.List(); len(entries) != 0 {
+		t.Errorf("expected no entries, got %+v", entries)
+	}
+	if _, err := os.Stat(r.path); !os.IsNotExist(err) {
+		t.Errorf("expected the state file to be removed")
+	}
+}
+
+func TestRegistryPrune(t *testing.T) {
+	r := Open(t.TempDir())
+	dead := exitedPID(t)
+
+	r.Register(Entry{Kind: KindMicro, Name: "api", PID: dead, Owner: dead, Status: "running"})
+	// the space process exited, but the micro still runs
+	r.Register(Entry{Kind: KindMicro, Name: "web", PID: os.Getpid(), Owner: dead, Status: "running", Control: "127.0.0.1:1"})
+
+	entries, err := r.List()
+	if err != nil {
+		t.Fatalf("failed to list entries: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "web" {
+		t.Fatalf("expected only micro web, got %+v", entries)
+	}
+	if !entries[0].Orphaned() || entries[0].Control != "" {
+		t.Errorf("expected micro web to be orphaned, got %+v", entries[0])
+	}
+}
+
+func TestRegistryConcurrentUpdates(t *testing.T) {
+	dir := t.TempDir()
+	owner := os.Getpid()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// every writer opens the registry like a separate space process
+			if err := Open(dir).Register(Entry{Kind: KindMicro, Name: fmt.Sprintf("micro-%02d", i), Owner: owner}); err != nil {
+				t.Errorf("failed to register micro: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if entries, _ := Open(dir).List(); len(entries) != 20 {
+		t.Errorf("expected 20 entries, got %d", len(entries))
+	}
+}
@@ -1,11 +1,14 @@
 package discovery
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/adrg/frontmatter"
 	"github.com/deta/space/shared"
 	"gopkg.in/yaml.v2"
 )
@@ -59,3 +62,46 @@ func CreateDiscoveryFile(name string, discovery shared.DiscoveryData) error {
 
 	return nil
 }
+
+// ReadDiscoveryFile parses the front matter and the content of a Discovery file
+func ReadDiscoveryFile(name string) (*shared.DiscoveryData, error) {
+	df, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	discoveryData := &shared.DiscoveryData{}
+	rest, err := frontmatter.Parse(bytes.NewReader(df), discoveryData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the Discovery file, %w", err)
+	}
+	discoveryData.ContentRaw = string(rest)
+
+	return discoveryData, nil
+}
+
+// SetAppName sets the app name in the Discovery file of a project if it has none, the file is created if needed.
+// It reports whether the file was changed.
+func SetAppName(sourceDir string, appName string) (bool, error) {
+	discoveryPath := filepath.Join(sourceDir, DiscoveryFilename)
+
+	discoveryData := &shared.DiscoveryData{}
+	if _, err := os.Stat(discoveryPath); err == nil {
+		if discoveryData, err = ReadDiscoveryFile(discoveryPath); err != nil {
+			return false, err
+		}
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+
+	if discoveryData.AppName != "" {
+		return false, nil
+	}
+
+	discoveryData.AppName = appName
+	if err := CreateDiscoveryFile(discoveryPath, *discoveryData); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
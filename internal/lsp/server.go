@@ -0,0 +1,452 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deta/space/internal/spacefile"
+	"github.com/deta/space/shared"
+)
+
+const serverName = "space"
+
+var ErrInvalidHeader = errors.New("invalid message header")
+
+// diagnosticsDelay is how long the server waits for the changes of a document to settle before checking it,
+// so that the project is not walked on every keystroke
+const diagnosticsDelay = 300 * time.Millisecond
+
+// Server is a language server for Spacefiles, it speaks json-rpc over a pair of streams, usually stdin and stdout
+type Server struct {
+	reader  *bufio.Reader
+	writer  io.Writer
+	version string
+
+	// writeMu guards the writer, so that messages are not interleaved
+	writeMu sync.Mutex
+
+	// mu guards the documents and the pending checks, which run after diagnosticsDelay
+	mu        sync.Mutex
+	documents map[string][]byte
+	pending   map[string]*time.Timer
+}
+
+// NewServer creates a server reading requests from in and writing responses to out
+func NewServer(in io.Reader, out io.Writer, version string) *Server {
+	return &Server{
+		reader:    bufio.NewReader(in),
+		writer:    out,
+		version:   version,
+		documents: make(map[string][]byte),
+		pending:   make(map[string]*time.Timer),
+	}
+}
+
+// Run serves requests until the client sends exit or closes the input
+func (s *Server) Run() error {
+	for {
+		body, err := s.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			s.respond(nil, nil, &responseError{Code: codeParseError, Message: err.Error()})
+			continue
+		}
+
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		result, rerr := s.handle(&msg)
+		if msg.ID != nil {
+			s.respond(msg.ID, result, rerr)
+		}
+	}
+}
+
+// read reads the body of the next message
+func (s *Server) read() ([]byte, error) {
+	length := -1
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidHeader, line)
+		}
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidHeader, line)
+			}
+		}
+	}
+
+	if length < 0 {
+		return nil, fmt.Errorf("%w: missing Content-Length", ErrInvalidHeader)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.reader, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// write writes a message with its header
+func (s *Server) write(v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if _, err := fmt.Fprintf(s.writer, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = s.writer.Write(body)
+	return err
+}
+
+// respond sends the result of a request, or its error, a response cannot have both
+func (s *Server) respond(id *json.RawMessage, result any, rerr *responseError) {
+	if rerr != nil {
+		s.write(errorResponse{JSONRPC: jsonrpcVersion, ID: id, Error: rerr})
+		return
+	}
+	s.write(response{JSONRPC: jsonrpcVersion, ID: id, Result: result})
+}
+
+func (s *Server) notify(method string, params any) {
+	s.write(notification{JSONRPC: jsonrpcVersion, Method: method, Params: params})
+}
+
+func (s *Server) handle(msg *message) (any, *responseError) {
+	switch msg.Method {
+	case "initialize":
+		return initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:   textDocumentSyncFull,
+				CompletionProvider: completionOptions{TriggerCharacters: []string{":", " "}},
+				HoverProvider:      true,
+				DefinitionProvider: true,
+			},
+			ServerInfo: serverInfo{Name: serverName, Version: s.version},
+		}, nil
+	case "shutdown":
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		s.setDocument(params.TextDocument.URI, []byte(params.TextDocument.Text))
+		s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		// the sync is full, the last change holds the whole document
+		if n := len(params.ContentChanges); n > 0 {
+			s.setDocument(params.TextDocument.URI, []byte(params.ContentChanges[n-1].Text))
+		}
+		s.schedulePublishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		s.closeDocument(params.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []diagnostic{}})
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.completion(params), nil
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.hover(params), nil
+	case "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.definition(params), nil
+	default:
+		if msg.ID != nil {
+			return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %s not found", msg.Method)}
+		}
+	}
+
+	return nil, nil
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+func (s *Server) document(uri string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, ok := s.documents[uri]
+	return content, ok
+}
+
+func (s *Server) setDocument(uri string, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.documents[uri] = content
+}
+
+// closeDocument forgets a document and cancels its pending check
+func (s *Server) closeDocument(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.documents, uri)
+	if timer, ok := s.pending[uri]; ok {
+		timer.Stop()
+		delete(s.pending, uri)
+	}
+}
+
+// schedulePublishDiagnostics checks a document once it stopped changing for diagnosticsDelay
+func (s *Server) schedulePublishDiagnostics(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if timer, ok := s.pending[uri]; ok {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(diagnosticsDelay, func() {
+		s.mu.Lock()
+		// the timer may have been replaced or stopped while it fired
+		current := s.pending[uri] == timer
+		if current {
+			delete(s.pending, uri)
+		}
+		s.mu.Unlock()
+
+		if current {
+			s.publishDiagnostics(uri)
+		}
+	})
+	s.pending[uri] = timer
+}
+
+// publishDiagnostics checks a document and sends the problems found to the client
+func (s *Server) publishDiagnostics(uri string) {
+	path, err := uriToPath(uri)
+	if err != nil {
+		return
+	}
+	content, ok := s.document(uri)
+	if !ok {
+		return
+	}
+
+	diagnostics := []diagnostic{}
+	found, err := spacefile.LintContent(filepath.Dir(path), path, content)
+	if err != nil {
+		diagnostics = append(diagnostics, diagnostic{Severity: diagnosticError, Source: serverName, Message: err.Error()})
+	}
+
+	for _, d := range found {
+		diagnostics = append(diagnostics, toDiagnostic(d))
+	}
+
+	s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+}
+
+func toDiagnostic(d spacefile.Diagnostic) diagnostic {
+	result := diagnostic{Code: d.Rule, Source: serverName, Message: d.Message}
+
+	switch d.Severity {
+	case spacefile.SeverityError:
+		result.Severity = diagnosticError
+	case spacefile.SeverityWarning:
+		result.Severity = diagnosticWarning
+	default:
+		result.Severity = diagnosticInformation
+	}
+
+	if d.Micro != "" {
+		result.Message = fmt.Sprintf("Micro '%s': %s", d.Micro, d.Message)
+	}
+
+	if d.Line > 0 {
+		start := position{Line: d.Line - 1}
+		if d.Column > 0 {
+			start.Character = d.Column - 1
+		}
+		length := d.Length
+		if length < 1 {
+			length = 1
+		}
+		result.Range = lspRange{Start: start, End: position{Line: start.Line, Character: start.Character + length}}
+	}
+
+	return result
+}
+
+var engineValueReg = regexp.MustCompile(`^\s*(?:-\s+)?engine:\s*["']?[\w.-]*$`)
+
+// completion completes the engine of a micro, from the engines and aliases supported by the cli
+func (s *Server) completion(params textDocumentPositionParams) completionList {
+	list := completionList{Items: []completionItem{}}
+
+	content, _ := s.document(params.TextDocument.URI)
+	lines := strings.Split(string(content), "\n")
+	if params.Position.Line >= len(lines) {
+		return list
+	}
+	line := lines[params.Position.Line]
+	if params.Position.Character < len(line) {
+		line = line[:params.Position.Character]
+	}
+
+	if !engineValueReg.MatchString(line) {
+		return list
+	}
+
+	// only suggest the engines the schema accepts
+	accepted := make(map[string]struct{})
+	for _, engine := range spacefile.Engines() {
+		accepted[engine] = struct{}{}
+	}
+
+	names := make(map[string]struct{})
+	for _, engine := range shared.SupportedEngines {
+		names[engine] = struct{}{}
+	}
+	for alias := range shared.EngineAliases {
+		names[alias] = struct{}{}
+	}
+
+	for name := range names {
+		if _, ok := accepted[name]; !ok {
+			continue
+		}
+
+		item := completionItem{Label: name, Kind: completionKindValue}
+		if engine, ok := shared.EngineAliases[name]; ok && engine != name {
+			item.Detail = fmt.Sprintf("alias of %s", engine)
+		}
+		list.Items = append(list.Items, item)
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Label < list.Items[j].Label
+	})
+	return list
+}
+
+// hover documents the field under the cursor with its description from the schema
+func (s *Server) hover(params textDocumentPositionParams) *hover {
+	content, _ := s.document(params.TextDocument.URI)
+	field, ok := spacefile.FieldAt(content, params.Position.Line+1, params.Position.Character+1)
+	if !ok {
+		return nil
+	}
+
+	documentation := field.Documentation()
+	if documentation == "" {
+		return nil
+	}
+
+	start := position{Line: field.Node.Line - 1, Character: field.Node.Column - 1}
+	return &hover{
+		Contents: markupContent{Kind: "markdown", Value: documentation},
+		Range:    &lspRange{Start: start, End: position{Line: start.Line, Character: start.Character + len(field.Node.Value)}},
+	}
+}
+
+// definition resolves the src, icon and include paths under the cursor
+func (s *Server) definition(params textDocumentPositionParams) *location {
+	path, err := uriToPath(params.TextDocument.URI)
+	if err != nil {
+		return nil
+	}
+
+	content, _ := s.document(params.TextDocument.URI)
+	field, ok := spacefile.FieldAt(content, params.Position.Line+1, params.Position.Character+1)
+	if !ok {
+		return nil
+	}
+
+	target, ok := field.Target(filepath.Dir(path), content)
+	if !ok {
+		return nil
+	}
+
+	if _, err := os.Stat(target); err != nil {
+		return nil
+	}
+
+	return &location{URI: pathToURI(target)}
+}
+
+func uriToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported uri scheme %s", u.Scheme)
+	}
+
+	path := u.Path
+	// file:///C:/project/Spacefile
+	if runtime.GOOS == "windows" {
+		path = strings.TrimPrefix(path, "/")
+	}
+	return filepath.FromSlash(path), nil
+}
+
+func pathToURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
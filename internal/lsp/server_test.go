@@ -0,0 +1,253 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// client is an in-process client talking to a server over pipes
+type client struct {
+	t      *testing.T
+	writer io.Writer
+	// reader reuses the framing of the server to read its messages
+	reader *Server
+	nextID int
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	server := NewServer(serverIn, serverOut, "test")
+	go func() {
+		server.Run()
+		serverOut.Close()
+	}()
+	t.Cleanup(func() { clientOut.Close() })
+
+	return &client{t: t, writer: clientOut, reader: &Server{reader: bufio.NewReader(clientIn)}}
+}
+
+func (c *client) send(v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		c.t.Fatalf("failed to marshal message: %v", err)
+	}
+	if _, err := io.WriteString(c.writer, "Content-Length: "+strconv.Itoa(len(body))+"\r\n\r\n"+string(body)); err != nil {
+		c.t.Fatalf("failed to send message: %v", err)
+	}
+}
+
+// receive reads the next message sent by the server
+func (c *client) receive() message {
+	body, err := c.reader.read()
+	if err != nil {
+		c.t.Fatalf("failed to read message: %v", err)
+	}
+
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		c.t.Fatalf("failed to unmarshal message: %v", err)
+	}
+	return msg
+}
+
+// request sends a request and decodes the result of its response into result
+func (c *client) request(method string, params any, result any) {
+	c.nextID++
+	c.send(map[string]any{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+
+	var raw struct {
+		Result json.RawMessage `json:"result"`
+		Error  *responseError  `json:"error"`
+	}
+	body, err := c.reader.read()
+	if err != nil {
+		c.t.Fatalf("failed to read response to %s: %v", method, err)
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		c.t.Fatalf("failed to unmarshal response to %s: %v", method, err)
+	}
+	if raw.Error != nil {
+		c.t.Fatalf("%s failed: %s", method, raw.Error.Message)
+	}
+	if err := json.Unmarshal(raw.Result, result); err != nil {
+		c.t.Fatalf("failed to unmarshal result of %s: %v", method, err)
+	}
+}
+
+func (c *client) notify(method string, params any) {
+	c.send(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func openProject(t *testing.T) (*client, string) {
+	path, err := filepath.Abs(filepath.Join("testdata", "project", "Spacefile"))
+	if err != nil {
+		t.Fatalf("failed to get absolute path: %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read spacefile: %v", err)
+	}
+
+	c := newClient(t)
+	var result initializeResult
+	c.request("initialize", map[string]any{}, &result)
+	if !result.Capabilities.HoverProvider || !result.Capabilities.DefinitionProvider {
+		t.Fatalf("expected hover and definition capabilities, got %+v", result.Capabilities)
+	}
+
+	uri := pathToURI(path)
+	c.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "yaml", "version": 1, "text": string(content)},
+	})
+	return c, uri
+}
+
+func TestDiagnostics(t *testing.T) {
+	c, uri := openProject(t)
+
+	msg := c.receive()
+	if msg.Method != "textDocument/publishDiagnostics" {
+		t.Fatalf("expected diagnostics to be published, got %s", msg.Method)
+	}
+
+	var params publishDiagnosticsParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		t.Fatalf("failed to unmarshal diagnostics: %v", err)
+	}
+
+	if params.URI != uri || len(params.Diagnostics) != 1 {
+		t.Fatalf("expected a single diagnostic for %s, got %+v", uri, params)
+	}
+
+	d := params.Diagnostics[0]
+	if d.Severity != diagnosticError || d.Code != "schema/enum" || d.Range.Start.Line != 4 || d.Range.Start.Character != 12 {
+		t.Fatalf("expected an enum error at 4:12, got %+v", d)
+	}
+
+	// changes in quick succession are checked once, with the last version of the document
+	for version, text := range []string{
+		"v: 0\nmicros:\n  - name: api\n    src: api\n    engine: python\n",
+		"v: 0\nmicros:\n  - name: api\n    src: api\n    engine: python3.9\n",
+	} {
+		c.notify("textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": version + 2},
+			"contentChanges": []map[string]any{{"text": text}},
+		})
+	}
+
+	msg = c.receive()
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		t.Fatalf("failed to unmarshal diagnostics: %v", err)
+	}
+	for _, d := range params.Diagnostics {
+		if d.Severity == diagnosticError {
+			t.Fatalf("expected no errors after the change, got %+v", d)
+		}
+	}
+
+	// the next message is the response to the request, not a second check
+	var result hover
+	c.request("textDocument/hover", map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": 4, "character": 6},
+	}, &result)
+}
+
+func TestErrorResponse(t *testing.T) {
+	c := newClient(t)
+	c.send(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "unknown"})
+
+	body, err := c.reader.read()
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if _, ok := fields["error"]; !ok {
+		t.Fatalf("expected an error, got %s", body)
+	}
+	if _, ok := fields["result"]; ok {
+		t.Fatalf("expected no result next to the error, got %s", body)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c, uri := openProject(t)
+	c.receive()
+
+	var list completionList
+	c.request("textDocument/completion", map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": 4, "character": 12},
+	}, &list)
+
+	labels := make(map[string]bool)
+	for _, item := range list.Items {
+		labels[item.Label] = true
+	}
+	for _, engine := range []string{"python3.9", "nodejs16", "static", "custom"} {
+		if !labels[engine] {
+			t.Errorf("expected %s to be completed, got %+v", engine, list.Items)
+		}
+	}
+
+	c.request("textDocument/completion", map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": 3, "character": 10},
+	}, &list)
+	if len(list.Items) != 0 {
+		t.Errorf("expected no completion outside of engine, got %+v", list.Items)
+	}
+}
+
+func TestHover(t *testing.T) {
+	c, uri := openProject(t)
+	c.receive()
+
+	var result hover
+	c.request("textDocument/hover", map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": 4, "character": 6},
+	}, &result)
+
+	if !strings.Contains(result.Contents.Value, "Runtime engine for the Micro") {
+		t.Fatalf("expected the engine to be documented, got %q", result.Contents.Value)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	c, uri := openProject(t)
+	c.receive()
+
+	cases := map[int]string{
+		3: "api",
+		7: filepath.Join("api", "main.py"),
+	}
+
+	for line, expected := range cases {
+		var result location
+		c.request("textDocument/definition", map[string]any{
+			"textDocument": map[string]any{"uri": uri},
+			"position":     map[string]any{"line": line, "character": 10},
+		}, &result)
+
+		path, err := uriToPath(result.URI)
+		if err != nil {
+			t.Fatalf("invalid definition uri %s: %v", result.URI, err)
+		}
+		if !strings.HasSuffix(path, filepath.Join("project", expected)) {
+			t.Errorf("expected line %d to point to %s, got %s", line, expected, path)
+		}
+	}
+}
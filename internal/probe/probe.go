@@ -0,0 +1,130 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTimeout is how long a micro has to become ready by default
+	DefaultTimeout = 60 * time.Second
+
+	initialBackoff = 50 * time.Millisecond
+	maxBackoff     = time.Second
+	// attemptTimeout bounds a single connection or request
+	attemptTimeout = 2 * time.Second
+)
+
+// ErrNotReady is returned when a micro does not become ready in time
+var ErrNotReady = errors.New("not ready")
+
+// Options configures Wait
+type Options struct {
+	// Host the micro listens on, localhost if it is empty
+	Host string
+	// Path, if set, is requested once the port accepts connections.
+	// The micro is ready once the path answers without a server error.
+	Path string
+	// Timeout is how long the micro has to become ready, DefaultTimeout if it is zero
+	Timeout time.Duration
+}
+
+// NotReadyError is returned when a micro does not become ready in time, it holds the last failed check
+type NotReadyError struct {
+	Timeout  time.Duration
+	Attempts int
+	Err      error
+}
+
+func (e *NotReadyError) Error() string {
+	return fmt.Sprintf("not ready after %s (%d attempts), %s", e.Timeout, e.Attempts, e.Err)
+}
+
+func (e *NotReadyError) Unwrap() error {
+	return ErrNotReady
+}
+
+// Wait waits until the port accepts connections and, if a path is set, answers it.
+// Checks are retried with an exponential backoff until the timeout, or until ctx is done.
+func Wait(ctx context.Context, port int, opts Options) error {
+	host := opts.Host
+	if host == "" {
+		host = "localhost"
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	client := &http.Client{
+		Timeout: attemptTimeout,
+		// a redirect is enough to know that the micro answers
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		err := check(ctx, client, addr, opts.Path)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return &NotReadyError{Timeout: timeout, Attempts: attempt, Err: err}
+			}
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// check connects to the address, then requests the path if it is set
+func check(ctx context.Context, client *http.Client, addr string, path string) error {
+	dialer := net.Dialer{Timeout: attemptTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	conn.Close()
+
+	if path == "" {
+		return nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s%s", addr, path), nil)
+	if err != nil {
+		return err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("GET %s answered %s", path, res.Status)
+	}
+	return nil
+}
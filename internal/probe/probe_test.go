@@ -0,0 +1,90 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func serverPort(t *testing.T, server *httptest.Server) int {
+	t.Helper()
+
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to parse server address: %v", err)
+	}
+	p, _ := strconv.Atoi(port)
+	return p
+}
+
+// freePort returns a port nothing listens on
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestWaitTCP(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	if err := Wait(context.Background(), serverPort(t, server), Options{Host: "127.0.0.1", Timeout: time.Second}); err != nil {
+		t.Errorf("expected the micro to be ready, got %v", err)
+	}
+}
+
+func TestWaitPath(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the micro is still starting for the first requests
+		if atomic.AddInt32(&requests, 1) < 3 || r.URL.Path != "/__space/actions" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"actions": []}`))
+	}))
+	defer server.Close()
+
+	if err := Wait(context.Background(), serverPort(t, server), Options{Host: "127.0.0.1", Path: "__space/actions", Timeout: 5 * time.Second}); err != nil {
+		t.Errorf("expected the micro to be ready, got %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestWaitNotReady(t *testing.T) {
+	start := time.Now()
+	err := Wait(context.Background(), freePort(t), Options{Host: "127.0.0.1", Timeout: 300 * time.Millisecond})
+
+	var notReady *NotReadyError
+	if !errors.As(err, &notReady) || !errors.Is(err, ErrNotReady) {
+		t.Fatalf("expected a NotReadyError, got %v", err)
+	}
+	if notReady.Attempts < 2 {
+		t.Errorf("expected the check to be retried, got %d attempts", notReady.Attempts)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected to give up after the timeout, waited %s", elapsed)
+	}
+}
+
+func TestWaitCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := Wait(ctx, freePort(t), Options{Host: "127.0.0.1"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the wait to be canceled, got %v", err)
+	}
+}
@@ -9,12 +9,14 @@ import (
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/deta/space/shared"
 )
 
 const (
-	actionEndpoint      = "/__space/actions"
+	// ActionEndpoint is where micros list their actions
+	ActionEndpoint      = "/__space/actions"
 	clientBaseEndpoint  = "/__space/v0/base"
 	clientDriveEndpoint = "/__space/v0/drive"
 )
@@ -55,6 +57,8 @@ type ProxyAction struct {
 }
 
 type ReverseProxy struct {
+	// mu guards the routes and the actions, micros are added while the proxy serves requests
+	mu            sync.RWMutex
 	appID         string
 	appName       string
 	instanceAlias string
@@ -76,18 +80,29 @@ func NewReverseProxy(projectKey string, appID string, appName string, instanceAl
 	}
 }
 
-func (p *ReverseProxy) AddMicro(micro *shared.Micro, port int) (int, error) {
+// AddRoute routes the requests to the path of a micro to its port, without extracting its actions
+func (p *ReverseProxy) AddRoute(micro *shared.Micro, port int) {
 	prefix := extractPrefix(micro.Path)
-	p.prefixToProxy[prefix] = httputil.NewSingleHostReverseProxy(&url.URL{
+	target := httputil.NewSingleHostReverseProxy(&url.URL{
 		Scheme: "http",
 		Host:   fmt.Sprintf("localhost:%d", port),
 	})
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prefixToProxy[prefix] = target
+}
+
+// AddMicro routes the requests to the path of a micro to its port, and extracts its actions if it provides some.
+// The micro has to be ready to answer requests.
+func (p *ReverseProxy) AddMicro(micro *shared.Micro, port int) (int, error) {
+	p.AddRoute(micro, port)
+
 	if !micro.ProvideActions {
 		return 0, nil
 	}
 
-	res, err := http.Get(fmt.Sprintf("http://localhost:%d%s", port, actionEndpoint))
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d%s", port, ActionEndpoint))
 	if err != nil {
 		return 0, err
 	}
@@ -99,6 +114,8 @@ func (p *ReverseProxy) AddMicro(micro *shared.Micro, port int) (int, error) {
 		return 0, err
 	}
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	for _, devAction := range actionMeta.Actions {
 		if devAction.Output == "" {
 			devAction.Output = "@deta/raw"
@@ -177,7 +194,7 @@ func (p *ReverseProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if r.URL.Path == actionEndpoint {
+	if r.URL.Path == ActionEndpoint {
 		switch r.Method {
 		case http.MethodOptions:
 			w.Header().Set("Access-Control-Allow-Origin", "https://deta.space")
@@ -185,10 +202,12 @@ func (p *ReverseProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 			return
 		case http.MethodGet:
+			p.mu.RLock()
 			var actions = make([]ProxyAction, 0, len(p.actionMap))
 			for _, action := range p.actionMap {
 				actions = append(actions, action)
 			}
+			p.mu.RUnlock()
 
 			w.Header().Set("Content-Type", "application/json")
 			w.Header().Set("Access-Control-Allow-Origin", "https://deta.space")
@@ -204,9 +223,9 @@ func (p *ReverseProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if strings.HasPrefix(r.URL.Path, actionEndpoint) {
-		actionName := strings.TrimPrefix(r.URL.Path, actionEndpoint+"/")
-		action, ok := p.actionMap[actionName]
+	if strings.HasPrefix(r.URL.Path, ActionEndpoint) {
+		actionName := strings.TrimPrefix(r.URL.Path, ActionEndpoint+"/")
+		action, ok := p.action(actionName)
 		if !ok {
 			http.NotFound(w, r)
 			return
@@ -218,12 +237,6 @@ func (p *ReverseProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 			return
 		case http.MethodGet:
-			action, ok := p.actionMap[actionName]
-			if !ok {
-				http.NotFound(w, r)
-				return
-			}
-
 			w.Header().Set("Content-Type", "application/json")
 			w.Header().Set("Access-Control-Allow-Origin", "https://deta.space")
 			w.Header().Set("Access-Control-Allow-Headers", "*")
@@ -276,7 +289,7 @@ func (p *ReverseProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	prefix := extractPrefix(r.URL.Path)
-	if proxy, ok := p.prefixToProxy[prefix]; ok {
+	if proxy, ok := p.route(prefix); ok {
 		if prefix != "/" {
 			r.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
 		}
@@ -284,9 +297,23 @@ func (p *ReverseProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fallback, ok := p.prefixToProxy["/"]
+	fallback, ok := p.route("/")
 	if ok {
 		fallback.ServeHTTP(w, r)
 		return
 	}
 }
+
+func (p *ReverseProxy) route(prefix string) (*httputil.ReverseProxy, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	proxy, ok := p.prefixToProxy[prefix]
+	return proxy, ok
+}
+
+func (p *ReverseProxy) action(name string) (ProxyAction, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	action, ok := p.actionMap[name]
+	return action, ok
+}
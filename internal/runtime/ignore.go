@@ -0,0 +1,175 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	ignore "github.com/sabhiram/go-gitignore"
+)
+
+var gitignoreFile = ".gitignore"
+
+// ignorePattern is a single line of an ignore file, compiled without its negation
+type ignorePattern struct {
+	pattern *ignore.GitIgnore
+	negate  bool
+	rule    IgnoreRule
+}
+
+// ignoreLayer holds the patterns of an ignore file, which apply to paths under its directory
+type ignoreLayer struct {
+	// dir is relative to the project with forward slashes, empty for the root
+	dir      string
+	patterns []ignorePattern
+}
+
+// ignoreMatcher matches paths with gitignore semantics against the default list, the .gitignore files of
+// the project if respected, and its .spaceignore files, in increasing order of precedence.
+// Within each kind, files in deeper directories take precedence, and the last matching pattern wins.
+type ignoreMatcher struct {
+	respectGitignore bool
+	defaults         ignoreLayer
+	gitignores       []ignoreLayer
+	spaceignores     []ignoreLayer
+}
+
+func newIgnoreMatcher(respectGitignore bool) *ignoreMatcher {
+	return &ignoreMatcher{
+		respectGitignore: respectGitignore,
+		defaults:         compileIgnoreLayer("", IgnoreSourceDefault, defaultSpaceignore),
+	}
+}
+
+// load reads the ignore files of a directory, it has to be called before matching paths under the directory
+func (m *ignoreMatcher) load(absDir string, relDir string) error {
+	if m.respectGitignore {
+		layer, err := readIgnoreLayer(absDir, relDir, gitignoreFile)
+		if err != nil {
+			return err
+		}
+		if layer != nil {
+			m.gitignores = append(m.gitignores, *layer)
+		}
+	}
+
+	layer, err := readIgnoreLayer(absDir, relDir, spaceignoreFile)
+	if err != nil {
+		return err
+	}
+	if layer != nil {
+		m.spaceignores = append(m.spaceignores, *layer)
+	}
+	return nil
+}
+
+// match returns the rule ignoring a path, or nil if the path is not ignored
+func (m *ignoreMatcher) match(relPath string) *IgnoreRule {
+	var matched *IgnoreRule
+	ignored := false
+
+	layers := append([]ignoreLayer{m.defaults}, m.gitignores...)
+	layers = append(layers, m.spaceignores...)
+	for _, layer := range layers {
+		subPath := relPath
+		if layer.dir != "" {
+			if !strings.HasPrefix(relPath, layer.dir+"/") {
+				continue
+			}
+			subPath = strings.TrimPrefix(relPath, layer.dir+"/")
+		}
+
+		for i := range layer.patterns {
+			p := &layer.patterns[i]
+			if p.pattern.MatchesPath(subPath) {
+				ignored = !p.negate
+				matched = &p.rule
+			}
+		}
+	}
+
+	if !ignored {
+		return nil
+	}
+	return matched
+}
+
+func readIgnoreLayer(absDir string, relDir string, name string) (*ignoreLayer, error) {
+	content, err := os.ReadFile(filepath.Join(absDir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path.Join(relDir, name), err)
+	}
+
+	layer := compileIgnoreLayer(relDir, path.Join(relDir, name), string(content))
+	return &layer, nil
+}
+
+func compileIgnoreLayer(dir string, source string, content string) ignoreLayer {
+	layer := ignoreLayer{dir: dir}
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.Trim(strings.TrimRight(line, "\r"), " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		// negation is handled across files, so patterns are compiled without it
+		negate := strings.HasPrefix(trimmed, "!")
+		if negate {
+			trimmed = trimmed[1:]
+		}
+
+		layer.patterns = append(layer.patterns, ignorePattern{
+			pattern: ignore.CompileIgnoreLines(trimmed),
+			negate:  negate,
+			rule:    IgnoreRule{Pattern: strings.TrimRight(line, "\r"), Source: source, Line: i + 1},
+		})
+	}
+	return layer
+}
+
+// Ignorer matches paths of a project against its ignore files, like they are matched when the project is pushed.
+// The ignore files of a directory are read the first time a path under it is matched.
+type Ignorer struct {
+	sourceDir string
+	opts      ScanOptions
+	matcher   *ignoreMatcher
+	loaded    map[string]bool
+}
+
+// NewIgnorer creates an Ignorer for the project in sourceDir
+func NewIgnorer(sourceDir string, opts ScanOptions) *Ignorer {
+	i := &Ignorer{sourceDir: sourceDir, opts: opts}
+	i.Reset()
+	return i
+}
+
+// Reset forgets the ignore files read so far, so that changes to them are picked up
+func (i *Ignorer) Reset() {
+	i.matcher = newIgnoreMatcher(i.opts.RespectGitignore)
+	i.loaded = map[string]bool{}
+}
+
+// Match returns the rule ignoring a path relative to the project, with forward slashes, or nil if the path is not ignored.
+// A path is ignored if any of its parent directories is.
+func (i *Ignorer) Match(relPath string) (*IgnoreRule, error) {
+	relDir := ""
+	for _, name := range strings.Split(relPath, "/") {
+		if !i.loaded[relDir] {
+			if err := i.matcher.load(filepath.Join(i.sourceDir, filepath.FromSlash(relDir)), relDir); err != nil {
+				return nil, err
+			}
+			i.loaded[relDir] = true
+		}
+
+		relDir = path.Join(relDir, name)
+		if rule := i.matcher.match(relDir); rule != nil {
+			return rule, nil
+		}
+	}
+	return nil, nil
+}
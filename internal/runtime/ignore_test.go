@@ -16,6 +16,7 @@ var files = map[string]bool{
 	"venv/main.py":          true,
 	"node_modules/index.js": true,
 	"folder/.env":           true,
+	"Spacefile.local":       true,
 	"main.py":               false,
 }
 
@@ -51,3 +52,46 @@ func TestOverrideExistingPattern(t *testing.T) {
 		t.Fatalf("expected venv/main.py to not be ignored")
 	}
 }
+
+func TestNestedSpaceignore(t *testing.T) {
+	dir := writeProject(t, map[string][]byte{
+		".spaceignore":          []byte("*.log\n"),
+		"main.py":               []byte("print('hello')"),
+		"debug.log":             []byte("log"),
+		"api/.spaceignore":      []byte("*.pyc\n/cache\n!keep.log\n"),
+		"api/main.pyc":          []byte("pyc"),
+		"api/keep.log":          []byte("log"),
+		"api/cache/data":        []byte("data"),
+		"api/lib/cache/data":    []byte("data"),
+		"web/main.pyc":          []byte("pyc"),
+		"web/.gitignore":        []byte("out\n*.tmp\n!important.log\n"),
+		"web/cache.tmp":         []byte("tmp"),
+		"web/out/index.html":    []byte("<html></html>"),
+		"web/important.log":     []byte("log"),
+		"web/.spaceignore":      []byte("!out\n"),
+		"web/src/important.txt": []byte("txt"),
+	})
+
+	list := func(opts ScanOptions) []string {
+		files, err := ListFiles(dir, opts)
+		if err != nil {
+			t.Fatalf("failed to list files: %v", err)
+		}
+		var paths []string
+		for _, f := range files {
+			paths = append(paths, f.Path)
+		}
+		return paths
+	}
+
+	expected := []string{"api/keep.log", "api/lib/cache/data", "main.py", "web/cache.tmp", "web/main.pyc", "web/out/index.html", "web/src/important.txt"}
+	if actual := list(ScanOptions{}); strings.Join(actual, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected files %v, got %v", expected, actual)
+	}
+
+	// .gitignore files are layered under .spaceignore files, which take precedence in both directions
+	expected = []string{"api/keep.log", "api/lib/cache/data", "main.py", "web/main.pyc", "web/out/index.html", "web/src/important.txt"}
+	if actual := list(ScanOptions{RespectGitignore: true}); strings.Join(actual, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected files %v, got %v", expected, actual)
+	}
+}
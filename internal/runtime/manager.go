@@ -22,15 +22,13 @@ var (
 
 	spaceDir        = ".space"
 	projectMetaFile = "meta"
+	pushMetaFile    = "push"
 )
 
 // StoreProjectMeta stores project meta to disk
 func StoreProjectMeta(projectDir string, p *ProjectMeta) error {
-	if _, err := os.Stat(filepath.Join(projectDir, spaceDir)); os.IsNotExist(err) {
-		err = os.Mkdir(filepath.Join(projectDir, spaceDir), dirPermMode)
-		if err != nil {
-			return err
-		}
+	if err := ensureSpaceDir(projectDir); err != nil {
+		return err
 	}
 	marshalled, err := json.Marshal(p)
 	if err != nil {
@@ -46,6 +44,40 @@ func StoreProjectMeta(projectDir string, p *ProjectMeta) error {
 	return ioutil.WriteFile(filepath.Join(projectDir, spaceDir, projectMetaFile), marshalled, filePermMode)
 }
 
+func ensureSpaceDir(projectDir string) error {
+	if _, err := os.Stat(filepath.Join(projectDir, spaceDir)); os.IsNotExist(err) {
+		return os.Mkdir(filepath.Join(projectDir, spaceDir), dirPermMode)
+	}
+	return nil
+}
+
+// StorePushMeta stores the last push of the project to disk
+func StorePushMeta(projectDir string, p *PushMeta) error {
+	if err := ensureSpaceDir(projectDir); err != nil {
+		return err
+	}
+	marshalled, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(projectDir, spaceDir, pushMetaFile), marshalled, filePermMode)
+}
+
+// GetPushMeta gets the last push of the project, it returns os.ErrNotExist if the project was never pushed
+func GetPushMeta(projectDir string) (*PushMeta, error) {
+	contents, err := os.ReadFile(filepath.Join(projectDir, spaceDir, pushMetaFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var p PushMeta
+	if err := json.Unmarshal(contents, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
 func GetProjectID(projectDir string) (string, error) {
 	projectMeta, err := GetProjectMeta(projectDir)
 	if err != nil {
@@ -1,7 +1,10 @@
 package runtime
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"time"
@@ -28,6 +31,45 @@ func projectMetaFromBytes(data []byte) (*ProjectMeta, error) {
 	return &p, nil
 }
 
+// PushMeta records the last successful push of a project
+type PushMeta struct {
+	ProjectID string `json:"project_id"`
+	// Digest is the digest of the contents and options of the push, see PushDigest
+	Digest string `json:"digest"`
+	// Revision is the id of the revision created by the push
+	Revision string    `json:"revision"`
+	Tag      string    `json:"tag,omitempty"`
+	PushedAt time.Time `json:"pushed_at"`
+}
+
+// PushContents are the contents and the options of a push, a push can be skipped if none of them changed
+type PushContents struct {
+	// ArchiveDigest is the digest of the archived project
+	ArchiveDigest string
+	// Spacefile is the pushed Spacefile, which can differ from the archived one
+	Spacefile []byte
+	// Icon is the pushed icon, nil if there is none
+	Icon []byte
+
+	GenerateIcon bool
+	Interpolate  bool
+	Symlinks     SymlinkPolicy
+	MaxSize      int64
+}
+
+// PushDigest is the hex encoded SHA-256 of the contents and the options of a push
+func PushDigest(c *PushContents) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "archive %s\n", c.ArchiveDigest)
+	// contents are prefixed with their length, so that the boundaries between them are part of the digest
+	fmt.Fprintf(h, "spacefile %d\n", len(c.Spacefile))
+	h.Write(c.Spacefile)
+	fmt.Fprintf(h, "icon %d\n", len(c.Icon))
+	h.Write(c.Icon)
+	fmt.Fprintf(h, "generate-icon %t\ninterpolate %t\nsymlinks %s\nmax-size %d\n", c.GenerateIcon, c.Interpolate, c.Symlinks, c.MaxSize)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 type Version struct {
 	Version   string `json:"version"`
 	UpdatedAt int64  `json:"updatedAt"`
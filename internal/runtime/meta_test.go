@@ -0,0 +1,42 @@
+package runtime
+
+import "testing"
+
+func TestPushDigest(t *testing.T) {
+	base := PushContents{
+		ArchiveDigest: "abc",
+		Spacefile:     []byte("v: 0\n"),
+		Icon:          []byte("png"),
+		GenerateIcon:  true,
+		Symlinks:      SymlinkFollow,
+		MaxSize:       DefaultMaxArchiveSize,
+	}
+
+	same := base
+	if PushDigest(&base) != PushDigest(&same) {
+		t.Fatalf("expected the same contents to have the same digest")
+	}
+
+	cases := map[string]func(c *PushContents){
+		"archive":       func(c *PushContents) { c.ArchiveDigest = "abd" },
+		"spacefile":     func(c *PushContents) { c.Spacefile = []byte("v: 0\n# changed\n") },
+		"icon":          func(c *PushContents) { c.Icon = []byte("other png") },
+		"no icon":       func(c *PushContents) { c.Icon = nil },
+		"generate-icon": func(c *PushContents) { c.GenerateIcon = false },
+		"interpolate":   func(c *PushContents) { c.Interpolate = true },
+		"symlinks":      func(c *PushContents) { c.Symlinks = SymlinkPreserve },
+		"max-size":      func(c *PushContents) { c.MaxSize = 0 },
+		// moving bytes from the Spacefile to the icon changes the digest
+		"boundary": func(c *PushContents) { c.Spacefile, c.Icon = []byte("v: 0"), []byte("\npng") },
+	}
+
+	for name, change := range cases {
+		t.Run(name, func(t *testing.T) {
+			changed := base
+			change(&changed)
+			if PushDigest(&base) == PushDigest(&changed) {
+				t.Fatalf("expected a change of %s to change the digest", name)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package runtime
+
+import "io"
+
+// IgnoreSourceDefault is the source of the rules of the default ignore list, embedded in the cli
+const IgnoreSourceDefault = "default"
+
+// IgnoreRule is a line of an ignore list
+type IgnoreRule struct {
+	Pattern string `json:"pattern"`
+	// Source is IgnoreSourceDefault or the path of the ignore file in the project
+	Source string `json:"source"`
+	Line   int    `json:"line"`
+}
+
+// IgnoredFile is a file, or a whole directory, excluded from an archive
+type IgnoredFile struct {
+	Path string     `json:"path"`
+	Size int64      `json:"size,omitempty"`
+	Dir  bool       `json:"dir,omitempty"`
+	Rule IgnoreRule `json:"rule"`
+}
+
+// UploadReport describes what would be uploaded by a push of a project
+type UploadReport struct {
+	Files   []ArchiveFile `json:"files"`
+	Ignored []IgnoredFile `json:"ignored"`
+	Skipped []SkippedFile `json:"skipped"`
+	// Size is the total size of the files, CompressedSize the size of their archive
+	Size           int64  `json:"size"`
+	CompressedSize int64  `json:"compressed_size"`
+	Digest         string `json:"digest"`
+}
+
+// Report walks sourceDir the same way Archive does and compresses it without keeping the archive
+func Report(sourceDir string, opts ScanOptions) (*UploadReport, error) {
+	scan, err := scanFiles(sourceDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := archiveScan(io.Discard, sourceDir, scan, ArchiveOptions{ScanOptions: opts})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &UploadReport{
+		Files:          scan.files,
+		Ignored:        scan.ignored,
+		Skipped:        scan.skipped,
+		CompressedSize: stats.Size,
+		Digest:         stats.Digest,
+	}
+	for _, file := range scan.files {
+		report.Size += file.Size
+	}
+	// empty lists are encoded as [] rather than null
+	if report.Files == nil {
+		report.Files = []ArchiveFile{}
+	}
+	if report.Ignored == nil {
+		report.Ignored = []IgnoredFile{}
+	}
+	if report.Skipped == nil {
+		report.Skipped = []SkippedFile{}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,237 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SymlinkPolicy is how symlinks of a project are archived
+type SymlinkPolicy string
+
+const (
+	// SymlinkFollow archives the files and directories symlinks point to as copies,
+	// directories outside of the project are skipped so that a link to a home or root directory is not archived
+	SymlinkFollow SymlinkPolicy = "follow"
+	// SymlinkPreserve archives symlinks as symlink entries
+	SymlinkPreserve SymlinkPolicy = "preserve"
+	// SymlinkError fails on any symlink
+	SymlinkError SymlinkPolicy = "error"
+)
+
+// SymlinkPolicies lists the valid symlink policies
+var SymlinkPolicies = []SymlinkPolicy{SymlinkFollow, SymlinkPreserve, SymlinkError}
+
+var ErrSymlinkNotAllowed = errors.New("symlinks are not allowed")
+
+// ScanOptions configures which files of a project are archived and how
+type ScanOptions struct {
+	// RespectGitignore layers the .gitignore files of the project under its .spaceignore files
+	RespectGitignore bool
+	// Symlinks is the symlink policy, symlinks are followed if it is empty
+	Symlinks SymlinkPolicy
+}
+
+// SkippedFile is a file that cannot be archived, like a socket or a broken symlink
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// scanResult splits the files of a project, every list is sorted by path
+type scanResult struct {
+	files   []ArchiveFile
+	ignored []IgnoredFile
+	skipped []SkippedFile
+}
+
+type scanner struct {
+	// realDir is the project directory with symlinks resolved
+	realDir string
+	opts    ScanOptions
+	matcher *ignoreMatcher
+	result  scanResult
+}
+
+// ListFiles lists the files of sourceDir that are not ignored by .spaceignore files, sorted by path
+func ListFiles(sourceDir string, opts ScanOptions) ([]ArchiveFile, error) {
+	result, err := scanFiles(sourceDir, opts)
+	if err != nil {
+		return nil, err
+	}
+	return result.files, nil
+}
+
+// scanFiles walks sourceDir and splits its files between the ones to archive, the ones ignored by .spaceignore files
+// and the ones that cannot be archived. Ignored directories are not walked, they are reported once.
+func scanFiles(sourceDir string, opts ScanOptions) (*scanResult, error) {
+	absDir, err := filepath.Abs(sourceDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve absolute path for dir %s to zip, %w", sourceDir, err)
+	}
+
+	if _, err := os.Stat(absDir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("source dir %s not found, %w", absDir, err)
+	}
+
+	realDir, err := filepath.EvalSymlinks(absDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve dir %s to zip, %w", sourceDir, err)
+	}
+
+	if opts.Symlinks == "" {
+		opts.Symlinks = SymlinkFollow
+	}
+
+	s := &scanner{realDir: realDir, opts: opts, matcher: newIgnoreMatcher(opts.RespectGitignore)}
+	if err := s.walkDir(absDir, "", []string{realDir}); err != nil {
+		return nil, fmt.Errorf("cannot scan contents of dir %s to zip, %w", sourceDir, err)
+	}
+
+	sort.Slice(s.result.files, func(i, j int) bool {
+		return s.result.files[i].Path < s.result.files[j].Path
+	})
+	sort.Slice(s.result.ignored, func(i, j int) bool {
+		return s.result.ignored[i].Path < s.result.ignored[j].Path
+	})
+	sort.Slice(s.result.skipped, func(i, j int) bool {
+		return s.result.skipped[i].Path < s.result.skipped[j].Path
+	})
+
+	return &s.result, nil
+}
+
+// walkDir scans a directory, ancestors holds the real paths of the directories being walked to detect cycles
+func (s *scanner) walkDir(absDir string, relDir string, ancestors []string) error {
+	// ignore files of a directory apply to everything under it
+	if err := s.matcher.load(absDir, relDir); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(absDir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		absPath := filepath.Join(absDir, entry.Name())
+		relPath := path.Join(relDir, entry.Name())
+
+		info, err := os.Lstat(absPath)
+		if err != nil {
+			return err
+		}
+
+		// matching against the relative path so that you can have your
+		// project in a folder called `dist`, for example.
+		if rule := s.matcher.match(relPath); rule != nil {
+			file := IgnoredFile{Path: relPath, Dir: info.IsDir(), Rule: *rule}
+			if info.Mode().IsRegular() {
+				file.Size = info.Size()
+			}
+			s.result.ignored = append(s.result.ignored, file)
+			continue
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			if err := s.addSymlink(absPath, relPath, ancestors); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := s.addEntry(absPath, relPath, info, ancestors, filepath.Join(ancestors[len(ancestors)-1], entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addEntry adds a directory, a regular file or a skipped special file, realPath is the path with symlinks resolved
+func (s *scanner) addEntry(absPath string, relPath string, info os.FileInfo, ancestors []string, realPath string) error {
+	switch {
+	case info.IsDir():
+		for _, ancestor := range ancestors {
+			if ancestor == realPath {
+				s.skip(relPath, "symlink cycle")
+				return nil
+			}
+		}
+		return s.walkDir(absPath, relPath, append(ancestors, realPath))
+	case info.Mode().IsRegular():
+		mode := archiveFileMode
+		if info.Mode()&0111 != 0 {
+			mode = archiveExecMode
+		}
+		s.result.files = append(s.result.files, ArchiveFile{Path: relPath, Size: info.Size(), absPath: absPath, mode: mode})
+	default:
+		s.skip(relPath, fmt.Sprintf("special file (%s)", fileType(info.Mode())))
+	}
+	return nil
+}
+
+func (s *scanner) addSymlink(absPath string, relPath string, ancestors []string) error {
+	switch s.opts.Symlinks {
+	case SymlinkError:
+		return fmt.Errorf("%w, found %s", ErrSymlinkNotAllowed, relPath)
+	case SymlinkPreserve:
+		target, err := os.Readlink(absPath)
+		if err != nil {
+			return err
+		}
+		s.result.files = append(s.result.files, ArchiveFile{
+			Path:       relPath,
+			Size:       int64(len(target)),
+			absPath:    absPath,
+			mode:       archiveSymlinkMode,
+			linkTarget: filepath.ToSlash(target),
+		})
+		return nil
+	}
+
+	realPath, err := filepath.EvalSymlinks(absPath)
+	if err != nil {
+		s.skip(relPath, "broken symlink")
+		return nil
+	}
+	info, err := os.Stat(realPath)
+	if err != nil {
+		s.skip(relPath, "broken symlink")
+		return nil
+	}
+
+	if info.IsDir() && !s.inProject(realPath) {
+		s.skip(relPath, "symlink to a directory outside of the project")
+		return nil
+	}
+
+	return s.addEntry(absPath, relPath, info, ancestors, realPath)
+}
+
+// inProject reports whether a path with symlinks resolved is in the project directory
+func (s *scanner) inProject(realPath string) bool {
+	rel, err := filepath.Rel(s.realDir, realPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (s *scanner) skip(relPath string, reason string) {
+	s.result.skipped = append(s.result.skipped, SkippedFile{Path: relPath, Reason: reason})
+}
+
+func fileType(mode os.FileMode) string {
+	switch {
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&os.ModeDevice != 0:
+		return "device"
+	default:
+		return "irregular"
+	}
+}
@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func symlink(t *testing.T, target string, link string) {
+	t.Helper()
+
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+}
+
+func TestScanSymlinks(t *testing.T) {
+	dir := writeProject(t, map[string][]byte{
+		"main.py":       []byte("print('hello')"),
+		"shared/lib.py": []byte("x = 1"),
+	})
+	symlink(t, "main.py", filepath.Join(dir, "app.py"))
+	symlink(t, "../shared", filepath.Join(dir, "shared", "loop"))
+	symlink(t, "missing.py", filepath.Join(dir, "broken.py"))
+	symlink(t, "shared", filepath.Join(dir, "lib"))
+
+	outside := writeProject(t, map[string][]byte{"secret.txt": []byte("secret")})
+	symlink(t, outside, filepath.Join(dir, "home"))
+	symlink(t, filepath.Join(outside, "secret.txt"), filepath.Join(dir, "config.txt"))
+
+	result, err := scanFiles(dir, ScanOptions{})
+	if err != nil {
+		t.Fatalf("failed to scan project: %v", err)
+	}
+
+	var paths []string
+	for _, f := range result.files {
+		paths = append(paths, f.Path)
+	}
+	// symlinked files are copied wherever they are, directories only if they are in the project
+	expected := "app.py,config.txt,lib/lib.py,main.py,shared/lib.py"
+	if strings.Join(paths, ",") != expected {
+		t.Errorf("expected followed files %s, got %v", expected, paths)
+	}
+
+	skipped := map[string]string{}
+	for _, f := range result.skipped {
+		skipped[f.Path] = f.Reason
+	}
+	if skipped["broken.py"] != "broken symlink" || skipped["shared/loop"] != "symlink cycle" || skipped["lib/loop"] != "symlink cycle" ||
+		skipped["home"] != "symlink to a directory outside of the project" {
+		t.Errorf("expected the broken symlink, the cycles and the outside directory to be skipped, got %v", result.skipped)
+	}
+
+	_, err = scanFiles(dir, ScanOptions{Symlinks: SymlinkError})
+	if !errors.Is(err, ErrSymlinkNotAllowed) {
+		t.Errorf("expected error %v, got %v", ErrSymlinkNotAllowed, err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := Archive(&buf, dir, ArchiveOptions{ScanOptions: ScanOptions{Symlinks: SymlinkPreserve}}); err != nil {
+		t.Fatalf("failed to archive project: %v", err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	for _, f := range r.File {
+		if f.Name != "app.py" {
+			continue
+		}
+		if f.Mode()&os.ModeSymlink == 0 {
+			t.Fatalf("expected app.py to be a symlink, got mode %s", f.Mode())
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open symlink entry: %v", err)
+		}
+		target, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(target) != "main.py" {
+			t.Errorf("expected symlink to main.py, got %s", target)
+		}
+	}
+}
+
+func TestScanSpecialFiles(t *testing.T) {
+	dir := writeProject(t, map[string][]byte{
+		"server": []byte("#!/bin/sh"),
+	})
+	if err := os.Chmod(filepath.Join(dir, "server"), 0750); err != nil {
+		t.Fatalf("failed to chmod file: %v", err)
+	}
+
+	listener, err := net.Listen("unix", filepath.Join(dir, "dev.sock"))
+	if err != nil {
+		t.Skipf("unix sockets are not supported: %v", err)
+	}
+	defer listener.Close()
+
+	result, err := scanFiles(dir, ScanOptions{})
+	if err != nil {
+		t.Fatalf("failed to scan project: %v", err)
+	}
+
+	if len(result.files) != 1 || result.files[0].mode != archiveExecMode {
+		t.Errorf("expected an executable server, got %+v", result.files)
+	}
+	if len(result.skipped) != 1 || result.skipped[0].Path != "dev.sock" || result.skipped[0].Reason != "special file (socket)" {
+		t.Errorf("expected the socket to be skipped, got %+v", result.skipped)
+	}
+}
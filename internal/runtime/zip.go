@@ -2,15 +2,18 @@ package runtime
 
 import (
 	"archive/zip"
-	"bytes"
+	"compress/flate"
+	"crypto/sha256"
 	_ "embed"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"os"
-	"path/filepath"
+	"sort"
 	"strings"
-
-	ignore "github.com/sabhiram/go-gitignore"
+	"time"
 )
 
 var (
@@ -20,108 +23,250 @@ var (
 //go:embed .spaceignore
 var defaultSpaceignore string
 
-func ZipDir(sourceDir string) ([]byte, int, error) {
-	absDir, err := filepath.Abs(sourceDir)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to resolve absolute path for dir %s to zip, %w", sourceDir, err)
-	}
+// DefaultMaxArchiveSize is the default maximum size of a project archive
+const DefaultMaxArchiveSize int64 = 250 << 20
 
-	// check if dir exists
-	if stat, err := os.Stat(absDir); err != nil && stat.IsDir() {
-		if os.IsNotExist(err) {
-			return nil, 0, fmt.Errorf("source dir %s not found, %w", absDir, err)
-		}
+// largestFilesShown is the number of files listed when an archive is too large
+const largestFilesShown = 5
+
+const (
+	// archiveCompressionLevel is fixed so that the same files always compress to the same bytes
+	archiveCompressionLevel = flate.DefaultCompression
+	// archiveFileMode, archiveExecMode and archiveSymlinkMode are the only modes of files in an archive
+	archiveFileMode    os.FileMode = 0644
+	archiveExecMode    os.FileMode = 0755
+	archiveSymlinkMode             = os.ModeSymlink | 0777
+)
+
+// archiveModTime is the modification time of every file in an archive, the earliest time zip files support
+var archiveModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var ErrArchiveTooLarge = errors.New("archive too large")
+
+// ArchiveFile is a file of the project added to an archive
+type ArchiveFile struct {
+	// Path is relative to the project, with forward slashes
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+
+	absPath string
+	mode    os.FileMode
+	// linkTarget is the target of a preserved symlink
+	linkTarget string
+}
+
+// ManifestEntry is the digest of a file in an archive
+type ManifestEntry struct {
+	Path string
+	// SHA256 is the hex encoded digest of the content of the file, or of the target of a symlink
+	SHA256 string
+	Mode   os.FileMode
+}
+
+// ArchiveProgress is reported after every file added to an archive
+type ArchiveProgress struct {
+	File ArchiveFile
+	// Files is the number of files added so far, out of Total
+	Files int
+	Total int
+	// Written is the size of the archive so far
+	Written int64
+}
+
+// ArchiveOptions configures Archive
+type ArchiveOptions struct {
+	ScanOptions
+	// MaxSize is the maximum size of the archive in bytes, there is no limit if it is zero
+	MaxSize int64
+	// Progress, if set, is called after every file added to the archive
+	Progress func(ArchiveProgress)
+}
+
+// ArchiveStats describes a written archive
+type ArchiveStats struct {
+	Files int
+	Size  int64
+	// Manifest lists the digest of every file in the archive, sorted by path
+	Manifest []ManifestEntry
+	// Digest is the hex encoded SHA-256 of the manifest, two archives with the same digest have the same content
+	Digest string
+	// Skipped lists the files that could not be archived
+	Skipped []SkippedFile
+}
+
+// ArchiveSizeError is returned when an archive grows over its maximum size
+type ArchiveSizeError struct {
+	MaxSize int64
+	// Largest are the largest files of the project, largest first
+	Largest []ArchiveFile
+}
+
+func (e *ArchiveSizeError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "project archive is larger than the maximum size of %s, the largest files are:", FormatSize(e.MaxSize))
+	for _, f := range e.Largest {
+		fmt.Fprintf(&b, "\n  %s (%s)", f.Path, FormatSize(f.Size))
 	}
+	fmt.Fprintf(&b, "\nexclude the files that are not needed with %s", spaceignoreFile)
+	return b.String()
+}
 
-	lines := strings.Split(string(defaultSpaceignore), "\n")
-	spaceIgnorePath := filepath.Join(sourceDir, spaceignoreFile)
-	if _, err := os.Stat(spaceIgnorePath); err == nil {
-		bytes, err := os.ReadFile(spaceIgnorePath)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to read .spaceignore: %w", err)
-		}
-		lines = append(lines, strings.Split(string(bytes), "\n")...)
+func (e *ArchiveSizeError) Unwrap() error {
+	return ErrArchiveTooLarge
+}
+
+// Archive streams a zip of the files of sourceDir, not ignored by .spaceignore files, to w.
+// Files are added in sorted order and read one at a time, so that memory usage does not grow with the project.
+// The archive is reproducible: timestamps and permissions are normalised, so the same files always give the same bytes.
+func Archive(w io.Writer, sourceDir string, opts ArchiveOptions) (*ArchiveStats, error) {
+	scan, err := scanFiles(sourceDir, opts.ScanOptions)
+	if err != nil {
+		return nil, err
 	}
 
-	spaceignore := ignore.CompileIgnoreLines(lines...)
+	return archiveScan(w, sourceDir, scan, opts)
+}
 
-	files := make(map[string][]byte)
-	// go through the dir and read all the files
-	err = filepath.Walk(absDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+// archiveScan writes the files found by a scan of sourceDir to w
+func archiveScan(w io.Writer, sourceDir string, scan *scanResult, opts ArchiveOptions) (*ArchiveStats, error) {
+	files := scan.files
+
+	counter := &limitedWriter{w: w, limit: opts.MaxSize}
+	zw := zip.NewWriter(counter)
+	zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, archiveCompressionLevel)
+	})
+
+	tooLarge := func() error {
+		return &ArchiveSizeError{MaxSize: opts.MaxSize, Largest: largestFiles(files, largestFilesShown)}
+	}
+
+	manifest := make([]ManifestEntry, 0, len(files))
+	digest := sha256.New()
+	for i, file := range files {
+		// flushing after every file keeps the written size accurate for the progress and the limit
+		entry, err := addFile(zw, file)
+		if err == nil {
+			manifest = append(manifest, entry)
+			writeManifestEntry(digest, entry)
+			err = zw.Flush()
 		}
-		if path == absDir {
-			return nil
+		if err != nil {
+			if errors.Is(err, errLimitExceeded) {
+				return nil, tooLarge()
+			}
+			return nil, fmt.Errorf("cannot compress file %s of dir %s, %w", file.Path, sourceDir, err)
 		}
 
-		// matching against the the `TrimPrefix`ed transformation so that you can have your
-		// project in a folder called `dist`, for example.
-		// skip if shouldSkip according to skipPaths which are derived from .spaceignore
-		shouldSkip := spaceignore.MatchesPath(strings.TrimPrefix(path, absDir+"/"))
-		if shouldSkip && info.IsDir() {
-			return filepath.SkipDir
+		if opts.Progress != nil {
+			opts.Progress(ArchiveProgress{File: file, Files: i + 1, Total: len(files), Written: counter.written})
 		}
+	}
 
-		if shouldSkip {
-			return nil
+	if err := zw.Close(); err != nil {
+		if errors.Is(err, errLimitExceeded) {
+			return nil, tooLarge()
 		}
+		return nil, fmt.Errorf("cannot close zip writer for dir %s, %w", sourceDir, err)
+	}
 
-		if info.IsDir() {
-			return nil
-		}
+	return &ArchiveStats{
+		Files:    len(files),
+		Size:     counter.written,
+		Manifest: manifest,
+		Digest:   hex.EncodeToString(digest.Sum(nil)),
+		Skipped:  scan.skipped,
+	}, nil
+}
 
-		absPath, err := filepath.Abs(path)
-		if err != nil {
-			return err
-		}
+// Mode is the normalised mode of the file in an archive
+func (f ArchiveFile) Mode() os.FileMode {
+	return f.mode
+}
 
-		// relative path of file from absolute locations of dir and path
-		relPath, err := filepath.Rel(absDir, absPath)
-		if err != nil {
-			return err
-		}
+// Open opens the content of the file as it is archived, the target of a preserved symlink is its content
+func (f ArchiveFile) Open() (io.ReadCloser, error) {
+	if f.mode == archiveSymlinkMode {
+		return io.NopCloser(strings.NewReader(f.linkTarget)), nil
+	}
+	return os.Open(f.absPath)
+}
 
-		// ensures to use forward slashes
-		relPath = filepath.ToSlash(relPath)
+func addFile(zw *zip.Writer, file ArchiveFile) (ManifestEntry, error) {
+	src, err := file.Open()
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+	defer src.Close()
 
-		f, e := os.Open(path)
-		if e != nil {
-			return e
-		}
-		defer f.Close()
-		contents, e := io.ReadAll(f)
-		if e != nil {
-			return e
-		}
+	header := &zip.FileHeader{
+		Name:     file.Path,
+		Method:   zip.Deflate,
+		Modified: archiveModTime,
+	}
+	header.SetMode(file.mode)
 
-		files[relPath] = contents
-		return nil
-	})
+	dst, err := zw.CreateHeader(header)
 	if err != nil {
-		return nil, 0, fmt.Errorf("cannot scan contents of dir %s to zip, %w", sourceDir, err)
+		return ManifestEntry{}, err
 	}
 
-	buf := new(bytes.Buffer)
-	w := zip.NewWriter(buf)
+	h := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(dst, h), src); err != nil {
+		return ManifestEntry{}, err
+	}
 
-	filenames := make([]string, 0, len(files))
-	for name, content := range files {
-		filenames = append(filenames, name)
-		f, err := w.Create(name)
-		if err != nil {
-			return nil, 0, fmt.Errorf("cannot compress file %s of dir %s, %w", name, sourceDir, err)
-		}
-		_, err = f.Write(content)
-		if err != nil {
-			return nil, 0, fmt.Errorf("cannot compress file %s of dir %s, %w", name, sourceDir, err)
-		}
+	return ManifestEntry{Path: file.Path, SHA256: hex.EncodeToString(h.Sum(nil)), Mode: file.mode}, nil
+}
+
+// writeManifestEntry adds an entry to the digest of a manifest, one line per file
+func writeManifestEntry(digest hash.Hash, entry ManifestEntry) {
+	fmt.Fprintf(digest, "%s %o %s\n", entry.SHA256, entry.Mode, entry.Path)
+}
+
+func largestFiles(files []ArchiveFile, n int) []ArchiveFile {
+	largest := make([]ArchiveFile, len(files))
+	copy(largest, files)
+	sort.SliceStable(largest, func(i, j int) bool {
+		return largest[i].Size > largest[j].Size
+	})
+
+	if len(largest) > n {
+		largest = largest[:n]
+	}
+	return largest
+}
+
+var errLimitExceeded = errors.New("write limit exceeded")
+
+// limitedWriter counts the bytes written and fails once more than limit bytes were written, if limit is set
+type limitedWriter struct {
+	w       io.Writer
+	limit   int64
+	written int64
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.limit > 0 && l.written+int64(len(p)) > l.limit {
+		return 0, errLimitExceeded
 	}
 
-	err = w.Close()
-	if err != nil {
-		return nil, 0, fmt.Errorf("cannot close zip writer for dir %s, %w", sourceDir, err)
+	n, err := l.w.Write(p)
+	l.written += int64(n)
+	return n, err
+}
+
+// FormatSize formats a size in bytes for humans
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
 	}
 
-	return buf.Bytes(), len(filenames), nil
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
 }
@@ -0,0 +1,200 @@
+package runtime
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeProject(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestArchive(t *testing.T) {
+	dir := writeProject(t, map[string][]byte{
+		"main.py":               []byte("print('hello')"),
+		"static/index.html":     []byte("<html></html>"),
+		"b.txt":                 []byte("b"),
+		".env":                  []byte("SECRET=1"),
+		"node_modules/index.js": []byte("module.exports = {}"),
+	})
+
+	var buf bytes.Buffer
+	var progress []string
+	stats, err := Archive(&buf, dir, ArchiveOptions{
+		Progress: func(p ArchiveProgress) {
+			progress = append(progress, p.File.Path)
+			if p.Total != 3 || p.Written <= 0 {
+				t.Errorf("unexpected progress %+v", p)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to archive project: %v", err)
+	}
+
+	if stats.Files != 3 || stats.Size != int64(buf.Len()) {
+		t.Errorf("unexpected stats %+v for an archive of %d bytes", stats, buf.Len())
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+
+	expected := []string{"b.txt", "main.py", "static/index.html"}
+	if len(r.File) != len(expected) {
+		t.Fatalf("expected files %v, got %d files", expected, len(r.File))
+	}
+	for i, f := range r.File {
+		if f.Name != expected[i] || progress[i] != expected[i] {
+			t.Errorf("expected file %d to be %s, got %s", i, expected[i], f.Name)
+		}
+	}
+}
+
+func TestArchiveMaxSize(t *testing.T) {
+	// random content does not compress
+	large := make([]byte, 64<<10)
+	rand.New(rand.NewSource(1)).Read(large)
+
+	dir := writeProject(t, map[string][]byte{
+		"assets/video.mp4": large,
+		"assets/small.png": large[:16<<10],
+		"main.py":          []byte("print('hello')"),
+	})
+
+	var buf bytes.Buffer
+	_, err := Archive(&buf, dir, ArchiveOptions{MaxSize: 32 << 10})
+	if !errors.Is(err, ErrArchiveTooLarge) {
+		t.Fatalf("expected error %v, got %v", ErrArchiveTooLarge, err)
+	}
+
+	var sizeErr *ArchiveSizeError
+	if !errors.As(err, &sizeErr) {
+		t.Fatalf("expected an archive size error, got %T", err)
+	}
+	if len(sizeErr.Largest) != 3 || sizeErr.Largest[0].Path != "assets/video.mp4" || sizeErr.Largest[1].Path != "assets/small.png" {
+		t.Errorf("expected the largest files first, got %+v", sizeErr.Largest)
+	}
+}
+
+func TestArchiveReproducible(t *testing.T) {
+	dir := writeProject(t, map[string][]byte{
+		"main.py":           []byte("print('hello')"),
+		"static/index.html": []byte("<html></html>"),
+	})
+
+	archive := func() ([]byte, *ArchiveStats) {
+		var buf bytes.Buffer
+		stats, err := Archive(&buf, dir, ArchiveOptions{})
+		if err != nil {
+			t.Fatalf("failed to archive project: %v", err)
+		}
+		return buf.Bytes(), stats
+	}
+
+	first, firstStats := archive()
+	if len(firstStats.Manifest) != 2 || firstStats.Manifest[0].Path != "main.py" || firstStats.Manifest[0].SHA256 != "96f43d529af3430cb6b0e2c02f6b38ef1a121e8a31d2d09a3ebb716f2f35c9de" {
+		t.Fatalf("unexpected manifest %+v", firstStats.Manifest)
+	}
+
+	// modification times and group permissions do not change the archive
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "main.py"), later, later); err != nil {
+		t.Fatalf("failed to touch file: %v", err)
+	}
+	if err := os.Chmod(filepath.Join(dir, "main.py"), 0600); err != nil {
+		t.Fatalf("failed to chmod file: %v", err)
+	}
+
+	second, secondStats := archive()
+	if !bytes.Equal(first, second) || firstStats.Digest != secondStats.Digest {
+		t.Errorf("expected the same archive for the same files")
+	}
+
+	// content and the executable bit do
+	if err := os.Chmod(filepath.Join(dir, "main.py"), 0755); err != nil {
+		t.Fatalf("failed to chmod file: %v", err)
+	}
+	_, execStats := archive()
+	if execStats.Digest == firstStats.Digest {
+		t.Errorf("expected the digest to change with the executable bit")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "static/index.html"), []byte("<html>hi</html>"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	_, changedStats := archive()
+	if changedStats.Digest == execStats.Digest {
+		t.Errorf("expected the digest to change with the content")
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	cases := map[int64]string{
+		512:       "512 B",
+		2048:      "2.0 KB",
+		250 << 20: "250.0 MB",
+	}
+	for size, expected := range cases {
+		if actual := FormatSize(size); actual != expected {
+			t.Errorf("expected %d to be formatted as %s, got %s", size, expected, actual)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	dir := writeProject(t, map[string][]byte{
+		"main.py":               []byte("print('hello')"),
+		"debug.log":             []byte("log"),
+		".env":                  []byte("SECRET=1"),
+		".spaceignore":          []byte("# logs\n*.log\n"),
+		"node_modules/index.js": []byte("module.exports = {}"),
+	})
+
+	report, err := Report(dir, ScanOptions{})
+	if err != nil {
+		t.Fatalf("failed to report project: %v", err)
+	}
+
+	if len(report.Files) != 1 || report.Files[0].Path != "main.py" || report.Size != 14 || report.CompressedSize == 0 {
+		t.Errorf("unexpected files %+v", report)
+	}
+
+	expected := map[string]IgnoreRule{
+		".env":         {Pattern: ".env", Source: IgnoreSourceDefault},
+		".spaceignore": {Pattern: ".spaceignore", Source: IgnoreSourceDefault},
+		"debug.log":    {Pattern: "*.log", Source: spaceignoreFile, Line: 2},
+		"node_modules": {Pattern: "node_modules", Source: IgnoreSourceDefault},
+	}
+	if len(report.Ignored) != len(expected) {
+		t.Fatalf("expected %d ignored files, got %+v", len(expected), report.Ignored)
+	}
+	for _, file := range report.Ignored {
+		rule, ok := expected[file.Path]
+		if !ok || file.Rule.Pattern != rule.Pattern || file.Rule.Source != rule.Source || (rule.Line != 0 && file.Rule.Line != rule.Line) {
+			t.Errorf("unexpected ignored file %+v", file)
+		}
+	}
+	if !report.Ignored[3].Dir {
+		t.Errorf("expected node_modules to be reported as a directory")
+	}
+}
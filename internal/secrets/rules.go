@@ -0,0 +1,190 @@
+package secrets
+
+import (
+	"math"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// rule finds a kind of secret in the lines of a file
+type rule struct {
+	id          string
+	description string
+	pattern     *regexp.Regexp
+	// group is the submatch holding the secret, the whole match if zero
+	group int
+	// check, if set, filters the secrets matched by the pattern
+	check func(secret string) bool
+}
+
+var rules = []rule{
+	{
+		id:          "private-key",
+		description: "PEM private key",
+		pattern:     regexp.MustCompile(`-----BEGIN ((RSA|DSA|EC|OPENSSH|PGP|ENCRYPTED) )?PRIVATE KEY( BLOCK)?-----`),
+	},
+	{
+		// project keys and access tokens are an id and a secret separated by an underscore
+		id:          "deta-key",
+		description: "Deta project key or access token",
+		pattern:     regexp.MustCompile(`\b([a-zA-Z0-9]{8}_[a-zA-Z0-9]{32})\b`),
+		group:       1,
+		check:       mixedCharacters,
+	},
+	{
+		id:          "aws-access-key-id",
+		description: "AWS access key id",
+		pattern:     regexp.MustCompile(`\b((AKIA|ASIA)[0-9A-Z]{16})\b`),
+		group:       1,
+	},
+	{
+		id:          "aws-secret-access-key",
+		description: "AWS secret access key",
+		pattern:     regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})\b`),
+		group:       1,
+	},
+	{
+		id:          "gcp-service-account",
+		description: "Google Cloud service account key",
+		pattern:     regexp.MustCompile(`"type"\s*:\s*"service_account"`),
+	},
+	{
+		id:          "google-api-key",
+		description: "Google API key",
+		pattern:     regexp.MustCompile(`\b(AIza[0-9A-Za-z_\-]{35})\b`),
+		group:       1,
+	},
+	{
+		id:          "azure-storage-key",
+		description: "Azure storage account key",
+		pattern:     regexp.MustCompile(`AccountKey=([A-Za-z0-9+/=]{88})`),
+		group:       1,
+	},
+	{
+		id:          "github-token",
+		description: "GitHub token",
+		pattern:     regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{82})\b`),
+		group:       1,
+	},
+	{
+		id:          "slack-token",
+		description: "Slack token",
+		pattern:     regexp.MustCompile(`\b(xox[abprs]-[A-Za-z0-9\-]{10,})`),
+		group:       1,
+	},
+	{
+		id:          "stripe-key",
+		description: "Stripe live key",
+		pattern:     regexp.MustCompile(`\b((sk|rk)_live_[0-9a-zA-Z]{24,})\b`),
+		group:       1,
+	},
+	{
+		// values assigned to secret looking names
+		id:          "high-entropy-assignment",
+		description: "High entropy value assigned to a secret",
+		pattern:     regexp.MustCompile(`(?i)(secret|token|passw(or)?d|api_?key|private_?key|access_?key|credentials?)\w*["']?\s*[:=]\s*["']?([A-Za-z0-9+/=_\-]{16,})(["'\s,;]|$)`),
+		group:       3,
+		check: func(secret string) bool {
+			return hasLetterAndDigit(secret) && entropy(secret) >= 3.5
+		},
+	},
+	{
+		// long quoted strings, the threshold is above the entropy of hex strings so that hashes are not reported
+		id:          "high-entropy-string",
+		description: "High entropy string",
+		pattern:     regexp.MustCompile(`["']([A-Za-z0-9+/=_\-]{32,})["']`),
+		group:       1,
+		check: func(secret string) bool {
+			return mixedCharacters(secret) && entropy(secret) >= 4.5
+		},
+	},
+}
+
+// fileRule finds files that usually hold secrets from their names
+type fileRule struct {
+	id          string
+	description string
+	match       func(name string) bool
+}
+
+var fileRules = []fileRule{
+	{
+		id:          "env-file",
+		description: "Environment file",
+		match: func(name string) bool {
+			if name != ".env" && !strings.HasPrefix(name, ".env.") {
+				return false
+			}
+			switch path.Ext(name) {
+			case ".example", ".sample", ".template", ".dist":
+				return false
+			}
+			return true
+		},
+	},
+	{
+		id:          "private-key-file",
+		description: "Private key file",
+		match: func(name string) bool {
+			switch path.Ext(name) {
+			case ".pem", ".key", ".p12", ".pfx":
+				return true
+			}
+			switch name {
+			case "id_rsa", "id_dsa", "id_ecdsa", "id_ed25519":
+				return true
+			}
+			return false
+		},
+	},
+}
+
+// skippedFiles are not scanned, lockfiles are full of hashes
+var skippedFiles = map[string]bool{
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"poetry.lock":       true,
+	"Pipfile.lock":      true,
+	"go.sum":            true,
+	"Cargo.lock":        true,
+}
+
+// entropy is the shannon entropy of a string in bits per character
+func entropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+
+	var e float64
+	n := float64(len(s))
+	for _, count := range counts {
+		p := float64(count) / n
+		e -= p * math.Log2(p)
+	}
+	return e
+}
+
+func hasLetterAndDigit(s string) bool {
+	return strings.ContainsAny(s, "0123456789") && strings.IndexFunc(s, func(r rune) bool {
+		return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+	}) != -1
+}
+
+// mixedCharacters checks that a string has lowercase and uppercase letters or digits, unlike words and identifiers
+func mixedCharacters(s string) bool {
+	var lower, upper, digit bool
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		}
+	}
+	return ((lower || upper) && digit) || (lower && upper)
+}
@@ -0,0 +1,195 @@
+// Package secrets finds secrets in the files of a project before they are pushed
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/deta/space/internal/runtime"
+	ignore "github.com/sabhiram/go-gitignore"
+)
+
+// AllowlistFile lists the files and rules that are not reported by the scan
+const AllowlistFile = ".spaceallowlist"
+
+// allowComment on a line skips the line
+const allowComment = "space:allow-secret"
+
+// maxLineSize is the longest line scanned, the rest of a file with a longer line is not scanned
+const maxLineSize = 1 << 20
+
+// binarySniffSize is the size of the start of a file checked for binary content
+const binarySniffSize = 8000
+
+// Finding is a potential secret
+type Finding struct {
+	Path string `json:"path"`
+	// Line is zero if the finding is about the file itself
+	Line        int    `json:"line,omitempty"`
+	Rule        string `json:"rule"`
+	Description string `json:"description"`
+	// Secret is redacted
+	Secret string `json:"secret,omitempty"`
+}
+
+func (f Finding) String() string {
+	location := f.Path
+	if f.Line > 0 {
+		location = fmt.Sprintf("%s:%d", f.Path, f.Line)
+	}
+	if f.Secret == "" {
+		return fmt.Sprintf("%s: %s (%s)", location, f.Description, f.Rule)
+	}
+	return fmt.Sprintf("%s: %s %s (%s)", location, f.Description, f.Secret, f.Rule)
+}
+
+// Allowlist skips the findings of some files and rules.
+// Every line of the allowlist file is a .spaceignore pattern of files to skip, or rule:<id> to skip a rule everywhere.
+type Allowlist struct {
+	files *ignore.GitIgnore
+	rules map[string]bool
+}
+
+// LoadAllowlist reads the allowlist of a project, an empty allowlist is returned if the project has none
+func LoadAllowlist(projectDir string) (*Allowlist, error) {
+	content, err := os.ReadFile(filepath.Join(projectDir, AllowlistFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s, %w", AllowlistFile, err)
+	}
+
+	return ParseAllowlist(string(content)), nil
+}
+
+// ParseAllowlist parses the content of an allowlist file
+func ParseAllowlist(content string) *Allowlist {
+	allowlist := &Allowlist{rules: map[string]bool{}}
+
+	var patterns []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if id, ok := cutPrefix(line, "rule:"); ok {
+			allowlist.rules[strings.TrimSpace(id)] = true
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	allowlist.files = ignore.CompileIgnoreLines(patterns...)
+
+	return allowlist
+}
+
+func (a *Allowlist) allows(filePath string, ruleID string) bool {
+	if a == nil {
+		return false
+	}
+	return a.rules[ruleID] || a.files.MatchesPath(filePath)
+}
+
+// Scan looks for secrets in files, findings are sorted by path and line
+func Scan(files []runtime.ArchiveFile, allowlist *Allowlist) ([]Finding, error) {
+	var findings []Finding
+	for _, file := range files {
+		if skippedFiles[path.Base(file.Path)] {
+			continue
+		}
+
+		f, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s, %w", file.Path, err)
+		}
+		fileFindings, err := ScanContent(file.Path, f, allowlist)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, fileFindings...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Path != findings[j].Path {
+			return findings[i].Path < findings[j].Path
+		}
+		return findings[i].Line < findings[j].Line
+	})
+
+	return findings, nil
+}
+
+// ScanContent looks for secrets in the name and content of a file
+func ScanContent(filePath string, r io.Reader, allowlist *Allowlist) ([]Finding, error) {
+	var findings []Finding
+	for _, fr := range fileRules {
+		if fr.match(path.Base(filePath)) && !allowlist.allows(filePath, fr.id) {
+			findings = append(findings, Finding{Path: filePath, Rule: fr.id, Description: fr.description})
+		}
+	}
+
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(binarySniffSize); bytes.IndexByte(head, 0) != -1 {
+		return findings, nil
+	}
+
+	scanner := bufio.NewScanner(br)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if strings.Contains(line, allowComment) {
+			continue
+		}
+		findings = append(findings, scanLine(filePath, lineNo, line, allowlist)...)
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, bufio.ErrTooLong) {
+		return nil, fmt.Errorf("failed to scan %s, %w", filePath, err)
+	}
+
+	return findings, nil
+}
+
+// scanLine reports the first match of every rule on a line
+func scanLine(filePath string, lineNo int, line string, allowlist *Allowlist) []Finding {
+	var findings []Finding
+	reported := map[string]bool{}
+	for _, r := range rules {
+		for _, match := range r.pattern.FindAllStringSubmatch(line, -1) {
+			secret := match[r.group]
+			if r.check != nil && !r.check(secret) {
+				continue
+			}
+			// the generic rules do not report secrets already found by a specific rule
+			if reported[secret] || allowlist.allows(filePath, r.id) {
+				break
+			}
+			reported[secret] = true
+			findings = append(findings, Finding{Path: filePath, Line: lineNo, Rule: r.id, Description: r.description, Secret: redact(secret)})
+			break
+		}
+	}
+	return findings
+}
+
+// redact keeps the start of a secret so that it can be recognized
+func redact(secret string) string {
+	if strings.HasPrefix(secret, "-----") {
+		return ""
+	}
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return secret[:4] + "****"
+}
+
+// cutPrefix is strings.CutPrefix, which is not available in go 1.19
+func cutPrefix(s, prefix string) (string, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
@@ -0,0 +1,358 @@
+package spacefile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// Severity of a diagnostic
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// rank orders severities, higher is more severe
+func (s Severity) rank() int {
+	switch s {
+	case SeverityError:
+		return 2
+	case SeverityWarning:
+		return 1
+	}
+	return 0
+}
+
+// rule ids of the checks done while loading a Spacefile
+const (
+	RuleYamlSyntax      = "yaml-syntax"
+	RuleDuplicateMicros = "duplicate-micros"
+	RuleMultiplePrimary = "multiple-primary"
+	RuleNoPrimary       = "no-primary"
+	RuleMissingSrc      = "missing-src"
+	RuleIconPath        = "icon-path"
+	RuleIconType        = "icon-type"
+	RuleIconSize        = "icon-size"
+	RuleNoIcon          = "no-icon"
+
+	RuleOutdatedVersion    = "outdated-version"
+	RuleUnsupportedVersion = "unsupported-version"
+)
+
+// Diagnostic is a single problem found in a Spacefile, along with its position
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	// Length is the number of columns the problem spans, used to underline it
+	Length  int    `json:"length,omitempty"`
+	Micro   string `json:"micro,omitempty"`
+	Message string `json:"message"`
+	// Err is the underlying error, if any, so that callers can use errors.Is
+	Err error `json:"-"`
+}
+
+func (d Diagnostic) String() string {
+	var message string
+	if d.Micro != "" {
+		message = fmt.Sprintf("Micro '%s': %s", d.Micro, d.Message)
+	} else {
+		message = d.Message
+	}
+
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s: %s", d.File, d.Severity, message)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s: %s", d.File, d.Line, d.Column, d.Severity, message)
+}
+
+// Snippet renders the source line of the diagnostic with a caret underline
+func (d Diagnostic) Snippet(source []byte) string {
+	lines := strings.Split(string(source), "\n")
+	if d.Line < 1 || d.Line > len(lines) {
+		return ""
+	}
+
+	line := strings.TrimRight(lines[d.Line-1], "\r")
+	gutter := strconv.Itoa(d.Line)
+	padding := strings.Repeat(" ", len(gutter))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, " %s | %s\n", gutter, line)
+	if d.Column < 1 {
+		return b.String()
+	}
+
+	length := d.Length
+	if length < 1 {
+		length = 1
+	}
+	fmt.Fprintf(&b, " %s | %s%s\n", padding, strings.Repeat(" ", d.Column-1), strings.Repeat("^", length))
+
+	return b.String()
+}
+
+// ValidationError is returned when a Spacefile is invalid, it holds every diagnostic found
+type ValidationError struct {
+	Diagnostics []Diagnostic
+	// sources are the contents of the files the diagnostics point to,
+	// files that are missing from the map are read from disk
+	sources map[string][]byte
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+
+	if len(e.Diagnostics) == 1 {
+		b.WriteString("1 problem found\n")
+	} else {
+		fmt.Fprintf(&b, "%d problems found\n", len(e.Diagnostics))
+	}
+
+	for _, d := range e.Diagnostics {
+		fmt.Fprintf(&b, "\n%s\n", d)
+		b.WriteString(d.Snippet(e.source(d.File)))
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (e *ValidationError) source(file string) []byte {
+	if source, ok := e.sources[file]; ok {
+		return source
+	}
+
+	source, _ := os.ReadFile(file)
+	return source
+}
+
+// NewValidationError groups diagnostics found in file, source is its content shown in the snippets
+func NewValidationError(diagnostics []Diagnostic, file string, source []byte) *ValidationError {
+	return newValidationError(diagnostics, file, source)
+}
+
+func newValidationError(diagnostics []Diagnostic, file string, source []byte) *ValidationError {
+	SortDiagnostics(diagnostics)
+	return &ValidationError{Diagnostics: diagnostics, sources: map[string][]byte{file: source}}
+}
+
+// SortDiagnostics orders diagnostics by file and position
+func SortDiagnostics(diagnostics []Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].File != diagnostics[j].File {
+			return diagnostics[i].File < diagnostics[j].File
+		}
+		if diagnostics[i].Line != diagnostics[j].Line {
+			return diagnostics[i].Line < diagnostics[j].Line
+		}
+		return diagnostics[i].Column < diagnostics[j].Column
+	})
+}
+
+// HighestSeverity returns the most severe level among the diagnostics, or an empty severity if there are none
+func HighestSeverity(diagnostics []Diagnostic) Severity {
+	var highest Severity
+	for _, d := range diagnostics {
+		if highest == "" || d.Severity.rank() > highest.rank() {
+			highest = d.Severity
+		}
+	}
+	return highest
+}
+
+// Is reports whether any of the diagnostics was caused by target
+func (e *ValidationError) Is(target error) bool {
+	for _, d := range e.Diagnostics {
+		if d.Err != nil && errors.Is(d.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Diagnostics extracts the diagnostics from an error returned while loading a Spacefile
+func Diagnostics(err error) []Diagnostic {
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return ve.Diagnostics
+	}
+	return nil
+}
+
+// nodeAt resolves a json pointer (as reported by the schema validator) against a yaml tree,
+// it returns the key node (for mapping members) and the value node
+func nodeAt(root *yaml.Node, pointer string) (*yaml.Node, *yaml.Node) {
+	node := root
+	if node != nil && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	var key *yaml.Node
+	for _, token := range strings.Split(pointer, "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		if node == nil {
+			return nil, nil
+		}
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+
+		switch node.Kind {
+		case yaml.MappingNode:
+			k, v := mappingEntry(node, token)
+			if v == nil {
+				return key, node
+			}
+			key, node = k, v
+		case yaml.SequenceNode:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node.Content) {
+				return key, node
+			}
+			key, node = nil, node.Content[i]
+		default:
+			return key, node
+		}
+	}
+
+	if node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	return key, node
+}
+
+// mappingEntry finds the key and value nodes of a field in a mapping node
+func mappingEntry(node *yaml.Node, field string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == field {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// nodeLength is the number of columns a node spans on its first line
+func nodeLength(node *yaml.Node) int {
+	if node == nil || node.Kind != yaml.ScalarNode || strings.Contains(node.Value, "\n") {
+		return 1
+	}
+
+	switch node.Style {
+	case yaml.DoubleQuotedStyle, yaml.SingleQuotedStyle:
+		return len(node.Value) + 2
+	case yaml.LiteralStyle, yaml.FoldedStyle:
+		return 1
+	}
+
+	if node.Value == "" {
+		return 1
+	}
+	return len(node.Value)
+}
+
+// diagnosticAt creates a diagnostic positioned on the node at the given pointer
+func diagnosticAt(root *yaml.Node, file string, pointer string, preferKey bool) Diagnostic {
+	d := Diagnostic{Severity: SeverityError, File: file}
+
+	key, value := nodeAt(root, pointer)
+	target := value
+	if key != nil && (preferKey || value == nil || value.Kind != yaml.ScalarNode) {
+		target = key
+	}
+	if target == nil {
+		target = key
+	}
+
+	if target != nil {
+		d.Line = target.Line
+		d.Column = target.Column
+		d.Length = nodeLength(target)
+	}
+
+	if matches := microIndexReg.FindStringSubmatch(pointer); len(matches) == 2 {
+		i, _ := strconv.Atoi(matches[1])
+		_, name := nodeAt(root, fmt.Sprintf("/micros/%d/name", i))
+		if name != nil && name.Kind == yaml.ScalarNode {
+			d.Micro = name.Value
+		}
+	}
+
+	return d
+}
+
+var (
+	microIndexReg         = regexp.MustCompile(`^/micros/(\d+)`)
+	additionalPropertyReg = regexp.MustCompile(`^additionalProperties '([^']+)'`)
+	yamlErrorLineReg      = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+)
+
+// leafMessage formats the message of a schema error without causes
+func leafMessage(ve *jsonschema.ValidationError) string {
+	message := strings.Replace(ve.Message, "additionalProperties", "unknown field", 1)
+	parts := strings.Split(ve.InstanceLocation, "/")
+
+	leaf := parts[len(parts)-1]
+	if leaf == "" || numberReg.MatchString(leaf) {
+		return message
+	}
+
+	return fmt.Sprintf("%s -> %s", leaf, message)
+}
+
+// schemaDiagnostics flattens a schema validation error into positioned diagnostics
+func schemaDiagnostics(ve *jsonschema.ValidationError, root *yaml.Node, file string) []Diagnostic {
+	if len(ve.Causes) > 0 {
+		var diagnostics []Diagnostic
+		for _, cause := range ve.Causes {
+			diagnostics = append(diagnostics, schemaDiagnostics(cause, root, file)...)
+		}
+		return diagnostics
+	}
+
+	// unknown fields are reported on the parent object, point at the offending key instead
+	pointer, preferKey := ve.InstanceLocation, false
+	if matches := additionalPropertyReg.FindStringSubmatch(ve.Message); len(matches) == 2 {
+		pointer, preferKey = pointer+"/"+matches[1], true
+	}
+
+	d := diagnosticAt(root, file, pointer, preferKey)
+	d.Rule = schemaRule(ve)
+	d.Message = leafMessage(ve)
+	return []Diagnostic{d}
+}
+
+// schemaRule derives a rule id from the schema keyword that failed, e.g. schema/enum
+func schemaRule(ve *jsonschema.ValidationError) string {
+	parts := strings.Split(ve.KeywordLocation, "/")
+	return "schema/" + parts[len(parts)-1]
+}
+
+// yamlDiagnostic converts a yaml syntax error into a diagnostic
+func yamlDiagnostic(err error, file string) Diagnostic {
+	d := Diagnostic{Severity: SeverityError, Rule: RuleYamlSyntax, File: file, Message: err.Error(), Err: err}
+
+	if matches := yamlErrorLineReg.FindStringSubmatch(err.Error()); len(matches) == 3 {
+		d.Line, _ = strconv.Atoi(matches[1])
+		d.Message = matches[2]
+	}
+
+	return d
+}
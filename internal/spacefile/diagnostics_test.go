@@ -0,0 +1,84 @@
+package spacefile
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type DiagnosticTestCase struct {
+	line    int
+	column  int
+	micro   string
+	message string
+}
+
+func TestSchemaDiagnostics(t *testing.T) {
+	_, err := LoadSpacefile("testdata/spacefile/invalid_fields")
+	if err == nil {
+		t.Fatalf("expected error but got none")
+	}
+
+	diagnostics := Diagnostics(err)
+	expected := []DiagnosticTestCase{
+		{line: 6, column: 13, micro: "api", message: "engine -> value must be one of"},
+		{line: 7, column: 5, micro: "frontend", message: "missing properties: 'serve'"},
+		{line: 10, column: 5, micro: "frontend", message: "unknown field 'unknown' not allowed"},
+	}
+
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("expected %d diagnostics but got %d: %v", len(expected), len(diagnostics), err)
+	}
+
+	for i, c := range expected {
+		d := diagnostics[i]
+		if d.Line != c.line || d.Column != c.column {
+			t.Errorf("expected diagnostic %d at %d:%d but got %d:%d", i, c.line, c.column, d.Line, d.Column)
+		}
+		if d.Micro != c.micro {
+			t.Errorf("expected diagnostic %d for micro %s but got %s", i, c.micro, d.Micro)
+		}
+		if !strings.HasPrefix(d.Message, c.message) {
+			t.Errorf("expected diagnostic %d message to start with %q but got %q", i, c.message, d.Message)
+		}
+	}
+}
+
+func TestSemanticDiagnostics(t *testing.T) {
+	_, err := LoadSpacefile("testdata/spacefile/duplicated_micros")
+	if !errors.Is(err, ErrDuplicateMicros) {
+		t.Fatalf("expected error to be %v but got %v", ErrDuplicateMicros, err)
+	}
+
+	diagnostics := Diagnostics(err)
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic but got %d", len(diagnostics))
+	}
+
+	if diagnostics[0].Line != 13 || diagnostics[0].Column != 11 {
+		t.Fatalf("expected diagnostic at 13:11 but got %d:%d", diagnostics[0].Line, diagnostics[0].Column)
+	}
+}
+
+func TestDiagnosticSnippet(t *testing.T) {
+	source := []byte("v: 0\nmicros:\n  - name: api\n    engine: pyton\n")
+	d := Diagnostic{Line: 4, Column: 13, Length: 5}
+
+	expected := " 4 |     engine: pyton\n   |             ^^^^^\n"
+	if snippet := d.Snippet(source); snippet != expected {
+		t.Fatalf("expected snippet:\n%s\nbut got:\n%s", expected, snippet)
+	}
+}
+
+func TestYamlSyntaxDiagnostic(t *testing.T) {
+	_, err := parseSpacefile(".", "Spacefile", []byte("v: 0\nmicros:\n  - name: api\n   src: .\n"))
+
+	diagnostics := Diagnostics(err)
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic but got %d: %v", len(diagnostics), err)
+	}
+
+	if diagnostics[0].Line == 0 {
+		t.Fatalf("expected the yaml syntax error to have a line")
+	}
+}
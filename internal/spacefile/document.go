@@ -0,0 +1,637 @@
+package spacefile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/deta/space/shared"
+	"gopkg.in/yaml.v3"
+)
+
+const spacefileDocsComment = "Spacefile Docs: https://go.deta.dev/docs/spacefile/v0"
+
+var (
+	ErrMicroNotFound   = errors.New("micro not found")
+	ErrMicroExists     = errors.New("a micro with the same name already exists")
+	ErrInvalidName     = errors.New("invalid micro name")
+	ErrInvalidDocument = errors.New("Spacefile is not a yaml mapping")
+	ErrInvalidPath     = errors.New("invalid field path")
+)
+
+// identityKeys are the fields used to match the items of a sequence across edits,
+// micros and env entries are matched by name, actions by id
+var identityKeys = []string{"id", "name"}
+
+// Document is an editable Spacefile backed by its yaml node tree.
+// Edits only touch the nodes they change, so comments, key order and anchors are kept.
+type Document struct {
+	root *yaml.Node
+}
+
+// NewDocument creates an empty Spacefile document with the docs header
+func NewDocument() *Document {
+	mapping := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", HeadComment: spacefileDocsComment}
+	root := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{mapping}}
+	return &Document{root: root}
+}
+
+// ParseDocument parses the raw contents of a Spacefile into a document
+func ParseDocument(content []byte) (*Document, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+
+	// an empty file has no document node
+	if root.Kind == 0 {
+		return NewDocument(), nil
+	}
+
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, ErrInvalidDocument
+	}
+
+	return &Document{root: &root}, nil
+}
+
+// LoadDocument reads the Spacefile of a project into a document
+func LoadDocument(projectDir string) (*Document, error) {
+	content, err := os.ReadFile(filepath.Join(projectDir, SpacefileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrSpacefileNotFound
+		}
+		return nil, fmt.Errorf("failed to read, %w", err)
+	}
+
+	return ParseDocument(content)
+}
+
+// Bytes encodes the document back to yaml
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(d.root); err != nil {
+		return nil, fmt.Errorf("failed to marshall spacefile document: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshall spacefile document: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Save writes the document to the Spacefile of a project
+func (d *Document) Save(projectDir string) error {
+	content, err := d.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(projectDir, SpacefileName), content, 0644); err != nil {
+		return fmt.Errorf("failed to write, %w", err)
+	}
+
+	return nil
+}
+
+func (d *Document) mapping() *yaml.Node {
+	return d.root.Content[0]
+}
+
+// micros returns the micros sequence for editing, creating it if needed
+func (d *Document) micros(create bool) *yaml.Node {
+	_, micros := mappingEntry(d.mapping(), "micros")
+	if micros == nil && create {
+		micros = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		setMappingEntry(d.mapping(), "micros", micros)
+	}
+	return expandAlias(micros)
+}
+
+// micro finds the node of a micro by name for editing
+func (d *Document) micro(name string) (*yaml.Node, error) {
+	micros := d.micros(false)
+	if micros == nil {
+		return nil, fmt.Errorf("%w: %s", ErrMicroNotFound, name)
+	}
+
+	if i := sequenceIndex(micros, "name", name); i >= 0 {
+		return expandAlias(micros.Content[i]), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrMicroNotFound, name)
+}
+
+// MicroNames lists the names of the micros in the document
+func (d *Document) MicroNames() []string {
+	_, micros := mappingEntry(d.mapping(), "micros")
+	micros = resolveAlias(micros)
+	if micros == nil {
+		return nil
+	}
+
+	var names []string
+	for _, item := range micros.Content {
+		if _, name := mappingEntry(resolveAlias(item), "name"); name != nil {
+			names = append(names, name.Value)
+		}
+	}
+	return names
+}
+
+// AddMicro appends a micro to the document
+func (d *Document) AddMicro(micro *shared.Micro) error {
+	if err := ValidateMicroName(micro.Name); err != nil {
+		return err
+	}
+
+	for _, name := range d.MicroNames() {
+		if name == micro.Name {
+			return fmt.Errorf("%w: %s", ErrMicroExists, micro.Name)
+		}
+	}
+
+	node, err := encodeNode(micro)
+	if err != nil {
+		return err
+	}
+
+	micros := d.micros(true)
+	micros.Content = append(micros.Content, node)
+	return nil
+}
+
+// RemoveMicro removes a micro from the document
+func (d *Document) RemoveMicro(name string) error {
+	micros := d.micros(false)
+	if micros == nil {
+		return fmt.Errorf("%w: %s", ErrMicroNotFound, name)
+	}
+
+	i := sequenceIndex(micros, "name", name)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrMicroNotFound, name)
+	}
+
+	micros.Content = append(micros.Content[:i], micros.Content[i+1:]...)
+	return nil
+}
+
+// UpdateMicro replaces the fields of the micro with the same name, only changed fields are touched
+func (d *Document) UpdateMicro(micro *shared.Micro) error {
+	node, err := d.micro(micro.Name)
+	if err != nil {
+		return err
+	}
+
+	var current shared.Micro
+	if err := node.Decode(&current); err != nil {
+		return err
+	}
+
+	return patchValue(node, &current, micro)
+}
+
+// SetEnv adds or updates an env preset of a micro, matched by name
+func (d *Document) SetEnv(microName string, env shared.Environment) error {
+	return d.updateMicro(microName, func(micro *shared.Micro) error {
+		if micro.Presets == nil {
+			micro.Presets = &shared.Presets{}
+		}
+
+		for i := range micro.Presets.Env {
+			if micro.Presets.Env[i].Name == env.Name {
+				micro.Presets.Env[i] = env
+				return nil
+			}
+		}
+
+		micro.Presets.Env = append(micro.Presets.Env, env)
+		return nil
+	})
+}
+
+// RemoveEnv removes an env preset of a micro
+func (d *Document) RemoveEnv(microName string, envName string) error {
+	return d.updateMicro(microName, func(micro *shared.Micro) error {
+		if micro.Presets != nil {
+			for i := range micro.Presets.Env {
+				if micro.Presets.Env[i].Name == envName {
+					micro.Presets.Env = append(micro.Presets.Env[:i], micro.Presets.Env[i+1:]...)
+					return nil
+				}
+			}
+		}
+
+		return fmt.Errorf("env %s not found in micro %s", envName, microName)
+	})
+}
+
+// SetAPIKeys toggles the api keys preset of a micro
+func (d *Document) SetAPIKeys(microName string, enabled bool) error {
+	return d.updateMicro(microName, func(micro *shared.Micro) error {
+		if micro.Presets == nil {
+			micro.Presets = &shared.Presets{}
+		}
+		micro.Presets.APIKeys = enabled
+		return nil
+	})
+}
+
+// SetAction adds or updates an action of a micro, matched by id
+func (d *Document) SetAction(microName string, action shared.Action) error {
+	return d.updateMicro(microName, func(micro *shared.Micro) error {
+		for i := range micro.Actions {
+			if micro.Actions[i].ID == action.ID {
+				micro.Actions[i] = action
+				return nil
+			}
+		}
+
+		micro.Actions = append(micro.Actions, action)
+		return nil
+	})
+}
+
+// RemoveAction removes an action of a micro
+func (d *Document) RemoveAction(microName string, actionID string) error {
+	return d.updateMicro(microName, func(micro *shared.Micro) error {
+		for i := range micro.Actions {
+			if micro.Actions[i].ID == actionID {
+				micro.Actions = append(micro.Actions[:i], micro.Actions[i+1:]...)
+				return nil
+			}
+		}
+
+		return fmt.Errorf("action %s not found in micro %s", actionID, microName)
+	})
+}
+
+// Set sets the field at a dotted path, such as "micros.api.presets.env.API_URL.default".
+// Items of micros and env presets are selected by name, actions by id, and missing
+// items and fields are created along the way, except micros which must already exist. The value is parsed as yaml unless the
+// field is a string in the schema, so that "8080" stays a string where one is expected.
+func (d *Document) Set(path string, value string) error {
+	segments := strings.Split(path, ".")
+	for _, segment := range segments {
+		if segment == "" {
+			return fmt.Errorf("%w: %s", ErrInvalidPath, path)
+		}
+	}
+
+	valueNode, err := parseValue(value, schemaAt(segments))
+	if err != nil {
+		return fmt.Errorf("failed to parse value %s, %w", value, err)
+	}
+
+	node := d.mapping()
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		schema := schemaAt(segments[:i+1])
+
+		switch node.Kind {
+		case yaml.MappingNode:
+			_, child := mappingEntry(node, segment)
+			if last {
+				if child == nil {
+					setMappingEntry(node, segment, valueNode)
+					return nil
+				}
+				// patch the existing value to keep its quoting style
+				patchNode(child, child, valueNode)
+				return nil
+			}
+
+			if child == nil {
+				child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				if schemaType(schema) == "array" {
+					child = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+				}
+				setMappingEntry(node, segment, child)
+			}
+			node = expandAlias(child)
+		case yaml.SequenceNode:
+			if last {
+				return fmt.Errorf("%w: %s, cannot replace a whole item", ErrInvalidPath, path)
+			}
+
+			key := itemIdentity(schema)
+			j := sequenceIndex(node, key, segment)
+			if j < 0 && i == 1 && segments[0] == "micros" {
+				return fmt.Errorf("%w: %s", ErrMicroNotFound, segment)
+			}
+			if j < 0 {
+				item := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				setMappingEntry(item, key, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: segment})
+				node.Content = append(node.Content, item)
+				j = len(node.Content) - 1
+			}
+			node = expandAlias(node.Content[j])
+		default:
+			return fmt.Errorf("%w: %s, %s is not a mapping or a list", ErrInvalidPath, path, strings.Join(segments[:i], "."))
+		}
+	}
+
+	return nil
+}
+
+// itemIdentity returns the field identifying the items of a list, given the schema of an item
+func itemIdentity(schema map[string]any) string {
+	properties, _ := schema["properties"].(map[string]any)
+	for _, key := range identityKeys {
+		if _, ok := properties[key]; ok {
+			return key
+		}
+	}
+	return "name"
+}
+
+// parseValue parses the value of a field into a yaml node according to its schema
+func parseValue(value string, schema map[string]any) (*yaml.Node, error) {
+	if schemaType(schema) == "string" {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}, nil
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &node); err != nil {
+		return nil, err
+	}
+	if len(node.Content) == 0 {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+
+	return node.Content[0], nil
+}
+
+// Validate checks the document as if it was the Spacefile of projectDir, problems are returned as a *ValidationError
+func (d *Document) Validate(projectDir string) error {
+	content, err := d.Bytes()
+	if err != nil {
+		return err
+	}
+
+	_, err = parseSpacefile(projectDir, filepath.Join(projectDir, SpacefileName), content)
+	return err
+}
+
+// updateMicro applies a change to the decoded micro and patches the node with the difference
+func (d *Document) updateMicro(name string, update func(micro *shared.Micro) error) error {
+	node, err := d.micro(name)
+	if err != nil {
+		return err
+	}
+
+	var current, updated shared.Micro
+	if err := node.Decode(&current); err != nil {
+		return err
+	}
+	if err := node.Decode(&updated); err != nil {
+		return err
+	}
+
+	if err := update(&updated); err != nil {
+		return err
+	}
+
+	return patchValue(node, &current, &updated)
+}
+
+// encodeNode encodes a value into a yaml node
+func encodeNode(v any) (*yaml.Node, error) {
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to marshall spacefile object: %w", err)
+	}
+	return &node, nil
+}
+
+// patchValue applies the difference between old and new to the node
+func patchValue(node *yaml.Node, old, new any) error {
+	oldNode, err := encodeNode(old)
+	if err != nil {
+		return err
+	}
+
+	newNode, err := encodeNode(new)
+	if err != nil {
+		return err
+	}
+
+	patchNode(node, oldNode, newNode)
+	return nil
+}
+
+// patchNode applies the changes from old to new onto dst.
+// Parts of dst that are the same in old and new are left untouched, this way values
+// that were normalized after loading (or come from anchors) are not written back.
+func patchNode(dst, old, new *yaml.Node) {
+	if nodesEqual(old, new) {
+		return
+	}
+
+	if dst.Kind == yaml.AliasNode || old == nil || dst.Kind != new.Kind || old.Kind != new.Kind {
+		replaceNode(dst, new)
+		return
+	}
+
+	switch new.Kind {
+	case yaml.MappingNode:
+		patchMapping(dst, old, new)
+	case yaml.SequenceNode:
+		patchSequence(dst, old, new)
+	default:
+		if dst.ShortTag() != new.ShortTag() {
+			dst.Style = new.Style
+		}
+		dst.Tag = new.Tag
+		dst.Value = new.Value
+	}
+}
+
+func patchMapping(dst, old, new *yaml.Node) {
+	// removed keys
+	for i := 0; i+1 < len(old.Content); i += 2 {
+		key := old.Content[i].Value
+		if _, v := mappingEntry(new, key); v == nil {
+			removeMappingEntry(dst, key)
+		}
+	}
+
+	// added and changed keys
+	for i := 0; i+1 < len(new.Content); i += 2 {
+		key, newValue := new.Content[i].Value, new.Content[i+1]
+		_, oldValue := mappingEntry(old, key)
+		if nodesEqual(oldValue, newValue) {
+			continue
+		}
+
+		_, dstValue := mappingEntry(dst, key)
+		if dstValue == nil {
+			setMappingEntry(dst, key, newValue)
+			continue
+		}
+
+		patchNode(dstValue, oldValue, newValue)
+	}
+}
+
+func patchSequence(dst, old, new *yaml.Node) {
+	key := sequenceIdentity(dst, old, new)
+	if key == "" {
+		replaceNode(dst, new)
+		return
+	}
+
+	var content []*yaml.Node
+	for _, newItem := range new.Content {
+		_, id := mappingEntry(newItem, key)
+
+		i := sequenceIndex(dst, key, id.Value)
+		if i < 0 {
+			content = append(content, newItem)
+			continue
+		}
+
+		dstItem := dst.Content[i]
+		var oldItem *yaml.Node
+		if j := sequenceIndex(old, key, id.Value); j >= 0 {
+			oldItem = old.Content[j]
+		}
+		patchNode(expandAlias(dstItem), oldItem, newItem)
+		content = append(content, dstItem)
+	}
+
+	dst.Content = content
+}
+
+// sequenceIdentity finds a key that uniquely identifies every item of the sequences
+func sequenceIdentity(sequences ...*yaml.Node) string {
+	for _, key := range identityKeys {
+		ok := true
+		for _, sequence := range sequences {
+			seen := make(map[string]struct{})
+			for _, item := range sequence.Content {
+				item = resolveAlias(item)
+				if item.Kind != yaml.MappingNode {
+					return ""
+				}
+				_, id := mappingEntry(item, key)
+				if id == nil {
+					ok = false
+					break
+				}
+				if _, duplicate := seen[id.Value]; duplicate {
+					ok = false
+					break
+				}
+				seen[id.Value] = struct{}{}
+			}
+			if !ok {
+				break
+			}
+		}
+		if ok {
+			return key
+		}
+	}
+	return ""
+}
+
+// sequenceIndex finds the index of the item of a sequence whose key has the given value
+func sequenceIndex(sequence *yaml.Node, key string, value string) int {
+	for i, item := range sequence.Content {
+		item = resolveAlias(item)
+		if item.Kind != yaml.MappingNode {
+			continue
+		}
+		if _, v := mappingEntry(item, key); v != nil && v.Value == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// replaceNode overwrites dst with src, keeping the comments of dst
+func replaceNode(dst, src *yaml.Node) {
+	head, line, foot := dst.HeadComment, dst.LineComment, dst.FootComment
+	*dst = *src
+	dst.HeadComment, dst.LineComment, dst.FootComment = head, line, foot
+}
+
+// setMappingEntry sets the value of a key in a mapping node, appending the key if needed
+func setMappingEntry(node *yaml.Node, key string, value *yaml.Node) {
+	if _, v := mappingEntry(node, key); v != nil {
+		replaceNode(v, value)
+		return
+	}
+
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// removeMappingEntry removes a key from a mapping node
+func removeMappingEntry(node *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	if node != nil && node.Kind == yaml.AliasNode {
+		return node.Alias
+	}
+	return node
+}
+
+// expandAlias replaces an alias node with a copy of its anchor, so that editing it
+// does not change the anchor and its other aliases
+func expandAlias(node *yaml.Node) *yaml.Node {
+	if node != nil && node.Kind == yaml.AliasNode {
+		replaceNode(node, copyNode(node.Alias))
+		node.Anchor = ""
+	}
+	return node
+}
+
+// copyNode deep copies a node, resolving aliases
+func copyNode(node *yaml.Node) *yaml.Node {
+	node = resolveAlias(node)
+	copied := *node
+	copied.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		copied.Content[i] = copyNode(child)
+	}
+	return &copied
+}
+
+// nodesEqual compares the values of two nodes, ignoring styles and comments
+func nodesEqual(a, b *yaml.Node) bool {
+	a, b = resolveAlias(a), resolveAlias(b)
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	if a.Kind != b.Kind || len(a.Content) != len(b.Content) {
+		return false
+	}
+
+	if a.Kind == yaml.ScalarNode {
+		return a.ShortTag() == b.ShortTag() && a.Value == b.Value
+	}
+
+	for i := range a.Content {
+		if !nodesEqual(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,312 @@
+package spacefile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deta/space/shared"
+	"gopkg.in/yaml.v3"
+)
+
+const commentedSpacefile = `# Spacefile Docs: https://go.deta.dev/docs/spacefile/v0
+v: 0
+micros:
+  # the backend
+  - name: api
+    src: .
+    engine: python3.9 # keep me
+    primary: true
+    presets: &presets
+      env:
+        - name: API_URL
+          default: "http://localhost"
+  - name: worker
+    src: .
+    engine: python3.9
+    presets: *presets
+`
+
+func TestDocumentKeepsComments(t *testing.T) {
+	doc, err := ParseDocument([]byte(commentedSpacefile))
+	if err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+
+	if err := doc.AddMicro(&shared.Micro{Name: "frontend", Src: "frontend", Engine: shared.Static, Serve: "dist"}); err != nil {
+		t.Fatalf("failed to add micro: %v", err)
+	}
+
+	if err := doc.RemoveMicro("worker"); err != nil {
+		t.Fatalf("failed to remove micro: %v", err)
+	}
+
+	if err := doc.SetAction("api", shared.Action{ID: "cleanup", Name: "Cleanup", Trigger: "schedule", Interval: "0/15 * * * *"}); err != nil {
+		t.Fatalf("failed to set action: %v", err)
+	}
+
+	content, err := doc.Bytes()
+	if err != nil {
+		t.Fatalf("failed to encode document: %v", err)
+	}
+
+	for _, expected := range []string{
+		"# Spacefile Docs: https://go.deta.dev/docs/spacefile/v0\nv: 0",
+		"# the backend\n  - name: api",
+		"engine: python3.9 # keep me",
+		"presets: &presets",
+		`default: "http://localhost"`,
+		"- name: frontend",
+		"id: cleanup",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Fatalf("expected document to contain %q, got:\n%s", expected, content)
+		}
+	}
+
+	if strings.Contains(string(content), "worker") {
+		t.Fatalf("expected micro worker to be removed, got:\n%s", content)
+	}
+}
+
+func TestDocumentSetEnv(t *testing.T) {
+	doc, err := ParseDocument([]byte(commentedSpacefile))
+	if err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+
+	if err := doc.SetEnv("api", shared.Environment{Name: "API_URL", Default: "https://example.com"}); err != nil {
+		t.Fatalf("failed to set env: %v", err)
+	}
+
+	content, err := doc.Bytes()
+	if err != nil {
+		t.Fatalf("failed to encode document: %v", err)
+	}
+
+	if !strings.Contains(string(content), `default: "https://example.com"`) {
+		t.Fatalf("expected env default to be updated in place, got:\n%s", content)
+	}
+}
+
+func TestDocumentSetEnvThroughAlias(t *testing.T) {
+	doc, err := ParseDocument([]byte(commentedSpacefile))
+	if err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+
+	if err := doc.SetEnv("worker", shared.Environment{Name: "API_URL", Default: "https://worker.example.com"}); err != nil {
+		t.Fatalf("failed to set env: %v", err)
+	}
+
+	content, err := doc.Bytes()
+	if err != nil {
+		t.Fatalf("failed to encode document: %v", err)
+	}
+
+	var spacefile Spacefile
+	if err := yaml.Unmarshal(content, &spacefile); err != nil {
+		t.Fatalf("failed to parse edited document: %v\n%s", err, content)
+	}
+
+	defaults := map[string]string{}
+	for _, micro := range spacefile.Micros {
+		defaults[micro.Name] = micro.Presets.Env[0].Default
+	}
+	if defaults["api"] != "http://localhost" || defaults["worker"] != "https://worker.example.com" {
+		t.Fatalf("expected only the worker to change, got %v in:\n%s", defaults, content)
+	}
+}
+
+func TestSaveKeepsComments(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, SpacefileName), []byte(commentedSpacefile), 0644); err != nil {
+		t.Fatalf("failed to write spacefile: %v", err)
+	}
+
+	s, err := LoadSpacefile(dir)
+	if err != nil {
+		t.Fatalf("failed to load spacefile: %v", err)
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, "frontend"), 0755); err != nil {
+		t.Fatalf("failed to create micro src: %v", err)
+	}
+
+	if err := s.AddMicro(&shared.Micro{Name: "frontend", Src: "frontend", Engine: shared.Static, Serve: "dist"}); err != nil {
+		t.Fatalf("failed to add micro: %v", err)
+	}
+
+	if err := s.Save(dir); err != nil {
+		t.Fatalf("failed to save spacefile: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, SpacefileName))
+	if err != nil {
+		t.Fatalf("failed to read spacefile: %v", err)
+	}
+
+	if strings.Count(string(content), spacefileDocsComment) != 1 {
+		t.Fatalf("expected the docs header once, got:\n%s", content)
+	}
+
+	// the paths computed while loading must not be written back
+	if strings.Contains(string(content), "path:") {
+		t.Fatalf("expected no path to be written, got:\n%s", content)
+	}
+
+	for _, expected := range []string{"# the backend", "# keep me", "*presets", "- name: frontend"} {
+		if !strings.Contains(string(content), expected) {
+			t.Fatalf("expected spacefile to contain %q, got:\n%s", expected, content)
+		}
+	}
+
+	if _, err := LoadSpacefile(dir); err != nil {
+		t.Fatalf("failed to load saved spacefile: %v", err)
+	}
+}
+
+func TestCreateSpacefileWithMicros(t *testing.T) {
+	dir := t.TempDir()
+	micros := []*shared.Micro{{Name: "api", Src: ".", Engine: shared.Python39}}
+
+	if _, err := CreateSpacefileWithMicros(dir, micros); err != nil {
+		t.Fatalf("failed to create spacefile: %v", err)
+	}
+
+	s, err := LoadSpacefile(dir)
+	if err != nil {
+		t.Fatalf("failed to load spacefile: %v", err)
+	}
+
+	if len(s.Micros) != 1 || !s.Micros[0].Primary {
+		t.Fatalf("expected a single primary micro")
+	}
+}
+
+func TestDocumentSet(t *testing.T) {
+	doc, err := ParseDocument([]byte(commentedSpacefile))
+	if err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+
+	for path, value := range map[string]string{
+		"micros.api.presets.env.API_URL.default": "https://example.com",
+		"micros.api.presets.env.PORT.default":    "8080",
+		"micros.api.public":                      "true",
+		"micros.api.actions.cleanup.trigger":     "schedule",
+	} {
+		if err := doc.Set(path, value); err != nil {
+			t.Fatalf("failed to set %s: %v", path, err)
+		}
+	}
+
+	content, err := doc.Bytes()
+	if err != nil {
+		t.Fatalf("failed to encode document: %v", err)
+	}
+
+	for _, expected := range []string{
+		`default: "https://example.com"`,
+		"- name: PORT\n          default: \"8080\"",
+		"public: true",
+		"- id: cleanup\n        trigger: schedule",
+		"engine: python3.9 # keep me",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Fatalf("expected document to contain %q, got:\n%s", expected, content)
+		}
+	}
+
+	if err := doc.Set("micros.api.engine.name", "python3.9"); !errors.Is(err, ErrInvalidPath) {
+		t.Fatalf("expected an invalid path error, got %v", err)
+	}
+}
+
+func TestDocumentValidate(t *testing.T) {
+	doc, err := ParseDocument([]byte(commentedSpacefile))
+	if err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := doc.Validate(dir); err != nil {
+		t.Fatalf("expected document to be valid, got %v", err)
+	}
+
+	if err := doc.Set("micros.api.engine", "pyton"); err != nil {
+		t.Fatalf("failed to set engine: %v", err)
+	}
+
+	diagnostics := Diagnostics(doc.Validate(dir))
+	if len(diagnostics) != 1 || diagnostics[0].Rule != "schema/enum" || diagnostics[0].Line != 7 {
+		t.Fatalf("expected an enum diagnostic on line 7, got %v", diagnostics)
+	}
+}
+
+func TestDocumentSetThroughAlias(t *testing.T) {
+	doc, err := ParseDocument([]byte(commentedSpacefile))
+	if err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+
+	if err := doc.Set("micros.worker.presets.env.API_URL.default", "https://worker.example.com"); err != nil {
+		t.Fatalf("failed to set the default of the worker: %v", err)
+	}
+
+	content, err := doc.Bytes()
+	if err != nil {
+		t.Fatalf("failed to encode document: %v", err)
+	}
+
+	var spacefile Spacefile
+	if err := yaml.Unmarshal(content, &spacefile); err != nil {
+		t.Fatalf("failed to parse edited document: %v\n%s", err, content)
+	}
+
+	defaults := map[string]string{}
+	for _, micro := range spacefile.Micros {
+		defaults[micro.Name] = micro.Presets.Env[0].Default
+	}
+	if defaults["api"] != "http://localhost" || defaults["worker"] != "https://worker.example.com" {
+		t.Fatalf("expected only the worker to change, got %v in:\n%s", defaults, content)
+	}
+
+	if !strings.Contains(string(content), "presets: &presets") {
+		t.Fatalf("expected the anchor to be kept, got:\n%s", content)
+	}
+}
+
+func TestDocumentSetUnknownMicro(t *testing.T) {
+	doc, err := ParseDocument([]byte(commentedSpacefile))
+	if err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+
+	if err := doc.Set("micros.apo.public", "true"); !errors.Is(err, ErrMicroNotFound) {
+		t.Fatalf("expected a micro not found error, got %v", err)
+	}
+
+	if names := doc.MicroNames(); len(names) != 2 {
+		t.Fatalf("expected no micro to be created, got %v", names)
+	}
+}
+
+func TestAddMicroInvalidName(t *testing.T) {
+	doc, err := ParseDocument([]byte(commentedSpacefile))
+	if err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+
+	for _, name := range []string{"-api", "my api", "api.v2", ""} {
+		if err := doc.AddMicro(&shared.Micro{Name: name, Src: ".", Engine: "python3.9"}); !errors.Is(err, ErrInvalidName) {
+			t.Fatalf("expected an invalid name error for %q, got %v", name, err)
+		}
+	}
+
+	if err := doc.AddMicro(&shared.Micro{Name: "worker", Src: ".", Engine: "python3.9"}); !errors.Is(err, ErrMicroExists) {
+		t.Fatalf("expected a micro exists error, got %v", err)
+	}
+}
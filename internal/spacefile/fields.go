@@ -0,0 +1,131 @@
+package spacefile
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/deta/space/shared"
+	"gopkg.in/yaml.v3"
+)
+
+// Field is the field of a Spacefile found at a position of its contents
+type Field struct {
+	// Path of the field, items of lists are named by their name or id, or by their index
+	// if they have none, e.g. ["micros", "api", "presets", "env", "API_URL", "default"]
+	Path []string
+	// Node is the scalar at the position, either the key or the value of the field
+	Node *yaml.Node
+	// IsKey is set if the position is on the key of the field
+	IsKey bool
+}
+
+// FieldAt finds the field at a position of the contents of a Spacefile, line and column start at 1
+func FieldAt(content []byte, line int, column int) (*Field, bool) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil || len(root.Content) == 0 {
+		return nil, false
+	}
+
+	return fieldAt(root.Content[0], nil, line, column)
+}
+
+func fieldAt(node *yaml.Node, path []string, line int, column int) (*Field, bool) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			fieldPath := append(append([]string{}, path...), key.Value)
+			if contains(key, line, column) {
+				return &Field{Path: fieldPath, Node: key, IsKey: true}, true
+			}
+			if field, ok := fieldAt(value, fieldPath, line, column); ok {
+				return field, true
+			}
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			if field, ok := fieldAt(item, append(append([]string{}, path...), itemName(item, i)), line, column); ok {
+				return field, true
+			}
+		}
+	case yaml.ScalarNode:
+		if contains(node, line, column) {
+			return &Field{Path: path, Node: node}, true
+		}
+	}
+
+	return nil, false
+}
+
+// itemName names an item of a list the same way paths given to Document.Set do
+func itemName(item *yaml.Node, index int) string {
+	if item.Kind == yaml.MappingNode {
+		for _, key := range identityKeys {
+			if _, id := mappingEntry(item, key); id != nil && id.Kind == yaml.ScalarNode {
+				return id.Value
+			}
+		}
+	}
+	return strconv.Itoa(index)
+}
+
+// contains reports whether a position is within the first line of a scalar node
+func contains(node *yaml.Node, line int, column int) bool {
+	return node.Kind == yaml.ScalarNode && node.Line == line && column >= node.Column && column <= node.Column+nodeLength(node)
+}
+
+// Documentation returns the description of the field from the schema, in markdown
+func (f *Field) Documentation() string {
+	for i := len(f.Path); i > 0; i-- {
+		schema := schemaAt(f.Path[:i])
+		description, ok := schema["description"].(string)
+		if !ok {
+			// items of lists are documented by the list itself
+			continue
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "**%s**\n\n%s", f.Path[i-1], description)
+		if values, ok := schema["enum"].([]any); ok {
+			var allowed []string
+			for _, v := range values {
+				allowed = append(allowed, fmt.Sprintf("`%v`", v))
+			}
+			fmt.Fprintf(&b, "\n\nOne of: %s", strings.Join(allowed, ", "))
+		}
+		return b.String()
+	}
+
+	return ""
+}
+
+// Target resolves the file or directory a path field points to: the icon, the src of a micro or one of its include entries.
+// The second value is false if the field is not a path.
+func (f *Field) Target(projectDir string, content []byte) (string, bool) {
+	if f.IsKey {
+		return "", false
+	}
+
+	switch {
+	case len(f.Path) == 1 && f.Path[0] == "icon":
+		return filepath.Join(projectDir, f.Node.Value), true
+	case len(f.Path) == 3 && f.Path[0] == "micros" && f.Path[2] == "src":
+		return filepath.Join(projectDir, f.Node.Value), true
+	case len(f.Path) == 4 && f.Path[0] == "micros" && f.Path[2] == "include":
+		var s struct {
+			Micros []shared.Micro `yaml:"micros"`
+		}
+		if err := yaml.Unmarshal(content, &s); err != nil {
+			return "", false
+		}
+		for i, micro := range s.Micros {
+			if micro.Name == f.Path[1] || strconv.Itoa(i) == f.Path[1] {
+				return filepath.Join(projectDir, micro.Src, f.Node.Value), true
+			}
+		}
+	}
+
+	return "", false
+}
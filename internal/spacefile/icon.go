@@ -9,6 +9,8 @@ import (
 	"mime"
 	"os"
 	"path/filepath"
+
+	_ "golang.org/x/image/webp"
 )
 
 var (
@@ -44,18 +46,32 @@ type Icon struct {
 	IconMeta *IconMeta `json:"icon_meta"`
 }
 
-// ValidateSpacefileIcon validate spacefile icon
+// iconTypes are the formats icons can be converted from, besides svg
+var iconTypes = map[string]struct{}{
+	"image/png":  {},
+	"image/jpeg": {},
+	"image/gif":  {},
+	"image/webp": {},
+}
+
+// ValidateIcon checks that an icon can be converted to a 512x512 PNG with ConvertIcon.
+// Raster icons have to be at least 512 pixels wide and high, so that they are not upscaled.
 func ValidateIcon(iconPath string) error {
+	if isSVG(iconPath) {
+		_, err := ConvertIcon(iconPath)
+		return err
+	}
+
 	iconMeta, err := getIconMeta(iconPath)
 	if err != nil {
 		return err
 	}
 
-	if iconMeta.ContentType != "image/png" && iconMeta.ContentType != "image/webp" {
+	if _, ok := iconTypes[iconMeta.ContentType]; !ok {
 		return ErrInvalidIconType
 	}
 
-	if iconMeta.Height != MaxIconHeight && iconMeta.Width != MaxIconWidth {
+	if iconMeta.Height < MaxIconHeight || iconMeta.Width < MaxIconWidth {
 		return ErrInvalidIconSize
 	}
 
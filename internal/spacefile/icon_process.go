@@ -0,0 +1,213 @@
+package spacefile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// ConvertIcon reads a PNG, JPEG, GIF, WebP or SVG icon and processes it with ProcessIcon
+func ConvertIcon(iconPath string) (*Icon, error) {
+	f, err := os.Open(iconPath)
+	if err != nil {
+		return nil, ErrInvalidIconPath
+	}
+	defer f.Close()
+
+	var img image.Image
+	if isSVG(iconPath) {
+		img, err = rasterizeSVG(f)
+	} else {
+		img, _, err = image.Decode(f)
+		if err != nil {
+			err = fmt.Errorf("%w, %v", ErrInvalidIconType, err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ProcessIcon(img)
+}
+
+// ProcessIcon center-crops an image to a square, resizes it to 512x512 and encodes it to PNG
+func ProcessIcon(img image.Image) (*Icon, error) {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	if side == 0 {
+		return nil, ErrInvalidIconSize
+	}
+
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	crop := image.Rect(x, y, x+side, y+side)
+
+	dst := image.NewRGBA(image.Rect(0, 0, MaxIconWidth, MaxIconHeight))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Src, nil)
+
+	return encodeIcon(dst)
+}
+
+// GenerateIcon generates a placeholder icon with the initials of the app name over a gradient picked from the name,
+// the same name always gives the same icon
+func GenerateIcon(appName string) (*Icon, error) {
+	sum := sha256.Sum256([]byte(appName))
+	hue := float64(binary.BigEndian.Uint16(sum[:2])) / (1 << 16) * 360
+	top, bottom := hslColor(hue, 0.65, 0.55), hslColor(hue+40, 0.65, 0.38)
+
+	img := image.NewRGBA(image.Rect(0, 0, MaxIconWidth, MaxIconHeight))
+	for y := 0; y < MaxIconHeight; y++ {
+		t := float64(y) / float64(MaxIconHeight-1)
+		row := color.RGBA{
+			R: mix(top.R, bottom.R, t),
+			G: mix(top.G, bottom.G, t),
+			B: mix(top.B, bottom.B, t),
+			A: 0xff,
+		}
+		draw.Draw(img, image.Rect(0, y, MaxIconWidth, y+1), image.NewUniform(row), image.Point{}, draw.Src)
+	}
+
+	if text := initials(appName); text != "" {
+		if err := drawCentered(img, text); err != nil {
+			return nil, err
+		}
+	}
+
+	return encodeIcon(img)
+}
+
+func encodeIcon(img image.Image) (*Icon, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode icon, %w", err)
+	}
+
+	bounds := img.Bounds()
+	return &Icon{
+		Raw: buf.Bytes(),
+		IconMeta: &IconMeta{
+			ContentType: mime.TypeByExtension(".png"),
+			Width:       bounds.Dx(),
+			Height:      bounds.Dy(),
+		},
+	}, nil
+}
+
+func isSVG(iconPath string) bool {
+	return strings.EqualFold(filepath.Ext(iconPath), ".svg")
+}
+
+// rasterizeSVG draws an svg so that it covers a 512x512 image, the overflow is cropped equally on both sides
+func rasterizeSVG(r io.Reader) (image.Image, error) {
+	icon, err := oksvg.ReadIconStream(r, oksvg.WarnErrorMode)
+	if err != nil {
+		return nil, fmt.Errorf("%w, %v", ErrInvalidIconType, err)
+	}
+
+	w, h := icon.ViewBox.W, icon.ViewBox.H
+	if w <= 0 || h <= 0 {
+		return nil, ErrInvalidIconSize
+	}
+
+	side := float64(MaxIconWidth)
+	scale := math.Max(side/w, side/h)
+	icon.SetTarget((side-w*scale)/2, (side-h*scale)/2, w*scale, h*scale)
+
+	img := image.NewRGBA(image.Rect(0, 0, MaxIconWidth, MaxIconHeight))
+	scanner := rasterx.NewScannerGV(MaxIconWidth, MaxIconHeight, img, img.Bounds())
+	icon.Draw(rasterx.NewDasher(MaxIconWidth, MaxIconHeight, scanner), 1)
+
+	return img, nil
+}
+
+// initials takes the first letter of the first two words of a name
+func initials(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var result []rune
+	for _, word := range words {
+		if len(result) == 2 {
+			break
+		}
+		result = append(result, unicode.ToUpper([]rune(word)[0]))
+	}
+
+	return string(result)
+}
+
+// drawCentered draws white text in the middle of the image
+func drawCentered(img *image.RGBA, text string) error {
+	f, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return fmt.Errorf("failed to parse font, %w", err)
+	}
+
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: 220, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return fmt.Errorf("failed to load font, %w", err)
+	}
+	defer face.Close()
+
+	bounds, advance := font.BoundString(face, text)
+	x := (fixed.I(MaxIconWidth) - advance) / 2
+	y := (fixed.I(MaxIconHeight)-(bounds.Max.Y-bounds.Min.Y))/2 - bounds.Min.Y
+
+	drawer := font.Drawer{Dst: img, Src: image.White, Face: face, Dot: fixed.Point26_6{X: x, Y: y}}
+	drawer.DrawString(text)
+
+	return nil
+}
+
+// hslColor converts a hue in degrees, a saturation and a lightness to rgb
+func hslColor(hue, saturation, lightness float64) color.RGBA {
+	hue = math.Mod(hue, 360) / 60
+	chroma := (1 - math.Abs(2*lightness-1)) * saturation
+	x := chroma * (1 - math.Abs(math.Mod(hue, 2)-1))
+
+	var r, g, b float64
+	switch int(hue) {
+	case 0:
+		r, g = chroma, x
+	case 1:
+		r, g = x, chroma
+	case 2:
+		g, b = chroma, x
+	case 3:
+		g, b = x, chroma
+	case 4:
+		r, b = x, chroma
+	default:
+		r, b = chroma, x
+	}
+
+	m := lightness - chroma/2
+	return color.RGBA{R: uint8((r + m) * 255), G: uint8((g + m) * 255), B: uint8((b + m) * 255), A: 0xff}
+}
+
+func mix(a, b uint8, t float64) uint8 {
+	return uint8(float64(a) + (float64(b)-float64(a))*t)
+}
@@ -1,7 +1,14 @@
 package spacefile
 
 import (
+	"bytes"
 	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -20,3 +27,111 @@ func TestIconInvalidSize(t *testing.T) {
 		t.Fatalf("expected error %v but got %v", ErrInvalidIconPath, err)
 	}
 }
+
+func decodeIcon(t *testing.T, icon *Icon) image.Image {
+	t.Helper()
+
+	if icon.IconMeta.ContentType != "image/png" || icon.IconMeta.Width != MaxIconWidth || icon.IconMeta.Height != MaxIconHeight {
+		t.Fatalf("expected a %dx%d png, got %+v", MaxIconWidth, MaxIconHeight, icon.IconMeta)
+	}
+
+	img, err := png.Decode(bytes.NewReader(icon.Raw))
+	if err != nil {
+		t.Fatalf("failed to decode icon: %v", err)
+	}
+	if img.Bounds().Dx() != MaxIconWidth || img.Bounds().Dy() != MaxIconHeight {
+		t.Fatalf("expected icon to be resized, got %v", img.Bounds())
+	}
+	return img
+}
+
+func TestConvertIcon(t *testing.T) {
+	// a wide image, red on the sides and blue in the center square
+	src := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	for x := 0; x < 300; x++ {
+		for y := 0; y < 100; y++ {
+			c := color.RGBA{R: 0xff, A: 0xff}
+			if x >= 100 && x < 200 {
+				c = color.RGBA{B: 0xff, A: 0xff}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	iconPath := filepath.Join(t.TempDir(), "wide.jpg")
+	f, err := os.Create(iconPath)
+	if err != nil {
+		t.Fatalf("failed to create icon: %v", err)
+	}
+	if err := jpeg.Encode(f, src, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatalf("failed to encode icon: %v", err)
+	}
+	f.Close()
+
+	icon, err := ConvertIcon(iconPath)
+	if err != nil {
+		t.Fatalf("failed to convert icon: %v", err)
+	}
+
+	img := decodeIcon(t, icon)
+	for _, p := range []image.Point{{10, 10}, {256, 256}, {500, 500}} {
+		r, _, b, _ := img.At(p.X, p.Y).RGBA()
+		if r > 0x2000 || b < 0xe000 {
+			t.Errorf("expected the center of the image to be kept at %v, got %v", p, img.At(p.X, p.Y))
+		}
+	}
+
+	if _, err := ConvertIcon("./testdata/icons/size-128.png"); err != nil {
+		t.Errorf("expected small icons to be upscaled, got %v", err)
+	}
+}
+
+func TestConvertSVGIcon(t *testing.T) {
+	iconPath := filepath.Join(t.TempDir(), "icon.svg")
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 20 10"><rect x="5" y="0" width="10" height="10" fill="#00ff00"/></svg>`
+	if err := os.WriteFile(iconPath, []byte(svg), 0644); err != nil {
+		t.Fatalf("failed to write icon: %v", err)
+	}
+
+	if err := ValidateIcon(iconPath); err != nil {
+		t.Fatalf("expected svg icon to be valid, got %v", err)
+	}
+
+	icon, err := ConvertIcon(iconPath)
+	if err != nil {
+		t.Fatalf("failed to convert icon: %v", err)
+	}
+
+	img := decodeIcon(t, icon)
+	if _, g, _, a := img.At(256, 256).RGBA(); g < 0xe000 || a < 0xe000 {
+		t.Errorf("expected the rect to cover the icon, got %v", img.At(256, 256))
+	}
+}
+
+func TestGenerateIcon(t *testing.T) {
+	first, err := GenerateIcon("Todo App")
+	if err != nil {
+		t.Fatalf("failed to generate icon: %v", err)
+	}
+	decodeIcon(t, first)
+
+	second, err := GenerateIcon("Todo App")
+	if err != nil {
+		t.Fatalf("failed to generate icon: %v", err)
+	}
+	if !bytes.Equal(first.Raw, second.Raw) {
+		t.Errorf("expected the same name to generate the same icon")
+	}
+
+	other, err := GenerateIcon("Notes")
+	if err != nil {
+		t.Fatalf("failed to generate icon: %v", err)
+	}
+	if bytes.Equal(first.Raw, other.Raw) {
+		t.Errorf("expected different names to generate different icons")
+	}
+
+	if initials("todo app") != "TA" || initials("notes") != "N" || initials("--") != "" {
+		t.Errorf("unexpected initials %q %q %q", initials("todo app"), initials("notes"), initials("--"))
+	}
+}
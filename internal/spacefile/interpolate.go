@@ -0,0 +1,150 @@
+package spacefile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+	"mvdan.cc/sh/v3/shell"
+)
+
+// EnvFileName is the file variables are read from, on top of the process env
+const EnvFileName = ".env"
+
+var ErrSaveInterpolated = errors.New("cannot save a Spacefile with interpolated variables")
+
+// Lookup returns the value of a variable and whether it is set
+type Lookup func(name string) (string, bool)
+
+// EnvLookup looks variables up in the process env first, then in the .env file of the project
+func EnvLookup(projectDir string) (Lookup, error) {
+	dotenv := make(map[string]string)
+
+	envPath := filepath.Join(projectDir, EnvFileName)
+	if _, err := os.Stat(envPath); err == nil {
+		if dotenv, err = godotenv.Read(envPath); err != nil {
+			return nil, fmt.Errorf("failed to read %s, %w", EnvFileName, err)
+		}
+	}
+
+	return func(name string) (string, bool) {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, true
+		}
+		value, ok := dotenv[name]
+		return value, ok
+	}, nil
+}
+
+// interpolationReg matches ${VAR} and ${VAR:-default}, plain $VAR is left alone
+// as dev commands use it for variables set when the micro starts, such as $PORT
+var interpolationReg = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:?-[^}]*)?\}`)
+
+// interpolate expands the variables of a value with the same expansion as the shell of MicroCommand.
+// Variables that are not set and have no default are kept as is, so that they can still be set when the micro starts.
+func interpolate(value string, lookup Lookup) (string, error) {
+	var expandErr error
+	expanded := interpolationReg.ReplaceAllStringFunc(value, func(match string) string {
+		groups := interpolationReg.FindStringSubmatch(match)
+		if _, ok := lookup(groups[1]); !ok && groups[2] == "" {
+			return match
+		}
+
+		result, err := shell.Expand(match, func(name string) string {
+			value, _ := lookup(name)
+			return value
+		})
+		if err != nil {
+			expandErr = err
+			return match
+		}
+		return result
+	})
+	if expandErr != nil {
+		return "", fmt.Errorf("failed to interpolate %s, %w", value, expandErr)
+	}
+
+	return expanded, nil
+}
+
+// Interpolate expands ${VAR} and ${VAR:-default} in the dev, run, commands and env defaults of the micros.
+// A Spacefile with interpolated values cannot be saved, as it could leak them.
+func (s *Spacefile) Interpolate(lookup Lookup) error {
+	var err error
+	for _, micro := range s.Micros {
+		if micro.Dev, err = interpolate(micro.Dev, lookup); err != nil {
+			return err
+		}
+		if micro.Run, err = interpolate(micro.Run, lookup); err != nil {
+			return err
+		}
+		for i := range micro.Commands {
+			if micro.Commands[i], err = interpolate(micro.Commands[i], lookup); err != nil {
+				return err
+			}
+		}
+		if micro.Presets != nil {
+			for i := range micro.Presets.Env {
+				if micro.Presets.Env[i].Default, err = interpolate(micro.Presets.Env[i].Default, lookup); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	s.interpolated = true
+	return nil
+}
+
+// InterpolateContent expands variables in the raw contents of a Spacefile, in the same fields as Spacefile.Interpolate.
+// Comments and formatting are kept.
+func InterpolateContent(content []byte, lookup Lookup) ([]byte, error) {
+	doc, err := ParseDocument(content)
+	if err != nil {
+		return nil, err
+	}
+
+	micros := doc.micros(false)
+	if micros == nil {
+		return content, nil
+	}
+
+	var scalars []*yaml.Node
+	for _, micro := range micros.Content {
+		micro = resolveAlias(micro)
+		for _, field := range []string{"dev", "run"} {
+			if _, value := mappingEntry(micro, field); value != nil {
+				scalars = append(scalars, value)
+			}
+		}
+		if _, commands := mappingEntry(micro, "commands"); commands != nil {
+			scalars = append(scalars, resolveAlias(commands).Content...)
+		}
+		if _, presets := mappingEntry(micro, "presets"); presets != nil {
+			if _, env := mappingEntry(resolveAlias(presets), "env"); env != nil {
+				for _, item := range resolveAlias(env).Content {
+					if _, value := mappingEntry(resolveAlias(item), "default"); value != nil {
+						scalars = append(scalars, value)
+					}
+				}
+			}
+		}
+	}
+
+	for _, node := range scalars {
+		node = resolveAlias(node)
+		if node.Kind != yaml.ScalarNode || !strings.Contains(node.Value, "${") {
+			continue
+		}
+		if node.Value, err = interpolate(node.Value, lookup); err != nil {
+			return nil, err
+		}
+	}
+
+	return doc.Bytes()
+}
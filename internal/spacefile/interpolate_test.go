@@ -0,0 +1,110 @@
+package spacefile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	env := map[string]string{"NAME": "api", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	cases := []struct {
+		value    string
+		expected string
+	}{
+		{value: "serve ${NAME}", expected: "serve api"},
+		{value: "serve ${MISSING:-default}", expected: "serve default"},
+		{value: "serve ${NAME:-default}", expected: "serve api"},
+		{value: "serve ${EMPTY:-default}", expected: "serve default"},
+		{value: "serve ${MISSING}", expected: "serve ${MISSING}"},
+		{value: "serve --port $PORT", expected: "serve --port $PORT"},
+		{value: "serve $NAME", expected: "serve $NAME"},
+	}
+
+	for _, c := range cases {
+		result, err := interpolate(c.value, lookup)
+		if err != nil {
+			t.Errorf("failed to interpolate %s: %v", c.value, err)
+			continue
+		}
+		if result != c.expected {
+			t.Errorf("expected %s to be interpolated to %s, got %s", c.value, c.expected, result)
+		}
+	}
+}
+
+func TestSpacefileInterpolate(t *testing.T) {
+	projectDir := "testdata/spacefile/interpolate"
+	t.Setenv("LOG_LEVEL", "debug")
+
+	lookup, err := EnvLookup(projectDir)
+	if err != nil {
+		t.Fatalf("failed to read env: %v", err)
+	}
+
+	s, err := LoadSpacefile(projectDir)
+	if err != nil {
+		t.Fatalf("failed to load spacefile: %v", err)
+	}
+
+	if err := s.Interpolate(lookup); err != nil {
+		t.Fatalf("failed to interpolate spacefile: %v", err)
+	}
+
+	micro := s.Micros[0]
+	// the process env takes precedence over the .env file
+	if micro.Dev != "uvicorn main:app --port $PORT --log-level debug" {
+		t.Errorf("unexpected dev command %s", micro.Dev)
+	}
+	if micro.Run != "uvicorn main:app --root-path ${ROOT_PATH}" {
+		t.Errorf("expected unset variables to be kept, got %s", micro.Run)
+	}
+	if micro.Commands[0] != "pip install -r requirements.txt" {
+		t.Errorf("expected the default to be used, got %s", micro.Commands[0])
+	}
+	if micro.Presets.Env[0].Default != "https://example.com/v1" {
+		t.Errorf("expected the env default to be read from .env, got %s", micro.Presets.Env[0].Default)
+	}
+
+	if err := s.Save(t.TempDir()); !errors.Is(err, ErrSaveInterpolated) {
+		t.Errorf("expected saving an interpolated spacefile to fail, got %v", err)
+	}
+}
+
+func TestInterpolateContent(t *testing.T) {
+	projectDir := "testdata/spacefile/interpolate"
+
+	lookup, err := EnvLookup(projectDir)
+	if err != nil {
+		t.Fatalf("failed to read env: %v", err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(projectDir, SpacefileName))
+	if err != nil {
+		t.Fatalf("failed to read spacefile: %v", err)
+	}
+
+	content, err := InterpolateContent(raw, lookup)
+	if err != nil {
+		t.Fatalf("failed to interpolate content: %v", err)
+	}
+
+	result := string(content)
+	for _, expected := range []string{
+		"# the port is set by space dev",
+		"--log-level warning",
+		`default: "https://example.com/v1"`,
+		"--root-path ${ROOT_PATH}",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("expected interpolated content to contain %s, got:\n%s", expected, result)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package spacefile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LintRule is a check run on a valid Spacefile, on top of the schema and the checks done while loading
+type LintRule struct {
+	// ID is used to refer to the rule in diagnostics and disable comments
+	ID          string
+	Description string
+	// Severity of the problems reported by the rule, unless the rule reports them with another one
+	Severity Severity
+	Check    func(ctx *LintContext)
+}
+
+// LintContext is passed to the check of a rule, problems are reported through it
+type LintContext struct {
+	ProjectDir string
+	Spacefile  *Spacefile
+
+	rule        LintRule
+	diagnostics []Diagnostic
+}
+
+// Report reports a problem at the node of the given json pointer, with the severity of the rule
+func (ctx *LintContext) Report(pointer string, message string) {
+	ctx.ReportSeverity(ctx.rule.Severity, pointer, message)
+}
+
+// ReportSeverity reports a problem at the node of the given json pointer
+func (ctx *LintContext) ReportSeverity(severity Severity, pointer string, message string) {
+	d := diagnosticAt(ctx.Spacefile.node, ctx.Spacefile.path, pointer, false)
+	d.Severity = severity
+	d.Rule = ctx.rule.ID
+	d.Message = message
+	ctx.diagnostics = append(ctx.diagnostics, d)
+}
+
+// Has reports whether the Spacefile has a node at the given json pointer
+func (ctx *LintContext) Has(pointer string) bool {
+	node := ctx.Spacefile.node
+	if node != nil && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		node = resolveAlias(node)
+		if node == nil {
+			return false
+		}
+
+		switch node.Kind {
+		case yaml.MappingNode:
+			_, node = mappingEntry(node, token)
+		case yaml.SequenceNode:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node.Content) {
+				return false
+			}
+			node = node.Content[i]
+		default:
+			return false
+		}
+	}
+
+	return node != nil
+}
+
+var lintRules []LintRule
+
+// RegisterLintRule adds a rule to the ones run by Lint
+func RegisterLintRule(rule LintRule) {
+	lintRules = append(lintRules, rule)
+}
+
+// LintRules lists the registered rules
+func LintRules() []LintRule {
+	rules := make([]LintRule, len(lintRules))
+	copy(rules, lintRules)
+	return rules
+}
+
+// Lint loads the Spacefile in projectDir and runs every check on it, the lint rules included.
+// Problems are returned as diagnostics, the error is only set if the checks could not run.
+func Lint(projectDir string) ([]Diagnostic, error) {
+	spacefilePath := filepath.Join(projectDir, SpacefileName)
+	content, err := os.ReadFile(spacefilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrSpacefileNotFound
+		}
+		return nil, fmt.Errorf("failed to read, %w", err)
+	}
+
+	return LintContent(projectDir, spacefilePath, content)
+}
+
+// LintContent runs every check on the contents of a Spacefile, which do not have to be saved yet
+func LintContent(projectDir string, spacefilePath string, content []byte) ([]Diagnostic, error) {
+	diagnostics, err := lint(projectDir, spacefilePath, content)
+	if err != nil {
+		return nil, err
+	}
+
+	SortDiagnostics(diagnostics)
+	return diagnostics, nil
+}
+
+// lint checks the raw contents of a Spacefile
+func lint(projectDir string, spacefilePath string, content []byte) ([]Diagnostic, error) {
+	s, err := parseSpacefile(projectDir, spacefilePath, content)
+	if err != nil {
+		if diagnostics := Diagnostics(err); diagnostics != nil {
+			return diagnostics, nil
+		}
+		return nil, err
+	}
+
+	directives := parseLintDirectives(content)
+
+	diagnostics := append(s.iconDiagnostics(projectDir), s.Migrations()...)
+	for _, rule := range lintRules {
+		ctx := &LintContext{ProjectDir: projectDir, Spacefile: s, rule: rule}
+		rule.Check(ctx)
+
+		for _, d := range ctx.diagnostics {
+			if !directives.disabled(d.Rule, d.Line) {
+				diagnostics = append(diagnostics, d)
+			}
+		}
+	}
+
+	return diagnostics, nil
+}
+
+// lintDirectiveReg matches the comments used to disable rules:
+//
+//	# space-lint-disable rule-a, rule-b            disables the rules for the whole file
+//	# space-lint-disable-next-line rule-a          disables the rules for the next line
+//	run: ./main # space-lint-disable-line rule-a   disables the rules for the current line
+//
+// Rules are optional, a directive without rules disables all of them. A reason can follow after "--".
+var lintDirectiveReg = regexp.MustCompile(`#\s*space-lint-disable(-next-line|-line)?(?:\s+(.*))?$`)
+
+// allRules is the key used by directives that do not list any rule
+const allRules = "*"
+
+type lintDirectives struct {
+	file  map[string]struct{}
+	lines map[int]map[string]struct{}
+}
+
+func parseLintDirectives(content []byte) *lintDirectives {
+	directives := &lintDirectives{file: make(map[string]struct{}), lines: make(map[int]map[string]struct{})}
+
+	for i, line := range strings.Split(string(content), "\n") {
+		matches := lintDirectiveReg.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if matches == nil {
+			continue
+		}
+
+		// anything after -- is a free form reason
+		list, _, _ := strings.Cut(matches[2], "--")
+		rules := strings.FieldsFunc(list, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(rules) == 0 {
+			rules = []string{allRules}
+		}
+
+		target := directives.file
+		switch matches[1] {
+		case "-line":
+			target = directives.line(i + 1)
+		case "-next-line":
+			target = directives.line(i + 2)
+		}
+		for _, rule := range rules {
+			target[rule] = struct{}{}
+		}
+	}
+
+	return directives
+}
+
+func (d *lintDirectives) line(line int) map[string]struct{} {
+	if _, ok := d.lines[line]; !ok {
+		d.lines[line] = make(map[string]struct{})
+	}
+	return d.lines[line]
+}
+
+func (d *lintDirectives) disabled(rule string, line int) bool {
+	for _, rules := range []map[string]struct{}{d.file, d.lines[line]} {
+		if _, ok := rules[rule]; ok {
+			return true
+		}
+		if _, ok := rules[allRules]; ok {
+			return true
+		}
+	}
+	return false
+}
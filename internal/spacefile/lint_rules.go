@@ -0,0 +1,237 @@
+package spacefile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/deta/space/shared"
+)
+
+// ids of the builtin lint rules
+const (
+	RulePathCollision          = "path-collision"
+	RuleMissingInclude         = "missing-include"
+	RuleUnreachablePublicRoute = "unreachable-public-route"
+	RuleUnusedEnv              = "unused-env"
+	RuleIgnoredRun             = "ignored-run"
+)
+
+func init() {
+	RegisterLintRule(LintRule{
+		ID:          RulePathCollision,
+		Description: "micros must not share the first segment of their path",
+		Severity:    SeverityError,
+		Check:       checkPathCollision,
+	})
+	RegisterLintRule(LintRule{
+		ID:          RuleMissingInclude,
+		Description: "include entries must exist, or be created by the commands of the micro",
+		Severity:    SeverityError,
+		Check:       checkMissingInclude,
+	})
+	RegisterLintRule(LintRule{
+		ID:          RuleUnreachablePublicRoute,
+		Description: "public routes must be able to match a request",
+		Severity:    SeverityWarning,
+		Check:       checkUnreachablePublicRoutes,
+	})
+	RegisterLintRule(LintRule{
+		ID:          RuleUnusedEnv,
+		Description: "env presets should be used by the source of the micro",
+		Severity:    SeverityWarning,
+		Check:       checkUnusedEnv,
+	})
+	RegisterLintRule(LintRule{
+		ID:          RuleIgnoredRun,
+		Description: "run is only used by the custom, nodejs and python engines",
+		Severity:    SeverityWarning,
+		Check:       checkIgnoredRun,
+	})
+}
+
+// pathPrefix is the first segment of a micro path, which is what the dev proxy and Space route on
+func pathPrefix(path string) string {
+	parts := strings.Split(path, "/")
+	if len(parts) > 1 {
+		return "/" + parts[1]
+	}
+
+	return "/"
+}
+
+func checkPathCollision(ctx *LintContext) {
+	owners := make(map[string]string)
+	for i, micro := range ctx.Spacefile.Micros {
+		prefix := pathPrefix(micro.Path)
+		owner, ok := owners[prefix]
+		if !ok {
+			owners[prefix] = micro.Name
+			continue
+		}
+
+		pointer := fmt.Sprintf("/micros/%d/path", i)
+		if !ctx.Has(pointer) {
+			pointer = fmt.Sprintf("/micros/%d/name", i)
+		}
+		ctx.Report(pointer, fmt.Sprintf("path %s collides with micro `%s` on %s, requests can only be routed to one of them", micro.Path, owner, prefix))
+	}
+}
+
+func checkMissingInclude(ctx *LintContext) {
+	for i, micro := range ctx.Spacefile.Micros {
+		for j, include := range micro.Include {
+			matches, err := filepath.Glob(filepath.Join(ctx.ProjectDir, micro.Src, include))
+			if err == nil && len(matches) > 0 {
+				continue
+			}
+
+			pointer := fmt.Sprintf("/micros/%d/include/%d", i, j)
+			if len(micro.Commands) > 0 {
+				ctx.ReportSeverity(SeverityWarning, pointer, fmt.Sprintf("include %s does not exist yet, make sure the commands create it", include))
+				continue
+			}
+			ctx.Report(pointer, fmt.Sprintf("include %s does not exist", include))
+		}
+	}
+}
+
+func checkUnreachablePublicRoutes(ctx *LintContext) {
+	for i, micro := range ctx.Spacefile.Micros {
+		for j, route := range micro.PublicRoutes {
+			pointer := fmt.Sprintf("/micros/%d/public_routes/%d", i, j)
+
+			if !strings.HasPrefix(route, "/") {
+				ctx.Report(pointer, fmt.Sprintf("public route %s never matches, routes must start with /", route))
+				continue
+			}
+
+			if k := strings.Index(route, "*"); k >= 0 && k != len(route)-1 {
+				ctx.Report(pointer, fmt.Sprintf("public route %s never matches, * is only supported at the end of a route", route))
+				continue
+			}
+
+			for k, other := range micro.PublicRoutes {
+				if k != j && coversRoute(other, route) {
+					ctx.Report(pointer, fmt.Sprintf("public route %s is already covered by %s", route, other))
+					break
+				}
+			}
+		}
+	}
+}
+
+// coversRoute reports whether the wildcard route matches every request matched by route
+func coversRoute(wildcard string, route string) bool {
+	if !strings.HasSuffix(wildcard, "*") || wildcard == route {
+		return false
+	}
+
+	return strings.HasPrefix(route, strings.TrimSuffix(wildcard, "*"))
+}
+
+// skippedEnvDirs are not searched for env presets, they hold dependencies or generated files
+var skippedEnvDirs = map[string]struct{}{
+	".git":         {},
+	".space":       {},
+	"node_modules": {},
+	"__pycache__":  {},
+	".venv":        {},
+	"venv":         {},
+}
+
+// errEnvSearchDone stops the search once every env preset was found
+var errEnvSearchDone = errors.New("every env preset was found")
+
+// maxEnvSearchFileSize bounds the size of the files searched for env presets
+const maxEnvSearchFileSize = 1 << 20
+
+func checkUnusedEnv(ctx *LintContext) {
+	for i, micro := range ctx.Spacefile.Micros {
+		if micro.Presets == nil || len(micro.Presets.Env) == 0 {
+			continue
+		}
+
+		unused := make(map[string]int)
+		for j, env := range micro.Presets.Env {
+			unused[env.Name] = j
+		}
+
+		// the env can be used by the commands of the micro as well
+		for _, command := range append([]string{micro.Run, micro.Dev}, micro.Commands...) {
+			for name := range unused {
+				if strings.Contains(command, name) {
+					delete(unused, name)
+				}
+			}
+		}
+
+		filepath.WalkDir(filepath.Join(ctx.ProjectDir, micro.Src), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return filepath.SkipDir
+			}
+			if len(unused) == 0 {
+				return errEnvSearchDone
+			}
+
+			if d.IsDir() {
+				if _, ok := skippedEnvDirs[d.Name()]; ok {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			// the Spacefile itself declares the env presets
+			if filepath.Clean(path) == filepath.Clean(ctx.Spacefile.path) {
+				return nil
+			}
+
+			if info, err := d.Info(); err != nil || !info.Mode().IsRegular() || info.Size() > maxEnvSearchFileSize {
+				return nil
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+
+			for name := range unused {
+				if bytes.Contains(content, []byte(name)) {
+					delete(unused, name)
+				}
+			}
+			return nil
+		})
+
+		for name, j := range unused {
+			ctx.Report(fmt.Sprintf("/micros/%d/presets/env/%d/name", i, j), fmt.Sprintf("env %s is not used in the source of the micro", name))
+		}
+	}
+}
+
+// runEngines are the engines that start the micro with its run command
+var runEngines = map[string]struct{}{
+	shared.Custom:   {},
+	shared.Node14x:  {},
+	shared.Node16x:  {},
+	shared.Python38: {},
+	shared.Python39: {},
+}
+
+func checkIgnoredRun(ctx *LintContext) {
+	for i, micro := range ctx.Spacefile.Micros {
+		if micro.Run == "" {
+			continue
+		}
+
+		if _, ok := runEngines[shared.EngineAliases[micro.Engine]]; ok {
+			continue
+		}
+
+		ctx.Report(fmt.Sprintf("/micros/%d/run", i), fmt.Sprintf("run is ignored by the %s engine", micro.Engine))
+	}
+}
@@ -0,0 +1,63 @@
+package spacefile
+
+import (
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	diagnostics, err := Lint("testdata/spacefile/lint")
+	if err != nil {
+		t.Fatalf("failed to lint: %v", err)
+	}
+
+	expected := []struct {
+		rule     string
+		severity Severity
+		line     int
+	}{
+		{rule: RuleNoIcon, severity: SeverityInfo, line: 0},
+		{rule: RuleMissingInclude, severity: SeverityError, line: 11},
+		{rule: RuleUnreachablePublicRoute, severity: SeverityWarning, line: 14},
+		{rule: RuleUnreachablePublicRoute, severity: SeverityWarning, line: 15},
+		{rule: RuleUnreachablePublicRoute, severity: SeverityWarning, line: 16},
+		{rule: RuleUnusedEnv, severity: SeverityWarning, line: 20},
+		{rule: RulePathCollision, severity: SeverityError, line: 35},
+	}
+
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("expected %d diagnostics but got %d: %v", len(expected), len(diagnostics), diagnostics)
+	}
+
+	for i, e := range expected {
+		d := diagnostics[i]
+		if d.Rule != e.rule || d.Severity != e.severity || d.Line != e.line {
+			t.Errorf("expected %s %s on line %d but got %s %s on line %d", e.severity, e.rule, e.line, d.Severity, d.Rule, d.Line)
+		}
+	}
+}
+
+func TestLintDirectives(t *testing.T) {
+	directives := parseLintDirectives([]byte(`# space-lint-disable unused-env
+run: ./main # space-lint-disable-line ignored-run -- started by the custom script
+# space-lint-disable-next-line
+include: missing
+`))
+
+	cases := []struct {
+		rule     string
+		line     int
+		disabled bool
+	}{
+		{rule: RuleUnusedEnv, line: 10, disabled: true},
+		{rule: RuleIgnoredRun, line: 2, disabled: true},
+		{rule: RuleIgnoredRun, line: 3, disabled: false},
+		{rule: RuleMissingInclude, line: 4, disabled: true},
+		{rule: RuleMissingInclude, line: 5, disabled: false},
+	}
+
+	for _, c := range cases {
+		if directives.disabled(c.rule, c.line) != c.disabled {
+			t.Errorf("expected %s on line %d to be disabled: %v", c.rule, c.line, c.disabled)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package spacefile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/deta/space/internal/discovery"
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the latest version of the Spacefile format, older Spacefiles are migrated to it when loaded
+const CurrentVersion = 1
+
+// PushedVersion is the only version known by Space. Every version after it only removes
+// deprecated fields, so a migrated Spacefile is a valid v0 one once its version is set back.
+const PushedVersion = 0
+
+// migration upgrades a Spacefile from version from to from+1
+type migration struct {
+	from        int
+	description string
+	migrate     func(m *migrator)
+}
+
+// migrations are applied in order, there is exactly one migration for every version below CurrentVersion
+var migrations = []migration{
+	{
+		from:        0,
+		description: "replace deprecated engines and move app_name to Discovery.md",
+		migrate: func(m *migrator) {
+			migrateDeprecatedEngines(m)
+			migrateAppName(m)
+		},
+	},
+}
+
+// migrator holds a Spacefile being migrated and the changes made to it
+type migrator struct {
+	root *yaml.Node
+	path string
+
+	// from is the version of the Spacefile before the migration
+	from int
+	// applied describes the migrations applied, in order
+	applied []string
+
+	changes []Diagnostic
+	appName string
+}
+
+// change records a change made by a migration, positioned on the node at pointer.
+// It must be called before the node is changed, so that the position matches the original file.
+func (m *migrator) change(pointer string, format string, args ...any) {
+	d := diagnosticAt(m.root, m.path, pointer, false)
+	d.Severity = SeverityWarning
+	d.Rule = RuleOutdatedVersion
+	d.Message = fmt.Sprintf(format, args...)
+	m.changes = append(m.changes, d)
+}
+
+func (m *migrator) mapping() *yaml.Node {
+	return m.root.Content[0]
+}
+
+// deprecatedEngines maps engines removed from the Spacefile to their replacement
+var deprecatedEngines = map[string]string{
+	"nodejs14":   "nodejs16",
+	"nodejs14.x": "nodejs16",
+	"nodejs16.x": "nodejs16",
+	"python3.8":  "python3.9",
+}
+
+func migrateDeprecatedEngines(m *migrator) {
+	_, micros := mappingEntry(m.mapping(), "micros")
+	if micros == nil || micros.Kind != yaml.SequenceNode {
+		return
+	}
+
+	for i, micro := range micros.Content {
+		_, engine := mappingEntry(resolveAlias(micro), "engine")
+		if engine == nil || engine.Kind != yaml.ScalarNode {
+			continue
+		}
+
+		replacement, ok := deprecatedEngines[engine.Value]
+		if !ok {
+			continue
+		}
+
+		m.change(fmt.Sprintf("/micros/%d/engine", i), "engine %s is deprecated and was replaced by %s", engine.Value, replacement)
+		engine.Value = replacement
+	}
+}
+
+func migrateAppName(m *migrator) {
+	_, appName := mappingEntry(m.mapping(), "app_name")
+	if appName == nil || appName.Kind != yaml.ScalarNode {
+		return
+	}
+
+	m.change("/app_name", "app_name is deprecated in the Spacefile and was moved to %s", discovery.DiscoveryFilename)
+	removeMappingEntry(m.mapping(), "app_name")
+	m.appName = appName.Value
+}
+
+// migrate upgrades the yaml tree of a Spacefile to CurrentVersion in place. Only the changes made
+// to deprecated fields are recorded, bumping the version alone is not worth a warning.
+// Spacefiles without a valid version are left for the schema validation to report.
+func migrate(root *yaml.Node, path string) (*migrator, []Diagnostic) {
+	m := &migrator{root: root, path: path}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return m, nil
+	}
+
+	_, versionNode := mappingEntry(m.mapping(), "v")
+	if versionNode == nil || versionNode.Kind != yaml.ScalarNode || versionNode.ShortTag() != "!!int" {
+		return m, nil
+	}
+
+	version, err := strconv.Atoi(versionNode.Value)
+	if err != nil || version < 0 {
+		return m, nil
+	}
+	m.from = version
+
+	if version > CurrentVersion {
+		d := diagnosticAt(root, path, "/v", false)
+		d.Rule = RuleUnsupportedVersion
+		d.Message = fmt.Sprintf("Spacefile version %d is not supported, this version of the cli supports up to version %d, please upgrade it with `space version upgrade`", version, CurrentVersion)
+		return m, []Diagnostic{d}
+	}
+
+	for _, migration := range migrations {
+		if migration.from < version {
+			continue
+		}
+		migration.migrate(m)
+		m.applied = append(m.applied, migration.description)
+	}
+
+	versionNode.Value = strconv.Itoa(CurrentVersion)
+	return m, nil
+}
+
+// MigrationResult is the outcome of migrating the Spacefile of a project
+type MigrationResult struct {
+	// From and To are the versions before and after the migration
+	From, To int
+	// Applied describes the migrations applied to the Spacefile, in order
+	Applied []string
+	// Original and Migrated are the contents of the Spacefile before and after the migration
+	Original, Migrated []byte
+	// Changes describes every change, positioned in the original Spacefile
+	Changes []Diagnostic
+	// AppName is the app name moved out of the Spacefile, if any
+	AppName string
+}
+
+// Migrate migrates the Spacefile of a project to CurrentVersion without writing it,
+// comments and formatting are kept.
+func Migrate(projectDir string) (*MigrationResult, error) {
+	spacefilePath := filepath.Join(projectDir, SpacefileName)
+	original, err := os.ReadFile(spacefilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrSpacefileNotFound
+		}
+		return nil, fmt.Errorf("failed to read, %w", err)
+	}
+
+	// loading applies the same migrations in memory, and validates their result
+	s, err := parseSpacefile(projectDir, spacefilePath, original)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MigrationResult{From: s.from, To: CurrentVersion, Original: original, Migrated: original}
+	if s.from == CurrentVersion {
+		return result, nil
+	}
+
+	doc, err := ParseDocument(original)
+	if err != nil {
+		return nil, err
+	}
+
+	m, _ := migrate(doc.root, spacefilePath)
+	if result.Migrated, err = doc.Bytes(); err != nil {
+		return nil, err
+	}
+
+	result.Applied = m.applied
+	result.Changes = m.changes
+	result.AppName = m.appName
+	return result, nil
+}
+
+// Diff is a unified diff of the Spacefile before and after the migration
+func (r *MigrationResult) Diff() string {
+	return unifiedDiff(r.Original, r.Migrated, "a/"+SpacefileName, "b/"+SpacefileName)
+}
+
+// Migrations lists the changes made while loading the Spacefile, if it was outdated
+func (s *Spacefile) Migrations() []Diagnostic {
+	return s.migrations
+}
+
+// PushedContent sets the version of the raw content of a Spacefile back to PushedVersion, the one known by Space.
+// The content is returned as is if it is already at that version.
+func PushedContent(content []byte) ([]byte, error) {
+	doc, err := ParseDocument(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, v := mappingEntry(doc.mapping(), "v"); v == nil || v.Value == strconv.Itoa(PushedVersion) {
+		return content, nil
+	}
+
+	if err := doc.Set("v", strconv.Itoa(PushedVersion)); err != nil {
+		return nil, err
+	}
+	return doc.Bytes()
+}
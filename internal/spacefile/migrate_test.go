@@ -0,0 +1,143 @@
+package spacefile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadOutdatedSpacefile(t *testing.T) {
+	projectDir := "testdata/spacefile/migrate"
+
+	s, err := LoadSpacefile(projectDir)
+	if err != nil {
+		t.Fatalf("failed to load spacefile: %v", err)
+	}
+
+	if s.V != CurrentVersion {
+		t.Errorf("expected the spacefile to be migrated to version %d, got %d", CurrentVersion, s.V)
+	}
+	if s.Micros[0].Engine != "python3.9" || s.Micros[1].Engine != "nodejs16" {
+		t.Errorf("expected deprecated engines to be replaced, got %s and %s", s.Micros[0].Engine, s.Micros[1].Engine)
+	}
+	if s.AppName != "Todos" {
+		t.Errorf("expected the app name to be kept in memory, got %s", s.AppName)
+	}
+
+	migrations := s.Migrations()
+	if len(migrations) != 3 {
+		t.Fatalf("expected 3 migrations, got %v", migrations)
+	}
+	for _, d := range migrations {
+		if d.Severity != SeverityWarning || d.Rule != RuleOutdatedVersion || d.Line == 0 {
+			t.Errorf("expected a positioned warning, got %+v", d)
+		}
+	}
+
+	// saving does not migrate the file
+	dir := t.TempDir()
+	s.Micros[1].Run = "node server.js"
+	if err := s.Save(dir); err != nil {
+		t.Fatalf("failed to save spacefile: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, SpacefileName))
+	if err != nil {
+		t.Fatalf("failed to read saved spacefile: %v", err)
+	}
+	for _, expected := range []string{"v: 0", "app_name: Todos", "engine: python3.8", "run: node server.js"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("expected saved spacefile to contain %s, got:\n%s", expected, content)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	result, err := Migrate("testdata/spacefile/migrate")
+	if err != nil {
+		t.Fatalf("failed to migrate spacefile: %v", err)
+	}
+
+	if result.From != 0 || result.To != CurrentVersion || len(result.Applied) != 1 || result.AppName != "Todos" || len(result.Changes) != 3 {
+		t.Fatalf("unexpected migration result %+v", result)
+	}
+
+	migrated := string(result.Migrated)
+	for _, expected := range []string{"v: 1", "# the backend", "engine: python3.9", "engine: nodejs16 # the frontend"} {
+		if !strings.Contains(migrated, expected) {
+			t.Errorf("expected migrated spacefile to contain %s, got:\n%s", expected, migrated)
+		}
+	}
+	if strings.Contains(migrated, "app_name") {
+		t.Errorf("expected app_name to be removed, got:\n%s", migrated)
+	}
+
+	diff := result.Diff()
+	for _, expected := range []string{"--- a/Spacefile\n+++ b/Spacefile\n@@ -1,13 +1,12 @@\n", "-v: 0\n-app_name: Todos\n+v: 1\n", "+    engine: nodejs16 # the frontend\n"} {
+		if !strings.Contains(diff, expected) {
+			t.Errorf("expected diff to contain %q, got:\n%s", expected, diff)
+		}
+	}
+
+	// a v0 Spacefile without deprecated fields only has its version bumped
+	result, err = Migrate("testdata/spacefile/single_micro")
+	if err != nil {
+		t.Fatalf("failed to migrate spacefile: %v", err)
+	}
+	if len(result.Changes) != 0 || !strings.Contains(result.Diff(), "-v: 0\n+v: 1\n") {
+		t.Errorf("expected only the version to change, got %+v\n%s", result.Changes, result.Diff())
+	}
+
+	// a current Spacefile has nothing to migrate
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, SpacefileName), result.Migrated, 0644); err != nil {
+		t.Fatalf("failed to write spacefile: %v", err)
+	}
+	result, err = Migrate(projectDir)
+	if err != nil {
+		t.Fatalf("failed to migrate spacefile: %v", err)
+	}
+	if result.From != CurrentVersion || len(result.Applied) != 0 || result.Diff() != "" {
+		t.Errorf("expected nothing to migrate, got %+v", result)
+	}
+}
+
+func TestMigrationsFromVersion(t *testing.T) {
+	// the v0 to v1 migration is not applied again to a v1 Spacefile, deprecated engines are rejected
+	_, err := parseSpacefile(".", "Spacefile", []byte("v: 1\nmicros:\n  - name: api\n    src: .\n    engine: python3.8\n"))
+
+	diagnostics := Diagnostics(err)
+	if len(diagnostics) != 1 || diagnostics[0].Rule != "schema/enum" || diagnostics[0].Line != 5 {
+		t.Fatalf("expected the deprecated engine to be rejected, got %v", diagnostics)
+	}
+}
+
+func TestPushedContent(t *testing.T) {
+	content := []byte("# the app\nv: 1\nmicros:\n  - name: api\n    src: .\n    engine: python3.9 # keep me\n")
+
+	pushed, err := PushedContent(content)
+	if err != nil {
+		t.Fatalf("failed to set the pushed version: %v", err)
+	}
+	for _, expected := range []string{"# the app\nv: 0\n", "engine: python3.9 # keep me"} {
+		if !strings.Contains(string(pushed), expected) {
+			t.Errorf("expected pushed spacefile to contain %q, got:\n%s", expected, pushed)
+		}
+	}
+
+	// v0 Spacefiles are pushed as is
+	content = []byte("v: 0\nmicros:\n  - name: api\n    src: .\n    engine:   python3.9\n")
+	if pushed, err = PushedContent(content); err != nil || string(pushed) != string(content) {
+		t.Errorf("expected the content to be unchanged, got %q, %v", pushed, err)
+	}
+}
+
+func TestUnsupportedVersion(t *testing.T) {
+	_, err := parseSpacefile(".", "Spacefile", []byte("v: 2\nmicros:\n  - name: api\n    src: .\n    engine: python3.9\n"))
+
+	diagnostics := Diagnostics(err)
+	if len(diagnostics) != 1 || diagnostics[0].Rule != RuleUnsupportedVersion || diagnostics[0].Line != 1 {
+		t.Fatalf("expected an unsupported version error, got %v", diagnostics)
+	}
+}
@@ -0,0 +1,133 @@
+package spacefile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DevOverlayName is the overlay for development, meant to be committed
+	DevOverlayName = "Spacefile.dev"
+	// LocalOverlayName is the overlay for a single machine, meant to be ignored by version control
+	LocalOverlayName = "Spacefile.local"
+)
+
+var ErrSaveWithOverlays = errors.New("cannot save a Spacefile loaded with overlays")
+
+// Overlays lists the overlays to apply on top of the Spacefile of a project:
+// Spacefile.dev and Spacefile.local when they exist, followed by the extra ones
+func Overlays(projectDir string, extra []string) []string {
+	var overlays []string
+	for _, name := range []string{DevOverlayName, LocalOverlayName} {
+		path := filepath.Join(projectDir, name)
+		if _, err := os.Stat(path); err == nil {
+			overlays = append(overlays, path)
+		}
+	}
+
+	return append(overlays, extra...)
+}
+
+// Render deep merges the overlays, in order, over the Spacefile of a project and returns the result.
+// Micros and env presets are matched by name, actions by id, other lists are replaced
+// and a null value in an overlay removes the field.
+func Render(projectDir string, overlays []string) ([]byte, error) {
+	doc, err := LoadDocument(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range overlays {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read overlay %s, %w", path, err)
+		}
+
+		overlay, err := ParseDocument(content)
+		if err != nil {
+			if errors.Is(err, ErrInvalidDocument) {
+				return nil, fmt.Errorf("invalid overlay %s, %w", path, err)
+			}
+			return nil, newValidationError([]Diagnostic{yamlDiagnostic(err, path)}, path, content)
+		}
+
+		mergeNode(doc.mapping(), overlay.mapping())
+	}
+
+	return doc.Bytes()
+}
+
+// LoadSpacefileWithOverlays loads the Spacefile of a project with the overlays merged over it.
+// Problems found in the merged Spacefile are reported against its rendered contents.
+func LoadSpacefileWithOverlays(projectDir string, overlays []string) (*Spacefile, error) {
+	if len(overlays) == 0 {
+		return LoadSpacefile(projectDir)
+	}
+
+	content, err := Render(projectDir, overlays)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, overlay := range overlays {
+		names = append(names, filepath.Base(overlay))
+	}
+	spacefilePath := fmt.Sprintf("%s (merged with %s)", filepath.Join(projectDir, SpacefileName), strings.Join(names, ", "))
+
+	s, err := parseSpacefile(projectDir, spacefilePath, content)
+	if err != nil {
+		return nil, err
+	}
+
+	s.overlays = overlays
+	return s, nil
+}
+
+// mergeNode deep merges the overlay node into dst
+func mergeNode(dst, overlay *yaml.Node) {
+	overlay = resolveAlias(overlay)
+
+	// do not change the other aliases of an anchor
+	expandAlias(dst)
+
+	switch {
+	case dst.Kind == yaml.MappingNode && overlay.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(overlay.Content); i += 2 {
+			key, value := overlay.Content[i].Value, resolveAlias(overlay.Content[i+1])
+			if value.Kind == yaml.ScalarNode && value.ShortTag() == "!!null" {
+				removeMappingEntry(dst, key)
+				continue
+			}
+
+			_, existing := mappingEntry(dst, key)
+			if existing == nil {
+				setMappingEntry(dst, key, copyNode(value))
+				continue
+			}
+			mergeNode(existing, value)
+		}
+	case dst.Kind == yaml.SequenceNode && overlay.Kind == yaml.SequenceNode:
+		key := sequenceIdentity(dst, overlay)
+		if key == "" {
+			replaceNode(dst, copyNode(overlay))
+			return
+		}
+
+		for _, item := range overlay.Content {
+			_, id := mappingEntry(resolveAlias(item), key)
+			if i := sequenceIndex(dst, key, id.Value); i >= 0 {
+				mergeNode(dst.Content[i], item)
+				continue
+			}
+			dst.Content = append(dst.Content, copyNode(item))
+		}
+	default:
+		replaceNode(dst, copyNode(overlay))
+	}
+}
@@ -0,0 +1,90 @@
+package spacefile
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadSpacefileWithOverlays(t *testing.T) {
+	projectDir := "testdata/spacefile/overlay"
+	overlays := Overlays(projectDir, nil)
+	if len(overlays) != 1 || filepath.Base(overlays[0]) != LocalOverlayName {
+		t.Fatalf("expected %s to be found, got %v", LocalOverlayName, overlays)
+	}
+
+	s, err := LoadSpacefileWithOverlays(projectDir, overlays)
+	if err != nil {
+		t.Fatalf("failed to load spacefile with overlays: %v", err)
+	}
+
+	if len(s.Micros) != 2 || s.Micros[1].Name != "admin" {
+		t.Fatalf("expected the admin micro to be added, got %v", s.Micros)
+	}
+
+	api := s.Micros[0]
+	if api.Dev != "uvicorn main:app --reload" {
+		t.Errorf("expected the dev command to be overridden, got %s", api.Dev)
+	}
+
+	env := api.Presets.Env
+	if len(env) != 2 || env[0].Default != "http://localhost:8000" || env[1].Default != "info" {
+		t.Errorf("expected env presets to be merged by name, got %v", env)
+	}
+
+	if len(api.Actions) != 1 || api.Actions[0].Name != "Cleanup" || api.Actions[0].Interval != "* * * * *" {
+		t.Errorf("expected actions to be merged by id, got %v", api.Actions)
+	}
+
+	if err := s.Save(t.TempDir()); !errors.Is(err, ErrSaveWithOverlays) {
+		t.Errorf("expected saving a merged spacefile to fail, got %v", err)
+	}
+
+	base, err := LoadSpacefile(projectDir)
+	if err != nil {
+		t.Fatalf("failed to load spacefile: %v", err)
+	}
+	if len(base.Micros) != 1 || base.Micros[0].Dev != "uvicorn main:app" {
+		t.Errorf("expected the base spacefile to be left untouched, got %v", base.Micros)
+	}
+}
+
+func TestRenderKeepsComments(t *testing.T) {
+	projectDir := "testdata/spacefile/overlay"
+	content, err := Render(projectDir, Overlays(projectDir, nil))
+	if err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+
+	for _, expected := range []string{
+		"# Spacefile Docs",
+		"dev: uvicorn main:app --reload # started by space dev",
+		"- name: admin",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("expected rendered spacefile to contain %q, got:\n%s", expected, content)
+		}
+	}
+}
+
+func TestMergeRemovesNullFields(t *testing.T) {
+	doc, err := ParseDocument([]byte(commentedSpacefile))
+	if err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+	overlay, err := ParseDocument([]byte("micros:\n  - name: worker\n    presets: null\n"))
+	if err != nil {
+		t.Fatalf("failed to parse overlay: %v", err)
+	}
+
+	mergeNode(doc.mapping(), overlay.mapping())
+
+	content, err := doc.Bytes()
+	if err != nil {
+		t.Fatalf("failed to encode document: %v", err)
+	}
+	if strings.Contains(string(content), "*presets") || !strings.Contains(string(content), "&presets") {
+		t.Fatalf("expected only the presets of worker to be removed, got:\n%s", content)
+	}
+}
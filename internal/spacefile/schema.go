@@ -0,0 +1,93 @@
+package spacefile
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// schemaDocument is the embedded json schema decoded as plain maps, used to look up the
+// type, description and allowed values of a field
+var schemaDocument = mustDecodeSchema(spacefileSchemaString)
+
+func mustDecodeSchema(schema string) map[string]any {
+	var v map[string]any
+	if err := json.Unmarshal([]byte(schema), &v); err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// microNameReg is the pattern of micro names in the schema
+var microNameReg = regexp.MustCompile(schemaAt([]string{"micros", "", "name"})["pattern"].(string))
+
+// resolveSchemaRef follows the local $ref of a schema, if any
+func resolveSchemaRef(schema map[string]any) map[string]any {
+	ref, ok := schema["$ref"].(string)
+	if !ok || !strings.HasPrefix(ref, "#/") {
+		return schema
+	}
+
+	var current any = schemaDocument
+	for _, segment := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = m[segment]
+	}
+
+	resolved, _ := current.(map[string]any)
+	return resolved
+}
+
+// schemaAt returns the schema of the field at path. Items of arrays take one segment
+// of the path, whatever its value, so that "micros.api.engine" resolves to the engine of a micro.
+func schemaAt(path []string) map[string]any {
+	schema := resolveSchemaRef(schemaDocument)
+	for _, segment := range path {
+		if schema == nil {
+			return nil
+		}
+
+		switch schema["type"] {
+		case "array":
+			items, _ := schema["items"].(map[string]any)
+			schema = resolveSchemaRef(items)
+		case "object":
+			properties, _ := schema["properties"].(map[string]any)
+			property, _ := properties[segment].(map[string]any)
+			schema = resolveSchemaRef(property)
+		default:
+			return nil
+		}
+	}
+	return schema
+}
+
+// schemaType returns the json type of a schema, or an empty string if unknown
+func schemaType(schema map[string]any) string {
+	t, _ := schema["type"].(string)
+	return t
+}
+
+// Engines lists the engines accepted by the Spacefile schema
+func Engines() []string {
+	var engines []string
+	values, _ := schemaAt([]string{"micros", "", "engine"})["enum"].([]any)
+	for _, v := range values {
+		if engine, ok := v.(string); ok {
+			engines = append(engines, engine)
+		}
+	}
+	return engines
+}
+
+// ValidateMicroName checks a micro name against the pattern of the schema
+func ValidateMicroName(name string) error {
+	if !microNameReg.MatchString(name) {
+		return fmt.Errorf("%w %s, it must start with a letter or a digit and contain only letters, digits, - and _", ErrInvalidName, name)
+	}
+	return nil
+}
@@ -1,7 +1,6 @@
 package spacefile
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"os"
@@ -31,15 +30,32 @@ var (
 	ErrDuplicateMicros   = errors.New("micro names have to be unique")
 	ErrMultiplePrimary   = errors.New("multiple primary micros present")
 	ErrNoPrimaryMicro    = errors.New("no primary micro present")
+	ErrMicroSrcNotFound  = errors.New("micro src not found")
 )
 
 // Spacefile xx
 type Spacefile struct {
-	V       int             `yaml:"v"`
-	Icon    string          `yaml:"icon,omitempty"`
-	AppName string          `yaml:"app_name,omitempty"`
-	AutoPWA *bool           `yaml:"auto_pwa,omitempty"`
-	Micros  []*shared.Micro `yaml:"micros,omitempty"`
+	V       int    `yaml:"v"`
+	Icon    string `yaml:"icon,omitempty"`
+	AppName string `yaml:"app_name,omitempty"`
+	AutoPWA *bool  `yaml:"auto_pwa,omitempty"`
+	// RespectGitignore excludes the files ignored by git from pushes, under the .spaceignore rules
+	RespectGitignore bool            `yaml:"respect_gitignore,omitempty"`
+	Micros           []*shared.Micro `yaml:"micros,omitempty"`
+
+	// path and node of the file the Spacefile was loaded from
+	path string
+	node *yaml.Node
+	// snapshot of the Spacefile after loading, used to only save the fields that changed
+	snapshot *yaml.Node
+	// overlays merged over the Spacefile, if any
+	overlays []string
+	// interpolated is set once variables were expanded
+	interpolated bool
+	// from is the version of the Spacefile before it was migrated in memory
+	from int
+	// migrations made in memory while loading an outdated Spacefile
+	migrations []Diagnostic
 }
 
 func extractMicro(v any, index int) (map[string]any, bool) {
@@ -152,15 +168,7 @@ func PrettyValidationErrors(ve *jsonschema.ValidationError, v any, prefix string
 
 	// If there are no causes, just print the message
 	if len(ve.Causes) == 0 {
-		message := strings.Replace(ve.Message, "additionalProperties", "unknown field", 1)
-		parts := strings.Split(ve.InstanceLocation, "/")
-
-		leaf := parts[len(parts)-1]
-		if leaf == "" || numberReg.MatchString(leaf) {
-			return fmt.Sprintf("%sL %s", prefix, message)
-		}
-
-		return fmt.Sprintf("%sL %s -> %s", prefix, leaf, message)
+		return fmt.Sprintf("%sL %s", prefix, leafMessage(ve))
 	}
 
 	var rows []string
@@ -251,8 +259,26 @@ func LoadSpacefile(projectDir string) (*Spacefile, error) {
 		return nil, fmt.Errorf("failed to read, %w", err)
 	}
 
+	return parseSpacefile(projectDir, spacefilePath, content)
+}
+
+// parseSpacefile validates the raw contents of a Spacefile, every problem found is
+// reported as a diagnostic positioned on the offending node of the yaml tree
+func parseSpacefile(projectDir string, spacefilePath string, content []byte) (*Spacefile, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, newValidationError([]Diagnostic{yamlDiagnostic(err, spacefilePath)}, spacefilePath, content)
+	}
+
+	// outdated Spacefiles are migrated in memory, the original tree is kept to save changes without migrating the file
+	migrated := copyNode(&root)
+	m, diagnostics := migrate(migrated, spacefilePath)
+	if len(diagnostics) > 0 {
+		return nil, newValidationError(diagnostics, spacefilePath, content)
+	}
+
 	var v any
-	if err := yaml.Unmarshal(content, &v); err != nil {
+	if err := migrated.Decode(&v); err != nil {
 		return nil, err
 	}
 
@@ -260,34 +286,42 @@ func LoadSpacefile(projectDir string) (*Spacefile, error) {
 	if err := spacefileSchema.Validate(v); err != nil {
 		var ve *jsonschema.ValidationError
 		if errors.As(err, &ve) {
-			return nil, fmt.Errorf(PrettyValidationErrors(ve, v, ""))
+			return nil, newValidationError(schemaDiagnostics(ve, migrated, spacefilePath), spacefilePath, content)
 		}
+		return nil, err
 	}
 
 	var spacefile Spacefile
-	if err := yaml.Unmarshal(content, &spacefile); err != nil {
+	if err := migrated.Decode(&spacefile); err != nil {
 		return nil, err
 	}
-	if spacefile.AutoPWA == nil {
-		spacefile.AutoPWA = new(bool)
-		*spacefile.AutoPWA = true
+	if m.appName != "" {
+		spacefile.AppName = m.appName
 	}
 	if spacefile.AutoPWA == nil {
 		spacefile.AutoPWA = new(bool)
 		*spacefile.AutoPWA = true
 	}
 
+	report := func(pointer string, rule string, err error, message string) {
+		d := diagnosticAt(migrated, spacefilePath, pointer, false)
+		d.Rule = rule
+		d.Err = err
+		d.Message = message
+		diagnostics = append(diagnostics, d)
+	}
+
 	foundPrimaryMicro := false
 	micros := make(map[string]struct{})
 	for i, micro := range spacefile.Micros {
 		if _, ok := micros[micro.Name]; ok {
-			return nil, fmt.Errorf("%w, duplicate micro `%s` found", ErrDuplicateMicros, micro.Name)
+			report(fmt.Sprintf("/micros/%d/name", i), RuleDuplicateMicros, ErrDuplicateMicros, fmt.Sprintf("%s, duplicate micro `%s` found", ErrDuplicateMicros, micro.Name))
 		}
 		micros[micro.Name] = struct{}{}
 
 		if micro.Primary {
 			if foundPrimaryMicro {
-				return nil, ErrMultiplePrimary
+				report(fmt.Sprintf("/micros/%d/primary", i), RuleMultiplePrimary, ErrMultiplePrimary, ErrMultiplePrimary.Error())
 			}
 
 			foundPrimaryMicro = true
@@ -296,7 +330,7 @@ func LoadSpacefile(projectDir string) (*Spacefile, error) {
 		}
 
 		if _, err := os.Stat(filepath.Join(projectDir, micro.Src)); os.IsNotExist(err) {
-			return nil, fmt.Errorf("micro %s src %s not found", micro.Name, micro.Src)
+			report(fmt.Sprintf("/micros/%d/src", i), RuleMissingSrc, ErrMicroSrcNotFound, fmt.Sprintf("src %s not found", micro.Src))
 		}
 
 		if micro.Path != "" {
@@ -317,35 +351,56 @@ func LoadSpacefile(projectDir string) (*Spacefile, error) {
 			spacefile.Micros[0].Primary = true
 			spacefile.Micros[0].Path = "/"
 		} else {
-			return nil, ErrNoPrimaryMicro
+			report("/micros", RuleNoPrimary, ErrNoPrimaryMicro, ErrNoPrimaryMicro.Error())
 		}
 	}
 
+	if len(diagnostics) > 0 {
+		return nil, newValidationError(diagnostics, spacefilePath, content)
+	}
+
+	snapshot, err := encodeNode(&spacefile)
+	if err != nil {
+		return nil, err
+	}
+
+	spacefile.path = spacefilePath
+	spacefile.node = &root
+	spacefile.snapshot = snapshot
+	spacefile.from = m.from
+	spacefile.migrations = m.changes
+
 	return &spacefile, nil
 }
 
+// Save writes the Spacefile to sourceDir. If the Spacefile was loaded from a file, only the fields
+// that changed since it was loaded are written back so that comments and formatting are kept.
 func (s *Spacefile) Save(sourceDir string) error {
+	if len(s.overlays) > 0 {
+		return ErrSaveWithOverlays
+	}
+	if s.interpolated {
+		return ErrSaveInterpolated
+	}
 
-	spacefileDocsUrl := "# Spacefile Docs: https://go.deta.dev/docs/spacefile/v0\n"
+	doc := NewDocument()
+	if s.node != nil {
+		doc = &Document{root: s.node}
+	}
 
-	// marshall spacefile object
-	var rawSpacefile bytes.Buffer
-	yamlEncoder := yaml.NewEncoder(&rawSpacefile)
-	yamlEncoder.SetIndent(2)
-	err := yamlEncoder.Encode(&s)
+	current, err := encodeNode(s)
 	if err != nil {
-		return fmt.Errorf("failed to marshall spacefile object: %w", err)
+		return err
 	}
 
-	c := []byte(spacefileDocsUrl)
-	c = append(c, rawSpacefile.Bytes()...)
-
-	// write spacefile object to file
-	err = os.WriteFile(filepath.Join(sourceDir, SpacefileName), c, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to write, %w", err)
+	patchNode(doc.mapping(), s.snapshot, current)
+	if err := doc.Save(sourceDir); err != nil {
+		return err
 	}
 
+	s.node = doc.root
+	s.snapshot = current
+
 	return nil
 }
 
@@ -358,23 +413,6 @@ func (s *Spacefile) AddMicros(newMicros []*shared.Micro) error {
 	return nil
 }
 
-func (s *Spacefile) GetIcon() (*Icon, error) {
-	if s.Icon == "" {
-		return nil, ErrInvalidIconPath
-	}
-	iconMeta, err := getIconMeta(s.Icon)
-	if err != nil {
-		return nil, err
-	}
-
-	raw, err := os.ReadFile(filepath.Join(s.Icon))
-	if err != nil {
-		return nil, fmt.Errorf("cannot read image, %w", err)
-	}
-
-	return &Icon{Raw: raw, IconMeta: iconMeta}, nil
-}
-
 func (s *Spacefile) AddMicro(newMicro *shared.Micro) error {
 	// mark new micro as primary if it is the only one
 	if len(s.Micros) == 0 {
@@ -68,3 +68,13 @@ func TestImplicitPrimary(t *testing.T) {
 		t.Fatalf("expected primary to be true but got false")
 	}
 }
+
+func TestPrimaryMicroSrcNotChecked(t *testing.T) {
+	content := []byte("v: 0\nmicros:\n  - name: api\n    src: ./missing\n    engine: python3.9\n    primary: true\n  - name: web\n    src: ./missing-web\n    engine: python3.9\n")
+
+	_, err := parseSpacefile(t.TempDir(), "Spacefile", content)
+	diagnostics := Diagnostics(err)
+	if len(diagnostics) != 1 || diagnostics[0].Rule != RuleMissingSrc || diagnostics[0].Line != 8 {
+		t.Fatalf("expected only the src of the web micro to be reported, got %v", diagnostics)
+	}
+}
@@ -0,0 +1,3 @@
+import os
+
+API_URL = os.getenv("API_URL")
@@ -0,0 +1,60 @@
+package spacefile
+
+import (
+	"errors"
+	"path/filepath"
+)
+
+// Validate loads the Spacefile in projectDir and runs every check on it, including the icon checks.
+// Problems with the Spacefile are returned as diagnostics, the error is only set if the checks could not run.
+func Validate(projectDir string) ([]Diagnostic, error) {
+	s, err := LoadSpacefile(projectDir)
+	if err != nil {
+		if diagnostics := Diagnostics(err); diagnostics != nil {
+			return diagnostics, nil
+		}
+		return nil, err
+	}
+
+	diagnostics := append(s.iconDiagnostics(projectDir), s.Migrations()...)
+	SortDiagnostics(diagnostics)
+
+	return diagnostics, nil
+}
+
+func (s *Spacefile) iconDiagnostics(projectDir string) []Diagnostic {
+	if s.Icon == "" {
+		return []Diagnostic{{
+			Severity: SeverityInfo,
+			Rule:     RuleNoIcon,
+			File:     s.path,
+			Message:  "no app icon specified, a placeholder is generated from the app name when pushing",
+		}}
+	}
+
+	iconPath := s.Icon
+	if !filepath.IsAbs(iconPath) {
+		iconPath = filepath.Join(projectDir, iconPath)
+	}
+
+	err := ValidateIcon(iconPath)
+	if err == nil {
+		return nil
+	}
+
+	d := diagnosticAt(s.node, s.path, "/icon", false)
+	d.Err = err
+	switch {
+	case errors.Is(err, ErrInvalidIconType):
+		d.Rule = RuleIconType
+		d.Message = "invalid icon type, please use a PNG, JPEG, GIF, WebP or SVG icon"
+	case errors.Is(err, ErrInvalidIconSize):
+		d.Rule = RuleIconSize
+		d.Message = "icon is too small, please use an icon of at least 512x512 pixels"
+	default:
+		d.Rule = RuleIconPath
+		d.Message = "cannot find the icon in provided path, please provide a valid icon path or leave it empty to auto-generate one"
+	}
+
+	return []Diagnostic{d}
+}
@@ -0,0 +1,70 @@
+package spacefile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestValidateIcon(t *testing.T) {
+	diagnostics, err := Validate("testdata/spacefile/invalid_icon")
+	if err != nil {
+		t.Fatalf("failed to validate spacefile: %v", err)
+	}
+
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic but got %d", len(diagnostics))
+	}
+
+	d := diagnostics[0]
+	if d.Rule != RuleIconSize || !errors.Is(d.Err, ErrInvalidIconSize) {
+		t.Fatalf("expected rule %s but got %s", RuleIconSize, d.Rule)
+	}
+
+	if d.Line != 2 || d.Column != 7 {
+		t.Fatalf("expected diagnostic at 2:7 but got %d:%d", d.Line, d.Column)
+	}
+}
+
+func TestValidateNoIcon(t *testing.T) {
+	diagnostics, err := Validate("testdata/spacefile/single_micro")
+	if err != nil {
+		t.Fatalf("failed to validate spacefile: %v", err)
+	}
+
+	if HighestSeverity(diagnostics) != SeverityInfo {
+		t.Fatalf("expected highest severity to be %s but got %s", SeverityInfo, HighestSeverity(diagnostics))
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	diagnostics, err := Validate("testdata/spacefile/invalid_fields")
+	if err != nil {
+		t.Fatalf("failed to validate spacefile: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, diagnostics, "test"); err != nil {
+		t.Fatalf("failed to write sarif: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("failed to parse sarif output: %v", err)
+	}
+
+	results := log.Runs[0].Results
+	if len(results) != len(diagnostics) {
+		t.Fatalf("expected %d results but got %d", len(diagnostics), len(results))
+	}
+
+	first := results[0]
+	if first.RuleID != "schema/enum" || first.Level != "error" {
+		t.Fatalf("expected an error for rule schema/enum but got %s for rule %s", first.Level, first.RuleID)
+	}
+
+	if region := first.Locations[0].PhysicalLocation.Region; region == nil || region.StartLine != 6 {
+		t.Fatalf("expected result to start on line 6")
+	}
+}
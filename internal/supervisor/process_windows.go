@@ -0,0 +1,53 @@
+//go:build windows
+// +build windows
+
+package supervisor
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// setProcessGroup starts the command in its own process group, so that its children can be signaled with it
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+}
+
+// terminate asks the process tree to exit
+func terminate(p *os.Process) error {
+	return exec.Command("taskkill", "/T", "/PID", strconv.Itoa(p.Pid)).Run()
+}
+
+// kill kills the process tree
+func kill(p *os.Process) error {
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(p.Pid)).Run()
+}
+
+// groupAlive reports whether the process tree of pid is running, the children of a process
+// cannot be found once it exited so only the process itself is checked
+func groupAlive(pid int) bool {
+	return Alive(pid)
+}
+
+// stillActive is the exit code of a process that has not exited
+const stillActive = 259
+
+// Alive reports whether a process exists and has not exited
+func Alive(pid int) bool {
+	h, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
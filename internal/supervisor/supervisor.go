@@ -0,0 +1,368 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// RestartPolicy decides whether a process is restarted when it exits by itself
+type RestartPolicy string
+
+const (
+	RestartNever     RestartPolicy = "never"
+	RestartOnFailure RestartPolicy = "on-failure"
+	RestartAlways    RestartPolicy = "always"
+)
+
+// RestartPolicies lists the supported restart policies
+var RestartPolicies = []RestartPolicy{RestartNever, RestartOnFailure, RestartAlways}
+
+// Status of a supervised process
+type Status string
+
+const (
+	StatusStarting Status = "starting"
+	StatusRunning  Status = "running"
+	// StatusRestarting is reported while a process waits to be started again
+	StatusRestarting Status = "restarting"
+	StatusStopping   Status = "stopping"
+	// StatusStopped is reported once a process was stopped by the supervisor, or on demand by Stop
+	StatusStopped Status = "stopped"
+	// StatusExited is reported when a process exited successfully and is not restarted
+	StatusExited Status = "exited"
+	// StatusFailed is reported when a process failed and is not restarted
+	StatusFailed Status = "failed"
+)
+
+// defaults of Options
+const (
+	DefaultMaxRestarts    = 5
+	DefaultInitialBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff     = 30 * time.Second
+	DefaultGracePeriod    = 5 * time.Second
+	// DefaultStableAfter is how long a process has to run for its failures to be forgotten
+	DefaultStableAfter = 30 * time.Second
+)
+
+// Event is a status transition of a supervised process
+type Event struct {
+	Name   string
+	Status Status
+	// PID is the id of the running process, if any
+	PID int
+	// Restarts is the number of times the process was restarted
+	Restarts int
+	// Err is why the process exited, if it failed
+	Err error
+	// Delay is how long the process waits before being restarted
+	Delay time.Duration
+	// Reason is why a restart was requested
+	Reason string
+	Time   time.Time
+}
+
+// Options configures a supervised process, zero values are replaced by the defaults except for MaxRestarts
+type Options struct {
+	Policy RestartPolicy
+	// MaxRestarts is the maximum number of consecutive restarts, the process is never restarted by itself if it is 0
+	// and there is no limit if it is negative. Callers start from DefaultMaxRestarts.
+	MaxRestarts int
+	// InitialBackoff is the delay before the first restart, it doubles on every consecutive restart up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// GracePeriod is how long a process has to exit once terminated, before it is killed
+	GracePeriod time.Duration
+	// StableAfter is how long a process has to run for its consecutive restarts and backoff to be reset
+	StableAfter time.Duration
+	// ReturnOnExit makes Run return the exit error of the process once it exits and is not restarted,
+	// instead of waiting for it to be restarted on demand
+	ReturnOnExit bool
+	// OnEvent, if set, is called on every status transition
+	OnEvent func(Event)
+}
+
+// Process runs a command and restarts it according to its restart policy.
+// The command is created again for every start, and runs in its own process group so that its children are stopped with it.
+// When the command exits by itself, what is left of its group is stopped too, so that it does not keep holding its port.
+type Process struct {
+	name       string
+	newCommand func() (*exec.Cmd, error)
+	opts       Options
+	restarts   chan string
+	stops      chan struct{}
+
+	mu     sync.Mutex
+	status Event
+}
+
+// New creates a supervised process, it is started by Run
+func New(name string, newCommand func() (*exec.Cmd, error), opts Options) *Process {
+	if opts.Policy == "" {
+		opts.Policy = RestartOnFailure
+	}
+	if opts.InitialBackoff == 0 {
+		opts.InitialBackoff = DefaultInitialBackoff
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+	if opts.GracePeriod == 0 {
+		opts.GracePeriod = DefaultGracePeriod
+	}
+	if opts.StableAfter == 0 {
+		opts.StableAfter = DefaultStableAfter
+	}
+
+	return &Process{
+		name:       name,
+		newCommand: newCommand,
+		opts:       opts,
+		restarts:   make(chan string, 1),
+		stops:      make(chan struct{}, 1),
+		status:     Event{Name: name},
+	}
+}
+
+// Restart stops the process if it is running and starts it again, even if it was not restarted after exiting.
+// A restart requested while another one is pending is dropped.
+func (p *Process) Restart(reason string) {
+	select {
+	case p.restarts <- reason:
+	default:
+	}
+}
+
+// Stop stops the process if it is running, it stays stopped until it is restarted with Restart
+func (p *Process) Stop() {
+	select {
+	case p.stops <- struct{}{}:
+	default:
+	}
+}
+
+// Status returns the last status transition of the process
+func (p *Process) Status() Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+// Run starts the process and supervises it until ctx is done, then stops it.
+// It only returns early if the command cannot be created, or with ReturnOnExit once the process is not restarted.
+func (p *Process) Run(ctx context.Context) error {
+	total := 0
+	// consecutive counts the restarts after failures since the process last ran for StableAfter
+	consecutive := 0
+	backoff := p.opts.InitialBackoff
+
+	for {
+		cmd, err := p.newCommand()
+		if err != nil {
+			p.emit(Event{Status: StatusFailed, Restarts: total, Err: err})
+			return err
+		}
+		setProcessGroup(cmd)
+
+		p.emit(Event{Status: StatusStarting, Restarts: total})
+		startedAt := time.Now()
+
+		exitErr := cmd.Start()
+		if exitErr == nil {
+			p.emit(Event{Status: StatusRunning, PID: cmd.Process.Pid, Restarts: total})
+
+			exited := make(chan error, 1)
+			go func() {
+				exited <- cmd.Wait()
+			}()
+
+			select {
+			case <-ctx.Done():
+				p.emit(Event{Status: StatusStopping, PID: cmd.Process.Pid, Restarts: total})
+				p.stop(cmd, exited)
+				p.emit(Event{Status: StatusStopped, Restarts: total})
+				return nil
+			case reason := <-p.restarts:
+				p.emit(Event{Status: StatusStopping, PID: cmd.Process.Pid, Restarts: total, Reason: reason})
+				p.stop(cmd, exited)
+				total++
+				consecutive, backoff = 0, p.opts.InitialBackoff
+				p.emit(Event{Status: StatusRestarting, Restarts: total, Reason: reason})
+				continue
+			case <-p.stops:
+				p.emit(Event{Status: StatusStopping, PID: cmd.Process.Pid, Restarts: total})
+				p.stop(cmd, exited)
+				p.emit(Event{Status: StatusStopped, Restarts: total})
+
+				reason, ok := p.waitRestart(ctx, total)
+				if !ok {
+					return nil
+				}
+				total++
+				consecutive, backoff = 0, p.opts.InitialBackoff
+				p.emit(Event{Status: StatusRestarting, Restarts: total, Reason: reason})
+				continue
+			case exitErr = <-exited:
+				p.stopGroup(cmd.Process)
+			}
+		}
+
+		// the process exited by itself, or could not be started
+		if time.Since(startedAt) >= p.opts.StableAfter {
+			consecutive, backoff = 0, p.opts.InitialBackoff
+		}
+
+		if !p.shouldRestart(exitErr) || (p.opts.MaxRestarts >= 0 && consecutive >= p.opts.MaxRestarts) {
+			status := StatusExited
+			if exitErr != nil {
+				status = StatusFailed
+			}
+			p.emit(Event{Status: status, Restarts: total, Err: exitErr})
+			if p.opts.ReturnOnExit {
+				return exitErr
+			}
+
+			// a process that is not restarted can still be restarted on demand
+			reason, ok := p.waitRestart(ctx, total)
+			if !ok {
+				return nil
+			}
+			total++
+			consecutive, backoff = 0, p.opts.InitialBackoff
+			p.emit(Event{Status: StatusRestarting, Restarts: total, Reason: reason})
+			continue
+		}
+
+		p.emit(Event{Status: StatusRestarting, Restarts: total, Err: exitErr, Delay: backoff})
+		select {
+		case <-ctx.Done():
+			p.emit(Event{Status: StatusStopped, Restarts: total})
+			return nil
+		case <-time.After(backoff):
+			consecutive++
+			backoff *= 2
+			if backoff > p.opts.MaxBackoff {
+				backoff = p.opts.MaxBackoff
+			}
+		case <-p.restarts:
+			consecutive, backoff = 0, p.opts.InitialBackoff
+		case <-p.stops:
+			p.emit(Event{Status: StatusStopped, Restarts: total})
+			reason, ok := p.waitRestart(ctx, total)
+			if !ok {
+				return nil
+			}
+			consecutive, backoff = 0, p.opts.InitialBackoff
+			p.emit(Event{Status: StatusRestarting, Restarts: total + 1, Reason: reason})
+		}
+		total++
+	}
+}
+
+// waitRestart waits until a restart is requested while the process is not running, it returns false once ctx is done
+func (p *Process) waitRestart(ctx context.Context, total int) (string, bool) {
+	for {
+		select {
+		case <-ctx.Done():
+			return "", false
+		case reason := <-p.restarts:
+			return reason, true
+		case <-p.stops:
+			// the process is not running, it is only reported as stopped
+			if p.Status().Status != StatusStopped {
+				p.emit(Event{Status: StatusStopped, Restarts: total})
+			}
+		}
+	}
+}
+
+// shouldRestart applies the restart policy to a process that exited by itself
+func (p *Process) shouldRestart(exitErr error) bool {
+	switch p.opts.Policy {
+	case RestartAlways:
+		// a command that cannot be found will not be found on the next start either
+		return !errors.Is(exitErr, exec.ErrNotFound)
+	case RestartOnFailure:
+		return exitErr != nil && !errors.Is(exitErr, exec.ErrNotFound)
+	}
+	return false
+}
+
+// stop terminates the process group of a running command and waits for it to exit,
+// the group is killed if the command or its children do not exit within the grace period
+func (p *Process) stop(cmd *exec.Cmd, exited <-chan error) {
+	deadline := time.Now().Add(p.opts.GracePeriod)
+	if err := terminate(cmd.Process); err != nil {
+		kill(cmd.Process)
+	}
+
+	select {
+	case <-exited:
+	case <-time.After(p.opts.GracePeriod):
+		kill(cmd.Process)
+		<-exited
+	}
+
+	// children that ignore the signal outlive the command
+	waitGroup(cmd.Process, deadline)
+}
+
+// stopGroup terminates the children left by a command that exited by itself,
+// they are killed if they do not exit within the grace period
+func (p *Process) stopGroup(process *os.Process) {
+	if !groupAlive(process.Pid) {
+		return
+	}
+
+	deadline := time.Now().Add(p.opts.GracePeriod)
+	terminate(process)
+	waitGroup(process, deadline)
+}
+
+// waitGroup waits for the process group of a command that exited to be empty, the group is killed at deadline
+func waitGroup(process *os.Process, deadline time.Time) {
+	for groupAlive(process.Pid) {
+		if time.Now().After(deadline) {
+			kill(process)
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func (p *Process) emit(e Event) {
+	e.Name = p.name
+	e.Time = time.Now()
+
+	p.mu.Lock()
+	p.status = e
+	p.mu.Unlock()
+
+	if p.opts.OnEvent != nil {
+		p.opts.OnEvent(e)
+	}
+}
+
+// Terminate stops the process group of a process that is not supervised by this process, e.g. a micro left running
+// by a space process that crashed. The group is killed if the process does not exit within the grace period.
+func Terminate(pid int, gracePeriod time.Duration) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	if err := terminate(process); err != nil {
+		return kill(process)
+	}
+
+	deadline := time.Now().Add(gracePeriod)
+	for Alive(pid) || groupAlive(pid) {
+		if time.Now().After(deadline) {
+			return kill(process)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+//go:build !windows
+// +build !windows
+
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// recorder collects the events of a process
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) record(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+// waitFor waits until the process reports a status after a number of restarts
+func waitFor(t *testing.T, p *Process, status Status, restarts int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if e := p.Status(); e.Status == status && e.Restarts == restarts {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	e := p.Status()
+	t.Fatalf("expected status %s after %d restarts, got %s after %d restarts", status, restarts, e.Status, e.Restarts)
+}
+
+func shell(script string) func() (*exec.Cmd, error) {
+	return func() (*exec.Cmd, error) {
+		return exec.Command("sh", "-c", script), nil
+	}
+}
+
+func run(t *testing.T, p *Process) context.CancelFunc {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("failed to run process: %v", err)
+		}
+	})
+	return cancel
+}
+
+func TestRestartPolicies(t *testing.T) {
+	tests := []struct {
+		policy   RestartPolicy
+		script   string
+		status   Status
+		restarts int
+	}{
+		{policy: RestartNever, script: "exit 1", status: StatusFailed, restarts: 0},
+		{policy: RestartOnFailure, script: "exit 0", status: StatusExited, restarts: 0},
+		{policy: RestartOnFailure, script: "exit 1", status: StatusFailed, restarts: 2},
+		{policy: RestartAlways, script: "exit 0", status: StatusExited, restarts: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy)+" "+tt.script, func(t *testing.T) {
+			p := New("api", shell(tt.script), Options{Policy: tt.policy, MaxRestarts: 2, InitialBackoff: 10 * time.Millisecond})
+			run(t, p)
+
+			waitFor(t, p, tt.status, tt.restarts)
+		})
+	}
+}
+
+func TestZeroMaxRestarts(t *testing.T) {
+	for _, policy := range []RestartPolicy{RestartOnFailure, RestartAlways} {
+		t.Run(string(policy), func(t *testing.T) {
+			r := &recorder{}
+			p := New("api", shell("exit 1"), Options{Policy: policy, MaxRestarts: 0, InitialBackoff: 10 * time.Millisecond, OnEvent: r.record})
+			run(t, p)
+			waitFor(t, p, StatusFailed, 0)
+
+			// the process would have been restarted several times by now
+			time.Sleep(100 * time.Millisecond)
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			for _, e := range r.events {
+				if e.Status == StatusRestarting || e.Restarts != 0 {
+					t.Fatalf("expected the process to never be restarted, got %+v", e)
+				}
+			}
+		})
+	}
+}
+
+func TestReturnOnExit(t *testing.T) {
+	tests := []struct {
+		policy   RestartPolicy
+		script   string
+		code     int
+		restarts int
+	}{
+		{policy: RestartNever, script: "exit 3", code: 3, restarts: 0},
+		{policy: RestartOnFailure, script: "exit 3", code: 3, restarts: 2},
+		{policy: RestartOnFailure, script: "exit 0", code: 0, restarts: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy)+" "+tt.script, func(t *testing.T) {
+			p := New("api", shell(tt.script), Options{Policy: tt.policy, MaxRestarts: 2, InitialBackoff: 10 * time.Millisecond, ReturnOnExit: true})
+
+			done := make(chan error, 1)
+			go func() {
+				done <- p.Run(context.Background())
+			}()
+
+			var err error
+			select {
+			case err = <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("expected Run to return once the process is not restarted")
+			}
+
+			var exitErr *exec.ExitError
+			if tt.code == 0 && err != nil || tt.code != 0 && (!errors.As(err, &exitErr) || exitErr.ExitCode() != tt.code) {
+				t.Errorf("expected exit code %d, got %v", tt.code, err)
+			}
+			if restarts := p.Status().Restarts; restarts != tt.restarts {
+				t.Errorf("expected %d restarts, got %d", tt.restarts, restarts)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	r := &recorder{}
+	p := New("api", shell("exit 1"), Options{MaxRestarts: 3, InitialBackoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond, OnEvent: r.record})
+	run(t, p)
+	waitFor(t, p, StatusFailed, 3)
+
+	var delays []string
+	r.mu.Lock()
+	for _, e := range r.events {
+		if e.Status == StatusRestarting {
+			delays = append(delays, e.Delay.String())
+		}
+	}
+	r.mu.Unlock()
+
+	if strings.Join(delays, ",") != "10ms,20ms,20ms" {
+		t.Errorf("expected the backoff to double up to its maximum, got %v", delays)
+	}
+}
+
+// childPID waits for a script to write the pid of its child to pidFile
+func childPID(t *testing.T, pidFile string) int {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		raw, _ := os.ReadFile(pidFile)
+		if child, _ := strconv.Atoi(strings.TrimSpace(string(raw))); child != 0 {
+			return child
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("the child process did not start")
+	return 0
+}
+
+// waitGone waits for a process to be killed
+func waitGone(t *testing.T, pid int, timeout time.Duration) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for alive(pid) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the child process %d to be killed", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRestartKillsProcessGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	p := New("api", shell("sleep 30 & echo $! > "+pidFile+"; wait"), Options{})
+	run(t, p)
+
+	waitFor(t, p, StatusRunning, 0)
+	child := childPID(t, pidFile)
+
+	p.Restart("main.py changed")
+	waitFor(t, p, StatusRunning, 1)
+
+	// the child of the stopped process is gone too
+	waitGone(t, child, 2*time.Second)
+}
+
+func TestStopKillsChildrenIgnoringTerm(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	// the shell exits on SIGTERM, its child ignores it
+	p := New("api", shell(`(trap "" TERM; exec sleep 30) & echo $! > `+pidFile+"; wait"), Options{GracePeriod: 200 * time.Millisecond})
+	cancel := run(t, p)
+
+	waitFor(t, p, StatusRunning, 0)
+	child := childPID(t, pidFile)
+
+	cancel()
+	waitFor(t, p, StatusStopped, 0)
+	waitGone(t, child, time.Second)
+}
+
+func TestExitKillsProcessGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	// the shell crashes and leaves its child holding the port
+	p := New("api", shell(`(trap "" TERM; exec sleep 30) & echo $! > `+pidFile+"; exit 1"), Options{Policy: RestartNever, GracePeriod: 200 * time.Millisecond})
+	run(t, p)
+
+	child := childPID(t, pidFile)
+	waitFor(t, p, StatusFailed, 0)
+	waitGone(t, child, time.Second)
+}
+
+// alive reports whether a process is running, zombies wait to be reaped by init and are not running
+func alive(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return false
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return true
+	}
+	// the state follows the command name, which is in parentheses
+	fields := strings.Fields(string(stat[strings.LastIndex(string(stat), ")")+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
+
+func TestStopEscalatesToKill(t *testing.T) {
+	p := New("api", shell(`trap "" TERM; while true; do sleep 0.05; done`), Options{GracePeriod: 200 * time.Millisecond})
+	cancel := run(t, p)
+	waitFor(t, p, StatusRunning, 0)
+	// give the shell time to install the trap
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	cancel()
+	waitFor(t, p, StatusStopped, 0)
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("expected the process to be killed after the grace period, stopped after %s", elapsed)
+	}
+}
+
+func TestRestartAfterFailure(t *testing.T) {
+	p := New("api", shell("exit 1"), Options{Policy: RestartNever})
+	run(t, p)
+	waitFor(t, p, StatusFailed, 0)
+
+	// a failed process is started again on demand
+	p.Restart("main.py changed")
+	waitFor(t, p, StatusFailed, 1)
+}
+
+func TestStop(t *testing.T) {
+	p := New("api", shell("sleep 30"), Options{Policy: RestartAlways})
+	run(t, p)
+	waitFor(t, p, StatusRunning, 0)
+
+	// a stopped process is not restarted by its policy
+	p.Stop()
+	waitFor(t, p, StatusStopped, 0)
+	time.Sleep(100 * time.Millisecond)
+	if e := p.Status(); e.Status != StatusStopped {
+		t.Fatalf("expected the process to stay stopped, got %s", e.Status)
+	}
+
+	p.Restart("space dev restart")
+	waitFor(t, p, StatusRunning, 1)
+}
+
+func TestTerminate(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "sleep 30 & wait")
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	if err := Terminate(cmd.Process.Pid, time.Second); err != nil {
+		t.Fatalf("failed to terminate process: %v", err)
+	}
+	select {
+	case <-exited:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the process to exit")
+	}
+	if Alive(cmd.Process.Pid) {
+		t.Errorf("expected the process to be gone")
+	}
+}
@@ -0,0 +1,190 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deta/space/internal/runtime"
+	"github.com/fsnotify/fsnotify"
+	ignore "github.com/sabhiram/go-gitignore"
+)
+
+// DefaultDebounce is how long changes have to settle before they are reported
+const DefaultDebounce = 300 * time.Millisecond
+
+// ignoreFiles are read by the ignorer, they are not reported as changes
+var ignoreFiles = map[string]bool{".spaceignore": true, ".gitignore": true}
+
+// Options configures Watch
+type Options struct {
+	runtime.ScanOptions
+	// Globs, if set, restrict the reported changes to the matching paths.
+	// They use the gitignore syntax and are relative to the watched directory.
+	Globs []string
+	// Debounce is how long changes have to settle before they are reported, DefaultDebounce if it is zero
+	Debounce time.Duration
+}
+
+// watcher watches a directory of a project recursively
+type watcher struct {
+	projectDir string
+	dir        string
+	fs         *fsnotify.Watcher
+	ignorer    *runtime.Ignorer
+	globs      *ignore.GitIgnore
+}
+
+// Watch watches dir, relative to projectDir, and its subdirectories until ctx is done.
+// The files ignored by the ignore files of the project are not watched.
+// Once changes settle, onChange is called with the changed paths, relative to dir with forward slashes.
+func Watch(ctx context.Context, projectDir string, dir string, opts Options, onChange func(paths []string)) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fsw.Close()
+
+	w := &watcher{
+		projectDir: projectDir,
+		dir:        path.Clean(filepath.ToSlash(dir)),
+		fs:         fsw,
+		ignorer:    runtime.NewIgnorer(projectDir, opts.ScanOptions),
+	}
+	if len(opts.Globs) > 0 {
+		w.globs = ignore.CompileIgnoreLines(opts.Globs...)
+	}
+	if _, err := w.addDir(w.dir); err != nil {
+		return err
+	}
+
+	debounce := opts.Debounce
+	if debounce == 0 {
+		debounce = DefaultDebounce
+	}
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	changed := map[string]bool{}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			if paths := w.handle(event); len(paths) > 0 {
+				for _, p := range paths {
+					changed[p] = true
+				}
+				timer.Reset(debounce)
+			}
+		case <-timer.C:
+			paths := make([]string, 0, len(changed))
+			for p := range changed {
+				paths = append(paths, p)
+			}
+			sort.Strings(paths)
+			changed = map[string]bool{}
+
+			onChange(paths)
+		}
+	}
+}
+
+// handle processes an event, it returns the changed paths that are reported, relative to the watched directory
+func (w *watcher) handle(event fsnotify.Event) []string {
+	if event.Op == fsnotify.Chmod {
+		return nil
+	}
+
+	relPath, err := filepath.Rel(w.projectDir, event.Name)
+	if err != nil {
+		return nil
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	if ignoreFiles[path.Base(relPath)] {
+		w.ignorer.Reset()
+		return nil
+	}
+	if rule, err := w.ignorer.Match(relPath); err != nil || rule != nil {
+		return nil
+	}
+
+	changed := []string{relPath}
+	// new directories are not watched yet, the files created in them before they are watched are reported with them
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			files, _ := w.addDir(relPath)
+			changed = append(changed, files...)
+		}
+	}
+
+	var reported []string
+	for _, p := range changed {
+		if w.dir != "." {
+			p = strings.TrimPrefix(p, w.dir+"/")
+		}
+		if w.globs == nil || w.globs.MatchesPath(p) {
+			reported = append(reported, p)
+		}
+	}
+	return reported
+}
+
+// addDir watches a directory, relative to the project, and its subdirectories that are not ignored.
+// It returns the files found in them, relative to the project.
+func (w *watcher) addDir(relDir string) ([]string, error) {
+	var files []string
+	root := filepath.Join(w.projectDir, filepath.FromSlash(relDir))
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// directories can be removed while they are walked
+			if errors.Is(err, fs.ErrNotExist) && p != root {
+				return nil
+			}
+			return err
+		}
+		if p == root {
+			return w.fs.Add(p)
+		}
+
+		rel, err := filepath.Rel(w.projectDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		rule, err := w.ignorer.Match(rel)
+		if err != nil {
+			return err
+		}
+		if rule != nil {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.IsDir() {
+			files = append(files, rel)
+			return nil
+		}
+		return w.fs.Add(p)
+	})
+	return files, err
+}
@@ -0,0 +1,109 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+}
+
+// watch starts watching dir of the project and returns the reported changes
+func watch(t *testing.T, projectDir string, dir string, opts Options) <-chan []string {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan []string, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(ctx, projectDir, dir, opts, func(paths []string) {
+			changes <- paths
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("failed to watch: %v", err)
+		}
+	})
+
+	// give the watcher time to add the directories
+	time.Sleep(100 * time.Millisecond)
+	return changes
+}
+
+func expectChange(t *testing.T, changes <-chan []string, expected []string) {
+	t.Helper()
+
+	select {
+	case paths := <-changes:
+		if !reflect.DeepEqual(paths, expected) {
+			t.Errorf("expected changes %v, got %v", expected, paths)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected changes %v, got none", expected)
+	}
+}
+
+func expectNoChange(t *testing.T, changes <-chan []string) {
+	t.Helper()
+
+	select {
+	case paths := <-changes:
+		t.Errorf("expected no changes, got %v", paths)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestWatch(t *testing.T) {
+	projectDir := t.TempDir()
+	writeFile(t, filepath.Join(projectDir, ".spaceignore"), "*.log\n")
+	writeFile(t, filepath.Join(projectDir, "api", "main.py"), "print(1)")
+	writeFile(t, filepath.Join(projectDir, "api", "__pycache__", "main.pyc"), "pyc")
+	writeFile(t, filepath.Join(projectDir, "web", "index.html"), "<html></html>")
+
+	changes := watch(t, projectDir, "api", Options{Debounce: 50 * time.Millisecond})
+
+	// changes settle before they are reported together
+	writeFile(t, filepath.Join(projectDir, "api", "main.py"), "print(2)")
+	writeFile(t, filepath.Join(projectDir, "api", "lib", "util.py"), "x = 1")
+	writeFile(t, filepath.Join(projectDir, "api", "lib", "util.py"), "x = 2")
+	expectChange(t, changes, []string{"lib", "lib/util.py", "main.py"})
+
+	// ignored files and other micros are not reported
+	writeFile(t, filepath.Join(projectDir, "api", "debug.log"), "log")
+	writeFile(t, filepath.Join(projectDir, "api", "__pycache__", "main.pyc"), "pyc 2")
+	writeFile(t, filepath.Join(projectDir, "web", "index.html"), "<html>2</html>")
+	expectNoChange(t, changes)
+
+	// new subdirectories are watched
+	writeFile(t, filepath.Join(projectDir, "api", "lib", "util.py"), "x = 3")
+	expectChange(t, changes, []string{"lib/util.py"})
+}
+
+func TestWatchGlobs(t *testing.T) {
+	projectDir := t.TempDir()
+	writeFile(t, filepath.Join(projectDir, "main.go"), "package main")
+	writeFile(t, filepath.Join(projectDir, "README.md"), "# app")
+
+	changes := watch(t, projectDir, ".", Options{Globs: []string{"*.go", "!*_test.go"}, Debounce: 50 * time.Millisecond})
+
+	writeFile(t, filepath.Join(projectDir, "README.md"), "# app 2")
+	writeFile(t, filepath.Join(projectDir, "main_test.go"), "package main")
+	expectNoChange(t, changes)
+
+	writeFile(t, filepath.Join(projectDir, "main.go"), "package main\n")
+	expectChange(t, changes, []string{"main.go"})
+}
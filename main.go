@@ -17,6 +17,10 @@ func main() {
 		if errors.Is(err, components.ErrPromptCancelled) {
 			return
 		}
+		var exitErr *utils.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		utils.StdErrLogger.Println(styles.Errorf("%s Error: %v", emoji.ErrorExclamation, err))
 		os.Exit(1)
 	}
@@ -0,0 +1,254 @@
+package dashboard
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/deta/space/pkg/writer"
+)
+
+// maxLines is the number of log lines kept for each pane
+const maxLines = 2000
+
+// Micro is shown in its own pane
+type Micro struct {
+	Name string
+	Port int
+	URL  string
+}
+
+// Status is the status of the process of a micro
+type Status struct {
+	Name     string
+	Status   string
+	PID      int
+	Restarts int
+	Err      error
+}
+
+// Action can be triggered from the dashboard
+type Action struct {
+	ID    string
+	Name  string
+	Micro string
+}
+
+type Input struct {
+	// Title is shown in the header, along with the URL of the proxy
+	Title    string
+	ProxyURL string
+	Actions  []Action
+	// Restart restarts a micro
+	Restart func(micro string) error
+	// Trigger triggers an action, and returns its response
+	Trigger func(action Action) (string, error)
+	// OpenURL opens a URL in the browser
+	OpenURL func(url string) error
+}
+
+// Dashboard shows the micros of space dev in full screen, with their status and logs.
+// The logs are buffered until the dashboard is run, and written to stdout once it is closed.
+type Dashboard struct {
+	store   *store
+	program *tea.Program
+}
+
+func New(i *Input) *Dashboard {
+	s := &store{
+		panes: []*pane{{micro: Micro{Name: spacePane, URL: i.ProxyURL}}},
+	}
+
+	return &Dashboard{
+		store:   s,
+		program: tea.NewProgram(initialModel(i, s), tea.WithAltScreen(), tea.WithMouseCellMotion()),
+	}
+}
+
+// AddMicro adds the pane of a micro
+func (d *Dashboard) AddMicro(m Micro) {
+	d.store.addMicro(m)
+}
+
+// SetStatus updates the status of a micro
+func (d *Dashboard) SetStatus(s Status) {
+	d.store.setStatus(s)
+}
+
+// Log returns the writer of the logs of a micro, or of the cli if micro is empty
+func (d *Dashboard) Log(micro string) io.Writer {
+	w := &logWriter{store: d.store, name: micro}
+	if micro == "" {
+		w.fallback = os.Stdout
+	} else {
+		w.fallback = writer.NewPrefixer(micro, os.Stdout)
+	}
+	return w
+}
+
+// Run shows the dashboard until it is quit
+func (d *Dashboard) Run() error {
+	_, err := d.program.Run()
+	d.store.close()
+	return err
+}
+
+// Quit closes the dashboard, the logs written after are written to stdout
+func (d *Dashboard) Quit() {
+	d.store.close()
+	d.program.Quit()
+}
+
+// Close stops buffering the logs of a dashboard that was not run
+func (d *Dashboard) Close() {
+	d.store.close()
+}
+
+// spacePane is the name of the pane of the logs of the cli
+const spacePane = "space"
+
+type pane struct {
+	micro  Micro
+	status Status
+	lines  []string
+}
+
+// store holds the panes, it is written by the micros and read by the model
+type store struct {
+	mu    sync.Mutex
+	panes []*pane
+	// version changes on every write, so that the model only renders the logs again when they changed
+	version int
+	closed  bool
+}
+
+func (s *store) addMicro(m Micro) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p := s.pane(m.Name); p != nil {
+		p.micro = m
+	} else {
+		s.panes = append(s.panes, &pane{micro: m, status: Status{Name: m.Name}})
+	}
+	s.version++
+}
+
+func (s *store) setStatus(status Status) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p := s.pane(status.Name); p != nil {
+		p.status = status
+		s.version++
+	}
+}
+
+// append adds lines to the logs of a pane, it returns false once the store is closed
+func (s *store) append(name string, lines []string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	if name == "" {
+		name = spacePane
+	}
+
+	p := s.pane(name)
+	if p == nil {
+		p = &pane{micro: Micro{Name: name}, status: Status{Name: name}}
+		s.panes = append(s.panes, p)
+	}
+	p.lines = append(p.lines, lines...)
+	if len(p.lines) > maxLines {
+		p.lines = append([]string(nil), p.lines[len(p.lines)-maxLines:]...)
+	}
+	s.version++
+	return true
+}
+
+func (s *store) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
+
+// pane returns the pane of a micro, the lock has to be held
+func (s *store) pane(name string) *pane {
+	for _, p := range s.panes {
+		if p.micro.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// snapshot returns a copy of the panes without their logs
+func (s *store) snapshot() ([]pane, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	panes := make([]pane, len(s.panes))
+	for i, p := range s.panes {
+		panes[i] = pane{micro: p.micro, status: p.status}
+	}
+	return panes, s.version
+}
+
+// lines returns the logs of a pane that contain filter, ignoring case
+func (s *store) lines(i int, filter string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i < 0 || i >= len(s.panes) {
+		return nil
+	}
+	if filter == "" {
+		return append([]string(nil), s.panes[i].lines...)
+	}
+
+	filter = strings.ToLower(filter)
+	var lines []string
+	for _, line := range s.panes[i].lines {
+		if strings.Contains(strings.ToLower(line), filter) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// logWriter splits the output of a micro into lines
+type logWriter struct {
+	store *store
+	name  string
+	// fallback receives the logs once the dashboard is closed
+	fallback io.Writer
+
+	mu      sync.Mutex
+	partial string
+}
+
+func (w *logWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	text := w.partial + string(b)
+	w.partial = ""
+
+	// carriage returns redraw progress bars, each redraw is kept as a line
+	normalized := strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+	lines := strings.Split(normalized, "\n")
+	if !w.store.append(w.name, lines[:len(lines)-1]) {
+		if _, err := w.fallback.Write([]byte(text)); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	w.partial = lines[len(lines)-1]
+	return len(b), nil
+}
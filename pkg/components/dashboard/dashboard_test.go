@@ -0,0 +1,114 @@
+package dashboard
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestLogWriter(t *testing.T) {
+	s := &store{panes: []*pane{{micro: Micro{Name: spacePane}}}}
+	var fallback bytes.Buffer
+	w := &logWriter{store: s, name: "api", fallback: &fallback}
+
+	fmt.Fprint(w, "starting\r\nlisten")
+	fmt.Fprint(w, "ing on 8080\ndownloading 10%\rdownloading 100%\n")
+
+	if got := strings.Join(s.lines(1, ""), "|"); got != "starting|listening on 8080|downloading 10%|downloading 100%" {
+		t.Errorf("unexpected lines %q", got)
+	}
+	if got := strings.Join(s.lines(1, "DOWNLOADING 1"), "|"); got != "downloading 10%|downloading 100%" {
+		t.Errorf("expected the filter to ignore case, got %q", got)
+	}
+
+	// once the dashboard is closed, the logs are written as they are
+	s.close()
+	fmt.Fprint(w, "stopped\n")
+	if fallback.String() != "stopped\n" {
+		t.Errorf("expected the logs to be written to the fallback, got %q", fallback.String())
+	}
+}
+
+func TestMaxLines(t *testing.T) {
+	s := &store{}
+	for i := 0; i < maxLines+10; i++ {
+		s.append("api", []string{fmt.Sprint(i)})
+	}
+
+	lines := s.lines(0, "")
+	if len(lines) != maxLines || lines[0] != "10" {
+		t.Errorf("expected the last %d lines, got %d lines from %s", maxLines, len(lines), lines[0])
+	}
+}
+
+func update(t *testing.T, m tea.Model, msgs ...tea.Msg) tea.Model {
+	t.Helper()
+
+	for _, msg := range msgs {
+		var cmd tea.Cmd
+		m, cmd = m.Update(msg)
+		// run the commands of the model, except the ticks
+		if cmd != nil {
+			if msg, ok := cmd().(actionMsg); ok {
+				m, _ = m.Update(msg)
+			}
+		}
+	}
+	return m
+}
+
+func keyMsg(k string) tea.KeyMsg {
+	switch k {
+	case "tab":
+		return tea.KeyMsg{Type: tea.KeyTab}
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+}
+
+func TestModel(t *testing.T) {
+	var restarted, triggered []string
+	input := &Input{
+		Title:    "todo",
+		ProxyURL: "http://localhost:4200",
+		Actions:  []Action{{ID: "cleanup", Name: "Cleanup", Micro: "api"}},
+		Restart: func(micro string) error {
+			restarted = append(restarted, micro)
+			return nil
+		},
+		Trigger: func(action Action) (string, error) {
+			triggered = append(triggered, action.ID)
+			return "200 OK\n{}", errors.New("boom")
+		},
+	}
+	d := New(input)
+	d.AddMicro(Micro{Name: "api", Port: 4201, URL: "http://localhost:4200/api"})
+	d.SetStatus(Status{Name: "api", Status: "running", PID: 42, Restarts: 2})
+	fmt.Fprintln(d.Log("api"), "GET /todos 200")
+
+	var m tea.Model = initialModel(input, d.store)
+	m = update(t, m, tea.WindowSizeMsg{Width: 100, Height: 30}, keyMsg("tab"))
+
+	view := m.View()
+	for _, want := range []string{"api", "running (pid 42)", "restarts 2", "GET /todos 200", "http://localhost:4200/api"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected the view to contain %q:\n%s", want, view)
+		}
+	}
+	if lines := strings.Count(view, "\n") + 1; lines != 30 {
+		t.Errorf("expected the view to fill the 30 lines of the terminal, got %d", lines)
+	}
+
+	m = update(t, m, keyMsg("r"), keyMsg("a"), keyMsg("enter"))
+	if strings.Join(restarted, ",") != "api" || strings.Join(triggered, ",") != "cleanup" {
+		t.Errorf("expected micro api to be restarted and action cleanup triggered, got %v and %v", restarted, triggered)
+	}
+	if lines := d.store.lines(0, ""); len(lines) != 4 || lines[3] != "boom" {
+		t.Errorf("expected the response of the action in the space pane, got %q", lines)
+	}
+}
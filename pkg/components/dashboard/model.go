@@ -0,0 +1,441 @@
+package dashboard
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/deta/space/pkg/components/emoji"
+	"github.com/deta/space/pkg/components/styles"
+	"github.com/muesli/reflow/truncate"
+	"github.com/muesli/reflow/wrap"
+)
+
+const (
+	refreshInterval = 100 * time.Millisecond
+	sidebarWidth    = 28
+)
+
+var boxStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("#4D73E0")).Padding(0, 1)
+
+type keyMap struct {
+	Next    key.Binding
+	Prev    key.Binding
+	Scroll  key.Binding
+	Top     key.Binding
+	Bottom  key.Binding
+	Restart key.Binding
+	Filter  key.Binding
+	Open    key.Binding
+	Action  key.Binding
+	Help    key.Binding
+	Quit    key.Binding
+}
+
+func (k keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Next, k.Restart, k.Filter, k.Open, k.Action, k.Help, k.Quit}
+}
+
+func (k keyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Next, k.Prev},
+		{k.Scroll, k.Top, k.Bottom},
+		{k.Restart, k.Filter, k.Open, k.Action},
+		{k.Help, k.Quit},
+	}
+}
+
+var keys = keyMap{
+	Next:    key.NewBinding(key.WithKeys("tab", "right", "l"), key.WithHelp("tab", "next micro")),
+	Prev:    key.NewBinding(key.WithKeys("shift+tab", "left", "h"), key.WithHelp("shift+tab", "previous micro")),
+	Scroll:  key.NewBinding(key.WithKeys("up", "down", "pgup", "pgdown"), key.WithHelp("↑/↓/pgup/pgdn", "scroll logs")),
+	Top:     key.NewBinding(key.WithKeys("g", "home"), key.WithHelp("g", "first line")),
+	Bottom:  key.NewBinding(key.WithKeys("G", "end"), key.WithHelp("G", "last line")),
+	Restart: key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "restart micro")),
+	Filter:  key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "filter logs")),
+	Open:    key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "open app")),
+	Action:  key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "trigger action")),
+	Help:    key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "more keys")),
+	Quit:    key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+}
+
+type tickMsg struct{}
+
+type actionMsg struct {
+	action Action
+	output string
+	err    error
+}
+
+type messageMsg string
+
+type model struct {
+	input *Input
+	store *store
+
+	width  int
+	height int
+	help   help.Model
+	logs   viewport.Model
+
+	panes    []pane
+	selected int
+	// version of the store rendered in the logs
+	version int
+
+	filter    textinput.Model
+	filtering bool
+
+	// choosing is set while an action is chosen
+	choosing bool
+	cursor   int
+
+	message string
+}
+
+func initialModel(i *Input, s *store) model {
+	filter := textinput.New()
+	filter.Prompt = "/"
+	filter.Placeholder = "filter logs"
+
+	panes, _ := s.snapshot()
+	return model{
+		input:   i,
+		store:   s,
+		help:    help.New(),
+		logs:    viewport.New(0, 0),
+		panes:   panes,
+		filter:  filter,
+		version: -1,
+	}
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(refreshInterval, func(time.Time) tea.Msg {
+		return tickMsg{}
+	})
+}
+
+func (m model) Init() tea.Cmd {
+	return tick()
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.resize()
+		m.refresh(true)
+		return m, nil
+
+	case tickMsg:
+		m.refresh(false)
+		return m, tick()
+
+	case messageMsg:
+		m.message = string(msg)
+		return m, nil
+
+	case actionMsg:
+		lines := []string{fmt.Sprintf("Action %s of micro %s:", msg.action.ID, msg.action.Micro)}
+		lines = append(lines, strings.Split(strings.TrimRight(msg.output, "\n"), "\n")...)
+		if msg.err != nil {
+			m.message = fmt.Sprintf("%s Failed to trigger action %s: %s", emoji.X, msg.action.ID, msg.err)
+			lines = append(lines, msg.err.Error())
+		} else {
+			m.message = fmt.Sprintf("%s Action %s triggered, its response is in the %s pane", emoji.Check, msg.action.ID, spacePane)
+		}
+		m.store.append(spacePane, lines)
+		return m, nil
+
+	case tea.MouseMsg:
+		var cmd tea.Cmd
+		m.logs, cmd = m.logs.Update(msg)
+		return m, cmd
+
+	case tea.KeyMsg:
+		if m.filtering {
+			return m.updateFilter(msg)
+		}
+		if m.choosing {
+			return m.updateChooser(msg)
+		}
+		return m.updateKeys(msg)
+	}
+
+	return m, nil
+}
+
+func (m model) updateKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if len(m.panes) == 0 {
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(msg, keys.Quit):
+		return m, tea.Quit
+	case key.Matches(msg, keys.Next):
+		m.selected = (m.selected + 1) % len(m.panes)
+		m.refresh(true)
+	case key.Matches(msg, keys.Prev):
+		m.selected = (m.selected + len(m.panes) - 1) % len(m.panes)
+		m.refresh(true)
+	case key.Matches(msg, keys.Top):
+		m.logs.GotoTop()
+	case key.Matches(msg, keys.Bottom):
+		m.logs.GotoBottom()
+	case key.Matches(msg, keys.Restart):
+		name := m.panes[m.selected].micro.Name
+		if m.selected == 0 {
+			m.message = "Select a micro to restart it"
+			return m, nil
+		}
+		if err := m.input.Restart(name); err != nil {
+			m.message = fmt.Sprintf("%s Failed to restart micro %s: %s", emoji.X, name, err)
+			return m, nil
+		}
+		m.message = fmt.Sprintf("Restarting micro %s", name)
+	case key.Matches(msg, keys.Filter):
+		m.filtering = true
+		return m, m.filter.Focus()
+	case key.Matches(msg, keys.Open):
+		url := m.input.ProxyURL
+		return m, func() tea.Msg {
+			if err := m.input.OpenURL(url); err != nil {
+				return messageMsg(fmt.Sprintf("%s Failed to open %s: %s", emoji.X, url, err))
+			}
+			return messageMsg(fmt.Sprintf("Opened %s", url))
+		}
+	case key.Matches(msg, keys.Action):
+		if len(m.input.Actions) == 0 {
+			m.message = "No actions in the Spacefile"
+			return m, nil
+		}
+		m.choosing, m.cursor = true, 0
+	case key.Matches(msg, keys.Help):
+		m.help.ShowAll = !m.help.ShowAll
+		m.resize()
+	default:
+		var cmd tea.Cmd
+		m.logs, cmd = m.logs.Update(msg)
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+func (m model) updateFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.filtering = false
+		m.filter.Blur()
+		return m, nil
+	case tea.KeyEsc:
+		m.filtering = false
+		m.filter.Blur()
+		m.filter.SetValue("")
+		m.refresh(true)
+		return m, nil
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	}
+
+	var cmd tea.Cmd
+	m.filter, cmd = m.filter.Update(msg)
+	m.refresh(true)
+	return m, cmd
+}
+
+func (m model) updateChooser(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	actions := m.input.Actions
+
+	switch msg.String() {
+	case "up", "k":
+		m.cursor = (m.cursor + len(actions) - 1) % len(actions)
+	case "down", "j":
+		m.cursor = (m.cursor + 1) % len(actions)
+	case "esc", "q":
+		m.choosing = false
+	case "ctrl+c":
+		return m, tea.Quit
+	case "enter":
+		m.choosing = false
+		action := actions[m.cursor]
+		m.message = fmt.Sprintf("Triggering action %s...", action.ID)
+		return m, func() tea.Msg {
+			output, err := m.input.Trigger(action)
+			return actionMsg{action: action, output: output, err: err}
+		}
+	}
+
+	return m, nil
+}
+
+// resize fits the logs in the pane of the selected micro
+func (m *model) resize() {
+	m.help.Width = m.width
+	m.logs.Width = max(m.width-sidebarWidth-4, 1)
+	// the header, the borders and the header of the pane, the footer and the help
+	m.logs.Height = max(m.height-1-2-3-1-lipgloss.Height(m.help.View(keys)), 1)
+	m.filter.Width = max(m.width-4, 1)
+}
+
+// refresh renders the logs of the selected micro again if they changed, they follow new lines when scrolled to the bottom
+func (m *model) refresh(force bool) {
+	panes, version := m.store.snapshot()
+	m.panes = panes
+	if m.selected >= len(m.panes) {
+		m.selected = 0
+	}
+	if !force && version == m.version {
+		return
+	}
+
+	follow := force || m.logs.AtBottom()
+	lines := m.store.lines(m.selected, m.filter.Value())
+	for i, line := range lines {
+		lines[i] = wrap.String(line, m.logs.Width)
+	}
+	m.logs.SetContent(strings.Join(lines, "\n"))
+	m.version = version
+	if follow {
+		m.logs.GotoBottom()
+	}
+}
+
+func (m model) View() string {
+	if m.width == 0 || len(m.panes) == 0 {
+		return ""
+	}
+
+	header := fmt.Sprintf(" %s %s  %s", styles.Bold("space dev"), m.input.Title, styles.Blue(m.input.ProxyURL))
+	bodyHeight := m.logs.Height + 3
+	body := lipgloss.JoinHorizontal(lipgloss.Top, m.sidebarView(bodyHeight), m.paneView(bodyHeight))
+
+	footer := " " + m.message
+	if m.filtering {
+		footer = " " + m.filter.View()
+	} else if m.filter.Value() != "" {
+		footer = fmt.Sprintf(" %s %s", styles.Pink("filter: "+m.filter.Value()), m.message)
+	}
+
+	return strings.Join([]string{fit(header, m.width), body, fit(footer, m.width), m.help.View(keys)}, "\n")
+}
+
+func (m model) sidebarView(height int) string {
+	var rows []string
+	for i, p := range m.panes {
+		row := fmt.Sprintf("%s %s", statusDot(p), p.micro.Name)
+		if p.micro.Port != 0 {
+			row += fmt.Sprintf(" :%d", p.micro.Port)
+		}
+		if p.status.Restarts > 0 {
+			row += styles.Pinkf(" ↻%d", p.status.Restarts)
+		}
+
+		if i == m.selected {
+			row = fmt.Sprintf("%s %s", styles.SelectTag, row)
+		} else {
+			row = "  " + row
+		}
+		rows = append(rows, fit(row, sidebarWidth-4))
+	}
+	if len(rows) > height {
+		rows = rows[:height]
+	}
+
+	return boxStyle.Copy().Width(sidebarWidth - 2).Height(height).MaxWidth(sidebarWidth).Render(strings.Join(rows, "\n"))
+}
+
+func (m model) paneView(height int) string {
+	p := m.panes[m.selected]
+
+	var title, details string
+	if m.selected == 0 {
+		title = styles.Bold(spacePane)
+		details = fmt.Sprintf("proxy %s", styles.Blue(m.input.ProxyURL))
+	} else {
+		title = fmt.Sprintf("%s  %s", styles.Bold(p.micro.Name), statusText(p.status))
+		details = fmt.Sprintf("port %d · %s · restarts %d", p.micro.Port, styles.Blue(p.micro.URL), p.status.Restarts)
+	}
+
+	content := m.logs.View()
+	if m.choosing {
+		content = m.chooserView()
+	}
+
+	width := m.width - sidebarWidth
+	title, details = fit(title, m.logs.Width), fit(details, m.logs.Width)
+	return boxStyle.Copy().Width(width - 2).Height(height).MaxWidth(width).Render(strings.Join([]string{title, details, "", content}, "\n"))
+}
+
+func (m model) chooserView() string {
+	rows := []string{styles.Bold("Trigger an action") + "  enter to trigger, esc to cancel", ""}
+	for i, a := range m.input.Actions {
+		row := fmt.Sprintf("%s (%s of micro %s)", a.Name, a.ID, a.Micro)
+		if i == m.cursor {
+			row = fmt.Sprintf("%s %s", styles.SelectTag, row)
+		} else {
+			row = "  " + row
+		}
+		rows = append(rows, fit(row, m.logs.Width))
+	}
+	return lipgloss.NewStyle().Height(m.logs.Height).MaxHeight(m.logs.Height).Render(strings.Join(rows, "\n"))
+}
+
+func statusDot(p pane) string {
+	if p.micro.Name == spacePane {
+		return styles.Blue("●")
+	}
+
+	switch p.status.Status {
+	case "running":
+		return styles.Green("●")
+	case "starting", "restarting", "stopping":
+		return styles.Pink("●")
+	case "failed":
+		return styles.Error("●")
+	}
+	return "○"
+}
+
+func statusText(s Status) string {
+	text := s.Status
+	if text == "" {
+		text = "starting"
+	}
+	if s.PID != 0 {
+		text += fmt.Sprintf(" (pid %d)", s.PID)
+	}
+
+	switch s.Status {
+	case "running":
+		text = styles.Green(text)
+	case "failed":
+		text = styles.Error(text)
+	default:
+		text = styles.Pink(text)
+	}
+	if s.Err != nil && s.Status != "running" {
+		text += " " + styles.Error(s.Err.Error())
+	}
+	return text
+}
+
+// fit truncates a line to a width, so that it is not wrapped
+func fit(line string, width int) string {
+	return truncate.String(line, uint(max(width, 0)))
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -97,24 +97,24 @@ type ActionRequest struct {
 // Environment xx
 type Environment struct {
 	Name        string `yaml:"name"`
-	Description string `yaml:"description"`
-	Default     string `yaml:"default"`
+	Description string `yaml:"description,omitempty"`
+	Default     string `yaml:"default,omitempty"`
 }
 
 // Presets xx
 type Presets struct {
-	Env     []Environment `yaml:"env"`
-	APIKeys bool          `yaml:"api_keys"`
+	Env     []Environment `yaml:"env,omitempty"`
+	APIKeys bool          `yaml:"api_keys,omitempty"`
 }
 
 // Action xx
 type Action struct {
 	ID          string `yaml:"id"`
 	Name        string `yaml:"name"`
-	Description string `yaml:"description"`
+	Description string `yaml:"description,omitempty"`
 	Trigger     string `yaml:"trigger"`
 	Interval    string `yaml:"default_interval"`
-	Path        string `yaml:"path"`
+	Path        string `yaml:"path,omitempty"`
 }
 
 // Micro xx
@@ -135,6 +135,8 @@ type Micro struct {
 	Serve          string   `yaml:"serve,omitempty"`
 	Run            string   `yaml:"run,omitempty"`
 	Dev            string   `yaml:"dev,omitempty"`
+	ReadyPath      string   `yaml:"ready_path,omitempty"`
+	Watch          []string `yaml:"watch,omitempty"`
 }
 
 type DiscoveryData struct {